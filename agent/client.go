@@ -0,0 +1,208 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"cybergame-api/model"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout applies when no timeout is configured.
+const DefaultTimeout = 10 * time.Second
+
+// Client is the WalletProvider of the agc credit API. Every call fails with
+// an *Error when the agent cannot be reached, answers a non 2xx status or
+// replies Success=false.
+type Client struct {
+	name       string
+	baseUrl    string
+	agentName  string
+	signer     Signer
+	httpClient *http.Client
+}
+
+func NewClient(name string, baseUrl string, agentName string, signer Signer, timeout time.Duration) *Client {
+	return &Client{
+		name:       name,
+		baseUrl:    strings.TrimRight(baseUrl, "/"),
+		agentName:  agentName,
+		signer:     signer,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+func (c *Client) Name() string {
+	return c.name
+}
+
+func (c *Client) AgentName() string {
+	return c.agentName
+}
+
+func (c *Client) Sign(data string, t time.Time) string {
+	return c.signer.Sign(data, t)
+}
+
+func (c *Client) Register(ctx context.Context, data model.AGCRegister) error {
+	if data.Sign == "" {
+		data.Agentname = c.agentName
+		data.Timestamp, data.Sign = c.sign(data.Agentname + data.Username)
+	}
+	var response model.AGCResponse
+	return c.post(ctx, "/credit-auth/xregister", data, &response)
+}
+
+func (c *Client) Login(ctx context.Context, data model.AGCLogin) (*model.AGCLoginResponse, error) {
+	if data.Sign == "" {
+		data.Partner = c.agentName
+		data.Timestamp, data.Sign = c.sign(data.Partner + data.Username)
+	}
+	var response model.AGCLoginResponse
+	if err := c.post(ctx, "/credit-auth/login", data, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (c *Client) ChangePassword(ctx context.Context, data model.AGCChangePassword) error {
+	if data.Sign == "" {
+		data.Partner = c.agentName
+		data.Timestamp, data.Sign = c.sign(data.Partner + data.PlayerName + data.NewPassword)
+	}
+	var response model.AGCResponse
+	return c.post(ctx, "/credit-auth/changepassword", data, &response)
+}
+
+func (c *Client) Deposit(ctx context.Context, data model.AGCDeposit) (*model.AGCTransferResponse, error) {
+	if data.Sign == "" {
+		data.Agentname = c.agentName
+		data.Timestamp, data.Sign = c.sign(data.Agentname + data.PlayerName)
+	}
+	var response model.AGCTransferResponse
+	if err := c.post(ctx, "/credit-transfer/deposit", data, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (c *Client) Withdraw(ctx context.Context, data model.AGCWithdraw) (*model.AGCTransferResponse, error) {
+	if data.Sign == "" {
+		data.Agentname = c.agentName
+		data.TimeStamp, data.Sign = c.sign(data.Agentname + data.PlayerName)
+	}
+	var response model.AGCTransferResponse
+	if err := c.post(ctx, "/credit-transfer/withdraw", data, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (c *Client) Balance(ctx context.Context, data model.AGCBalance) (*model.AGCBalanceResponse, error) {
+	if data.Sign == "" {
+		data.Agentname = c.agentName
+		data.Timestamp, data.Sign = c.sign(data.Agentname + data.PlayerName)
+	}
+	var response model.AGCBalanceResponse
+	if err := c.post(ctx, "/credit-transfer/balance", data, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// BetHistory pages through the bets of every player of the agent settled or
+// placed between StartTime and EndTime.
+func (c *Client) BetHistory(ctx context.Context, data model.AGCBetHistory) (*model.AGCBetHistoryResponse, error) {
+	if data.Sign == "" {
+		data.Agentname = c.agentName
+		data.Timestamp, data.Sign = c.sign(data.Agentname)
+	}
+	var response model.AGCBetHistoryResponse
+	if err := c.post(ctx, "/report/bet-history", data, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+func (c *Client) sign(data string) (int64, string) {
+	timeNow := time.Now()
+	return timeNow.Unix(), c.signer.Sign(data, timeNow)
+}
+
+func (c *Client) post(ctx context.Context, path string, body interface{}, out interface{}) error {
+
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseUrl+path, bytes.NewReader(jsonBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return transportError(path, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return transportError(path, err)
+	}
+
+	// the agent reports refusals in the body, often with a 200
+	var result model.AGCResponse
+	jsonErr := json.Unmarshal(data, &result)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		agentErr := &Error{Kind: KindHttp, Path: path, Status: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		if jsonErr == nil && result.Error != nil {
+			agentErr.Code = result.Error.Code
+			agentErr.Message = result.Error.Message
+		}
+		return agentErr
+	}
+
+	if jsonErr != nil {
+		return &Error{Kind: KindUnavailable, Path: path, Status: resp.StatusCode, Message: "invalid response", Err: jsonErr}
+	}
+
+	if !result.Success {
+		agentErr := &Error{Kind: KindRefused, Path: path, Status: resp.StatusCode, Message: "refused"}
+		if result.Error != nil {
+			agentErr.Code = result.Error.Code
+			if result.Error.Message != "" {
+				agentErr.Message = result.Error.Message
+			}
+		}
+		return agentErr
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return &Error{Kind: KindUnavailable, Path: path, Status: resp.StatusCode, Message: "invalid response", Err: err}
+	}
+
+	return nil
+}
+
+func transportError(path string, err error) error {
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return &Error{Kind: KindTimeout, Path: path, Message: "timeout", Err: err}
+	}
+	if errors.Is(err, context.Canceled) {
+		return &Error{Kind: KindCanceled, Path: path, Message: "canceled", Err: err}
+	}
+	return &Error{Kind: KindUnavailable, Path: path, Message: fmt.Sprintf("unreachable: %s", err.Error()), Err: err}
+}
@@ -0,0 +1,136 @@
+package agent_test
+
+import (
+	"context"
+	"cybergame-api/agent"
+	"cybergame-api/agent/fake"
+	"cybergame-api/model"
+	"net/http"
+	"testing"
+	"time"
+)
+
+const testAgentName = "testagent"
+const testAgentKey = "testkey"
+
+func newTestClient(t *testing.T, signer agent.Signer, timeout time.Duration) (*fake.Server, *agent.Client) {
+
+	server := fake.NewServer(testAgentName, testAgentKey)
+	httpServer := server.Start()
+	t.Cleanup(httpServer.Close)
+
+	return server, agent.NewClient(agent.MainWallet, httpServer.URL, testAgentName, signer, timeout)
+}
+
+func TestClientTransfers(t *testing.T) {
+
+	server, client := newTestClient(t, agent.SHA256Signer{Key: testAgentKey}, time.Second)
+	ctx := context.Background()
+
+	err := client.Register(ctx, model.AGCRegister{Username: "member1", Password: "secret", Fullname: "Member One", Currency: "THB"})
+	if err != nil {
+		t.Fatalf("register: %s", err.Error())
+	}
+
+	deposit, err := client.Deposit(ctx, model.AGCDeposit{PlayerName: "member1", Amount: 150.25, TransactionId: "D1"})
+	if err != nil {
+		t.Fatalf("deposit: %s", err.Error())
+	}
+	if deposit.Data.BeforeBalance != 0 || deposit.Data.Balance != 150.25 {
+		t.Fatalf("deposit balance %v -> %v, want 0 -> 150.25", deposit.Data.BeforeBalance, deposit.Data.Balance)
+	}
+
+	withdraw, err := client.Withdraw(ctx, model.AGCWithdraw{PlayerName: "member1", Amount: 50.25, TransactionId: "W1"})
+	if err != nil {
+		t.Fatalf("withdraw: %s", err.Error())
+	}
+	if withdraw.Data.Balance != 100 {
+		t.Fatalf("withdraw balance %v, want 100", withdraw.Data.Balance)
+	}
+
+	balance, err := client.Balance(ctx, model.AGCBalance{PlayerName: "member1"})
+	if err != nil {
+		t.Fatalf("balance: %s", err.Error())
+	}
+	if balance.Data.Balance != 100 {
+		t.Fatalf("balance %v, want 100", balance.Data.Balance)
+	}
+
+	// the same TransactionId answers the first transfer again
+	if _, err := client.Deposit(ctx, model.AGCDeposit{PlayerName: "member1", Amount: 150.25, TransactionId: "D1"}); err != nil {
+		t.Fatalf("deposit replay: %s", err.Error())
+	}
+	if player, _ := server.Player("member1"); player.Balance != 100 {
+		t.Fatalf("balance after replay %v, want 100", player.Balance)
+	}
+}
+
+func TestClientHMACSigner(t *testing.T) {
+
+	server := fake.NewServer(testAgentName, testAgentKey)
+	server.Signer = agent.HMACSigner{Key: testAgentKey}
+	server.AddPlayer("member1", "secret", 10)
+	httpServer := server.Start()
+	defer httpServer.Close()
+
+	client := agent.NewClient(agent.MainWallet, httpServer.URL, testAgentName, agent.HMACSigner{Key: testAgentKey}, time.Second)
+	if _, err := client.Balance(context.Background(), model.AGCBalance{PlayerName: "member1"}); err != nil {
+		t.Fatalf("balance: %s", err.Error())
+	}
+
+	// a sha256 sign is refused by an hmac agent
+	other := agent.NewClient(agent.MainWallet, httpServer.URL, testAgentName, agent.SHA256Signer{Key: testAgentKey}, time.Second)
+	_, err := other.Balance(context.Background(), model.AGCBalance{PlayerName: "member1"})
+	agentErr, ok := agent.AsError(err)
+	if !ok || agentErr.Kind != agent.KindRefused || agentErr.Code != fake.CodeInvalidSign {
+		t.Fatalf("sha256 sign: got %v, want refused invalid sign", err)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		failure  *fake.Failure
+		amount   float64
+		cancel   bool
+		wantKind string
+		wantCode int
+		wantHttp int
+	}{
+		{name: "insufficient balance", amount: 500, wantKind: agent.KindRefused, wantCode: fake.CodeInsufficientBalance},
+		{name: "refused", failure: &fake.Failure{Code: 9999}, amount: 10, wantKind: agent.KindRefused, wantCode: 9999},
+		{name: "bad gateway", failure: &fake.Failure{Status: http.StatusBadGateway}, amount: 10, wantKind: agent.KindHttp, wantHttp: http.StatusBadGateway},
+		{name: "timeout", failure: &fake.Failure{Delay: time.Second}, amount: 10, wantKind: agent.KindTimeout},
+		{name: "canceled", amount: 10, cancel: true, wantKind: agent.KindCanceled},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			server, client := newTestClient(t, agent.SHA256Signer{Key: testAgentKey}, 200*time.Millisecond)
+			server.AddPlayer("member1", "secret", 100)
+			if test.failure != nil {
+				server.InjectFailure(fake.PathWithdraw, *test.failure)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if test.cancel {
+				cancel()
+			}
+
+			_, err := client.Withdraw(ctx, model.AGCWithdraw{PlayerName: "member1", Amount: test.amount, TransactionId: "W1"})
+			agentErr, ok := agent.AsError(err)
+			if !ok {
+				t.Fatalf("got %v, want an *agent.Error", err)
+			}
+			if agentErr.Kind != test.wantKind || agentErr.Code != test.wantCode || agentErr.Status != test.wantHttp && test.wantHttp != 0 {
+				t.Fatalf("got %s status %d code %d, want %s status %d code %d", agentErr.Kind, agentErr.Status, agentErr.Code, test.wantKind, test.wantHttp, test.wantCode)
+			}
+			if player, _ := server.Player("member1"); player.Balance != 100 {
+				t.Fatalf("balance %v, want 100 untouched", player.Balance)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// KindRefused is an answer with Success=false
+	KindRefused = "refused"
+	// KindHttp is a non 2xx status
+	KindHttp = "http"
+	// KindTimeout is no answer within the client timeout or context deadline
+	KindTimeout = "timeout"
+	// KindCanceled is a context canceled by the caller
+	KindCanceled = "canceled"
+	// KindUnavailable is a network failure or an unreadable body
+	KindUnavailable = "unavailable"
+)
+
+type Error struct {
+	Kind    string
+	Path    string
+	Status  int
+	Code    int
+	Message string
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Code != 0 {
+		return fmt.Sprintf("agent %s %s: %s (code %d)", e.Path, e.Kind, e.Message, e.Code)
+	}
+	return fmt.Sprintf("agent %s %s: %s", e.Path, e.Kind, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// AsError returns the agent error wrapped in err, if any.
+func AsError(err error) (*Error, bool) {
+	var agentErr *Error
+	if errors.As(err, &agentErr) {
+		return agentErr, true
+	}
+	return nil, false
+}
@@ -0,0 +1,76 @@
+package fake
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type failureBody struct {
+	Path    string `json:"path"`
+	Status  int    `json:"status"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	DelayMs int    `json:"delayMs"`
+	Times   int    `json:"times"`
+	Apply   bool   `json:"apply"`
+}
+
+type playerBody struct {
+	Username string  `json:"username"`
+	Password string  `json:"password"`
+	Balance  float64 `json:"balance"`
+}
+
+// serveControl lets a developer drive the standalone server:
+//
+//	GET    /_fake/players            list wallets
+//	POST   /_fake/players            {"username","password","balance"}
+//	POST   /_fake/failures           {"path","status","code","message","delayMs","times","apply"}
+//	DELETE /_fake/failures           drop pending failures
+//	GET    /_fake/play               the url returned by login
+func (s *Server) serveControl(w http.ResponseWriter, r *http.Request) {
+
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	switch {
+	case path == "/_fake/players" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, s.Players())
+
+	case path == "/_fake/players" && r.Method == http.MethodPost:
+		var body playerBody
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Username == "" {
+			writeJSON(w, http.StatusBadRequest, fail(CodeInvalidRequest, "invalid request"))
+			return
+		}
+		s.AddPlayer(body.Username, body.Password, body.Balance)
+		writeJSON(w, http.StatusCreated, ok(nil))
+
+	case path == "/_fake/failures" && r.Method == http.MethodPost:
+		var body failureBody
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Path == "" {
+			writeJSON(w, http.StatusBadRequest, fail(CodeInvalidRequest, "invalid request"))
+			return
+		}
+		s.InjectFailure(body.Path, Failure{
+			Status:  body.Status,
+			Code:    body.Code,
+			Message: body.Message,
+			Delay:   time.Duration(body.DelayMs) * time.Millisecond,
+			Times:   body.Times,
+			Apply:   body.Apply,
+		})
+		writeJSON(w, http.StatusCreated, ok(nil))
+
+	case path == "/_fake/failures" && r.Method == http.MethodDelete:
+		s.ClearFailures()
+		writeJSON(w, http.StatusOK, ok(nil))
+
+	case path == "/_fake/play":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<html><body><h1>fake agent game</h1><p>" + r.URL.RawQuery + "</p></body></html>"))
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
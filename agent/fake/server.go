@@ -0,0 +1,583 @@
+// Package fake is an in memory agent API for development and tests. It checks
+// signatures the way the real agent does (see helper.CreateSign), keeps player
+// wallets and can be told to fail the next calls of an endpoint.
+package fake
+
+import (
+	"crypto/rand"
+	"cybergame-api/agent"
+	"cybergame-api/model"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Error codes returned in Error.Code.
+const (
+	CodeInvalidRequest      = 1000
+	CodeInvalidSign         = 1001
+	CodeExpiredTimestamp    = 1002
+	CodeInvalidAgent        = 1003
+	CodePlayerExists        = 1004
+	CodePlayerNotFound      = 1005
+	CodeInvalidPassword     = 1006
+	CodeInvalidAmount       = 1007
+	CodeInsufficientBalance = 1008
+	CodeDuplicateTransfer   = 1009
+)
+
+// MaxClockSkew is how far a request Timestamp may be from the server clock.
+const MaxClockSkew = 5 * time.Minute
+
+const (
+	PathRegister       = "/credit-auth/xregister"
+	PathLogin          = "/credit-auth/login"
+	PathChangePassword = "/credit-auth/changepassword"
+	PathDeposit        = "/credit-transfer/deposit"
+	PathWithdraw       = "/credit-transfer/withdraw"
+	PathBalance        = "/credit-transfer/balance"
+	PathBetHistory     = "/report/bet-history"
+)
+
+// BetTimeLayout is how the agent writes BetTime, SettleTime and the history
+// range.
+const BetTimeLayout = "2006-01-02 15:04:05"
+
+// Failure replaces the normal answer of an endpoint. A zero Status answers 200
+// with Success=false, Delay sleeps before answering so clients can time out.
+type Failure struct {
+	Status  int           `json:"status"`
+	Code    int           `json:"code"`
+	Message string        `json:"message"`
+	Delay   time.Duration `json:"delay"`
+	// Times is how many calls fail, 0 means one
+	Times int `json:"times"`
+	// Apply runs the call before failing, like an agent that debits then
+	// times out
+	Apply bool `json:"apply"`
+}
+
+type Player struct {
+	Username string  `json:"username"`
+	Password string  `json:"password"`
+	Fullname string  `json:"fullname"`
+	Currency string  `json:"currency"`
+	Balance  float64 `json:"balance"`
+}
+
+type Transfer struct {
+	TransactionId string  `json:"transactionId"`
+	PlayerName    string  `json:"playerName"`
+	Type          string  `json:"type"`
+	Amount        float64 `json:"amount"`
+	BeforeBalance float64 `json:"beforeBalance"`
+	Balance       float64 `json:"balance"`
+}
+
+type Server struct {
+	AgentName string
+	Key       string
+	// Signer checks the signs, sha256 with Key unless set
+	Signer agent.Signer
+	// Now is the server clock, tests may move it
+	Now func() time.Time
+
+	mutex     sync.Mutex
+	players   map[string]*Player
+	transfers map[string]Transfer
+	bets      []model.AGCBetRecord
+	failures  map[string][]Failure
+	calls     map[string]int
+}
+
+func NewServer(agentName string, key string) *Server {
+	return &Server{
+		AgentName: agentName,
+		Key:       key,
+		Signer:    agent.SHA256Signer{Key: key},
+		Now:       time.Now,
+		players:   make(map[string]*Player),
+		transfers: make(map[string]Transfer),
+		failures:  make(map[string][]Failure),
+		calls:     make(map[string]int),
+	}
+}
+
+// Start serves on a random local port, for go test. Point AGENT_API at the
+// returned server URL and Close it when done.
+func (s *Server) Start() *httptest.Server {
+	return httptest.NewServer(s)
+}
+
+// AddPlayer creates or replaces a wallet.
+func (s *Server) AddPlayer(username string, password string, balance float64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.players[strings.ToLower(username)] = &Player{Username: username, Password: password, Currency: "THB", Balance: balance}
+}
+
+// SetBalance changes a wallet the way bets and wins do, outside any transfer.
+func (s *Server) SetBalance(username string, balance float64) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	player, ok := s.players[strings.ToLower(username)]
+	if ok {
+		player.Balance = balance
+	}
+	return ok
+}
+
+// AddBet records a bet for the history report, a bet with a BetId already
+// recorded replaces it the way a settlement does.
+func (s *Server) AddBet(bet model.AGCBetRecord) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for i := range s.bets {
+		if s.bets[i].BetId == bet.BetId && s.bets[i].Provider == bet.Provider {
+			s.bets[i] = bet
+			return
+		}
+	}
+	s.bets = append(s.bets, bet)
+}
+
+func (s *Server) Player(username string) (Player, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	player, ok := s.players[strings.ToLower(username)]
+	if !ok {
+		return Player{}, false
+	}
+	return *player, true
+}
+
+func (s *Server) Players() []Player {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	list := make([]Player, 0, len(s.players))
+	for _, player := range s.players {
+		list = append(list, *player)
+	}
+	return list
+}
+
+func (s *Server) Transfer(transactionId string) (Transfer, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	transfer, ok := s.transfers[transactionId]
+	return transfer, ok
+}
+
+// Calls counts the requests received on path, failed ones included.
+func (s *Server) Calls(path string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.calls[path]
+}
+
+// InjectFailure makes the next calls of path fail.
+func (s *Server) InjectFailure(path string, failure Failure) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if failure.Times <= 0 {
+		failure.Times = 1
+	}
+	s.failures[path] = append(s.failures[path], failure)
+}
+
+func (s *Server) ClearFailures() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.failures = make(map[string][]Failure)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if strings.HasPrefix(r.URL.Path, "/_fake/") {
+		s.serveControl(w, r)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var handle func(r *http.Request) response
+	switch r.URL.Path {
+	case PathRegister:
+		handle = s.register
+	case PathLogin:
+		handle = s.login
+	case PathChangePassword:
+		handle = s.changePassword
+	case PathDeposit:
+		handle = s.deposit
+	case PathWithdraw:
+		handle = s.withdraw
+	case PathBalance:
+		handle = s.balance
+	case PathBetHistory:
+		handle = s.betHistory
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	failure, failing := s.nextFailure(r.URL.Path)
+	if failing && failure.Delay > 0 {
+		select {
+		case <-time.After(failure.Delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	if !failing || failure.Apply {
+		result := handle(r)
+		if !failing {
+			writeJSON(w, http.StatusOK, result)
+			return
+		}
+	}
+
+	status := failure.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	message := failure.Message
+	if message == "" {
+		message = "injected failure"
+	}
+	writeJSON(w, status, fail(failure.Code, message))
+}
+
+func (s *Server) nextFailure(path string) (Failure, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.calls[path]++
+	queue := s.failures[path]
+	if len(queue) == 0 {
+		return Failure{}, false
+	}
+
+	failure := queue[0]
+	queue[0].Times--
+	if queue[0].Times <= 0 {
+		s.failures[path] = queue[1:]
+	}
+	return failure, true
+}
+
+type response struct {
+	Success bool                    `json:"Success"`
+	Error   *model.AGCResponseError `json:"Error"`
+	Data    interface{}             `json:"Data,omitempty"`
+}
+
+func fail(code int, message string) response {
+	return response{Error: &model.AGCResponseError{Code: code, Message: message}}
+}
+
+func ok(data interface{}) response {
+	return response{Success: true, Data: data}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// checkSign accepts the request when sign is one of the signatures the
+// callers are expected to build from data.
+func (s *Server) checkSign(timestamp int64, sign string, data ...string) *response {
+
+	signedAt := time.Unix(timestamp, 0)
+	if skew := s.Now().Sub(signedAt); skew > MaxClockSkew || skew < -MaxClockSkew {
+		result := fail(CodeExpiredTimestamp, "timestamp expired")
+		return &result
+	}
+
+	for _, word := range data {
+		if strings.EqualFold(s.Signer.Sign(word, signedAt), sign) {
+			return nil
+		}
+	}
+
+	result := fail(CodeInvalidSign, "invalid sign")
+	return &result
+}
+
+func (s *Server) register(r *http.Request) response {
+
+	var body model.AGCRegister
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Username == "" || body.Password == "" {
+		return fail(CodeInvalidRequest, "invalid request")
+	}
+
+	if !strings.EqualFold(body.Agentname, s.AgentName) {
+		return fail(CodeInvalidAgent, "invalid agent")
+	}
+
+	if result := s.checkSign(body.Timestamp, body.Sign, body.Agentname+body.Username); result != nil {
+		return *result
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	key := strings.ToLower(body.Username)
+	if _, exist := s.players[key]; exist {
+		return fail(CodePlayerExists, "player already exists")
+	}
+
+	currency := body.Currency
+	if currency == "" {
+		currency = "THB"
+	}
+	s.players[key] = &Player{Username: body.Username, Password: body.Password, Fullname: body.Fullname, Currency: currency}
+
+	return ok(map[string]interface{}{"Username": body.Username})
+}
+
+func (s *Server) login(r *http.Request) response {
+
+	var body model.AGCLogin
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Username == "" {
+		return fail(CodeInvalidRequest, "invalid request")
+	}
+
+	if !strings.EqualFold(body.Partner, s.AgentName) {
+		return fail(CodeInvalidAgent, "invalid agent")
+	}
+
+	player, exist := s.Player(body.Username)
+	if !exist {
+		return fail(CodePlayerNotFound, "player not found")
+	}
+
+	// member login signs with the password, game launch without
+	if result := s.checkSign(body.Timestamp, body.Sign, body.Partner+body.Username, body.Partner+body.Username+player.Password); result != nil {
+		return *result
+	}
+
+	token := randomToken()
+	url := fmt.Sprintf("http://%s/_fake/play?token=%s", r.Host, token)
+	if body.Provider != "" {
+		url += "&provider=" + body.Provider + "&game=" + body.GameCode
+	}
+
+	return ok(map[string]interface{}{"Url": url, "Token": token})
+}
+
+func (s *Server) changePassword(r *http.Request) response {
+
+	var body model.AGCChangePassword
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.PlayerName == "" || body.NewPassword == "" {
+		return fail(CodeInvalidRequest, "invalid request")
+	}
+
+	if !strings.EqualFold(body.Partner, s.AgentName) {
+		return fail(CodeInvalidAgent, "invalid agent")
+	}
+
+	if result := s.checkSign(body.Timestamp, body.Sign, body.Partner+body.PlayerName+body.NewPassword); result != nil {
+		return *result
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	player, exist := s.players[strings.ToLower(body.PlayerName)]
+	if !exist {
+		return fail(CodePlayerNotFound, "player not found")
+	}
+	player.Password = body.NewPassword
+
+	return ok(nil)
+}
+
+func (s *Server) deposit(r *http.Request) response {
+
+	var body model.AGCDeposit
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return fail(CodeInvalidRequest, "invalid request")
+	}
+	return s.transfer("deposit", body.Agentname, body.PlayerName, body.Amount, body.Timestamp, body.Sign, body.TransactionId)
+}
+
+func (s *Server) withdraw(r *http.Request) response {
+
+	var body model.AGCWithdraw
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return fail(CodeInvalidRequest, "invalid request")
+	}
+	return s.transfer("withdraw", body.Agentname, body.PlayerName, body.Amount, body.TimeStamp, body.Sign, body.TransactionId)
+}
+
+func (s *Server) balance(r *http.Request) response {
+
+	var body model.AGCBalance
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.PlayerName == "" {
+		return fail(CodeInvalidRequest, "invalid request")
+	}
+
+	if !strings.EqualFold(body.Agentname, s.AgentName) {
+		return fail(CodeInvalidAgent, "invalid agent")
+	}
+
+	if result := s.checkSign(body.Timestamp, body.Sign, body.Agentname+body.PlayerName); result != nil {
+		return *result
+	}
+
+	player, exist := s.Player(body.PlayerName)
+	if !exist {
+		return fail(CodePlayerNotFound, "player not found")
+	}
+
+	return ok(map[string]interface{}{"PlayerName": player.Username, "Balance": player.Balance})
+}
+
+// betHistory answers the bets placed or settled within the range, a page at
+// a time.
+func (s *Server) betHistory(r *http.Request) response {
+
+	var body model.AGCBetHistory
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return fail(CodeInvalidRequest, "invalid request")
+	}
+
+	if !strings.EqualFold(body.Agentname, s.AgentName) {
+		return fail(CodeInvalidAgent, "invalid agent")
+	}
+
+	if result := s.checkSign(body.Timestamp, body.Sign, body.Agentname); result != nil {
+		return *result
+	}
+
+	start, err := time.ParseInLocation(BetTimeLayout, body.StartTime, time.Local)
+	if err != nil {
+		return fail(CodeInvalidRequest, "invalid start time")
+	}
+	end, err := time.ParseInLocation(BetTimeLayout, body.EndTime, time.Local)
+	if err != nil {
+		return fail(CodeInvalidRequest, "invalid end time")
+	}
+	if body.Page < 1 {
+		body.Page = 1
+	}
+	if body.PageSize < 1 {
+		body.PageSize = 100
+	}
+
+	inRange := func(value string) bool {
+		at, err := time.ParseInLocation(BetTimeLayout, value, time.Local)
+		return err == nil && !at.Before(start) && at.Before(end)
+	}
+
+	s.mutex.Lock()
+	records := []model.AGCBetRecord{}
+	for _, bet := range s.bets {
+		if inRange(bet.BetTime) || inRange(bet.SettleTime) {
+			records = append(records, bet)
+		}
+	}
+	s.mutex.Unlock()
+
+	total := len(records)
+	from := (body.Page - 1) * body.PageSize
+	if from > total {
+		from = total
+	}
+	to := from + body.PageSize
+	if to > total {
+		to = total
+	}
+
+	return ok(map[string]interface{}{"Total": total, "Page": body.Page, "Records": records[from:to]})
+}
+
+// transfer moves credit once per TransactionId, a replay answers the first
+// result again without touching the wallet.
+func (s *Server) transfer(kind string, agentName string, playerName string, amount float64, timestamp int64, sign string, transactionId string) response {
+
+	if playerName == "" || transactionId == "" {
+		return fail(CodeInvalidRequest, "invalid request")
+	}
+
+	if !strings.EqualFold(agentName, s.AgentName) {
+		return fail(CodeInvalidAgent, "invalid agent")
+	}
+
+	if result := s.checkSign(timestamp, sign, agentName+playerName); result != nil {
+		return *result
+	}
+
+	amount = math.Round(amount*100) / 100
+	if amount <= 0 {
+		return fail(CodeInvalidAmount, "invalid amount")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if done, exist := s.transfers[transactionId]; exist {
+		if done.Type != kind || done.Amount != amount || !strings.EqualFold(done.PlayerName, playerName) {
+			return fail(CodeDuplicateTransfer, "transaction id already used")
+		}
+		return ok(transferData(done))
+	}
+
+	player, exist := s.players[strings.ToLower(playerName)]
+	if !exist {
+		return fail(CodePlayerNotFound, "player not found")
+	}
+
+	before := player.Balance
+	if kind == "withdraw" {
+		if player.Balance < amount {
+			return fail(CodeInsufficientBalance, "insufficient balance")
+		}
+		player.Balance = math.Round((player.Balance-amount)*100) / 100
+	} else {
+		player.Balance = math.Round((player.Balance+amount)*100) / 100
+	}
+
+	done := Transfer{
+		TransactionId: transactionId,
+		PlayerName:    player.Username,
+		Type:          kind,
+		Amount:        amount,
+		BeforeBalance: before,
+		Balance:       player.Balance,
+	}
+	s.transfers[transactionId] = done
+
+	return ok(transferData(done))
+}
+
+func transferData(transfer Transfer) map[string]interface{} {
+	return map[string]interface{}{
+		"TransactionId": transfer.TransactionId,
+		"Amount":        transfer.Amount,
+		"BeforeBalance": transfer.BeforeBalance,
+		"Balance":       transfer.Balance,
+	}
+}
+
+func randomToken() string {
+	data := make([]byte, 16)
+	if _, err := rand.Read(data); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(data)
+}
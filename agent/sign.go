@@ -0,0 +1,49 @@
+package agent
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"cybergame-api/helper"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Signer builds the Sign field of a request from its identifying data and
+// Timestamp.
+type Signer interface {
+	Sign(data string, t time.Time) string
+}
+
+// SHA256Signer is sha256(lower(data) + unix + lower(key)), see
+// helper.CreateSign.
+type SHA256Signer struct {
+	Key string
+}
+
+func (s SHA256Signer) Sign(data string, t time.Time) string {
+	return helper.CreateSignWithKey(data, t, s.Key)
+}
+
+// HMACSigner is hex(hmac_sha256(key, data + unix)).
+type HMACSigner struct {
+	Key string
+}
+
+func (s HMACSigner) Sign(data string, t time.Time) string {
+	mac := hmac.New(sha256.New, []byte(s.Key))
+	mac.Write([]byte(data + fmt.Sprintf("%d", t.Unix())))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// NewSigner returns the signer of an adapter type.
+func NewSigner(walletType string, key string) (Signer, error) {
+	switch strings.ToLower(walletType) {
+	case "", TypeAGC:
+		return SHA256Signer{Key: key}, nil
+	case TypeAGCHmac:
+		return HMACSigner{Key: key}, nil
+	}
+	return nil, fmt.Errorf("unknown agent wallet type %q", walletType)
+}
@@ -0,0 +1,139 @@
+package agent
+
+import (
+	"context"
+	"cybergame-api/model"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Adapter types, AGENT_TYPE. Both are the agc Client, they only differ in the
+// Signer.
+const (
+	// TypeAGC signs with sha256, the current aggregator
+	TypeAGC = "agc"
+	// TypeAGCHmac is the same client signed with hmac-sha256
+	TypeAGCHmac = "agc-hmac"
+)
+
+// MainWallet is the wallet configured by AGENT_API, AGENT_NAME and AGENT_KEY.
+const MainWallet = "main"
+
+// WalletProvider is one aggregator holding the member wallets. Requests are
+// sent with the adapter's own agent name and signature, callers leave
+// Agentname/Partner, Timestamp and Sign empty unless they sign with Sign
+// themselves.
+type WalletProvider interface {
+	Name() string
+	AgentName() string
+	Sign(data string, t time.Time) string
+
+	Register(ctx context.Context, data model.AGCRegister) error
+	Login(ctx context.Context, data model.AGCLogin) (*model.AGCLoginResponse, error)
+	ChangePassword(ctx context.Context, data model.AGCChangePassword) error
+	Deposit(ctx context.Context, data model.AGCDeposit) (*model.AGCTransferResponse, error)
+	Withdraw(ctx context.Context, data model.AGCWithdraw) (*model.AGCTransferResponse, error)
+	Balance(ctx context.Context, data model.AGCBalance) (*model.AGCBalanceResponse, error)
+	BetHistory(ctx context.Context, data model.AGCBetHistory) (*model.AGCBetHistoryResponse, error)
+}
+
+// Config describes one wallet, see LoadConfig for the environment.
+type Config struct {
+	Name      string
+	Type      string
+	BaseUrl   string
+	AgentName string
+	Key       string
+	Timeout   time.Duration
+}
+
+// NewWallet builds the adapter of config.Type.
+func NewWallet(config Config) (WalletProvider, error) {
+
+	signer, err := NewSigner(config.Type, config.Key)
+	if err != nil {
+		return nil, err
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+	return NewClient(config.Name, config.BaseUrl, config.AgentName, signer, config.Timeout), nil
+}
+
+// LoadConfig reads the main wallet from AGENT_API, AGENT_NAME, AGENT_KEY,
+// AGENT_TIMEOUT and AGENT_TYPE.
+func LoadConfig() Config {
+
+	config := Config{
+		Name:      MainWallet,
+		Type:      os.Getenv("AGENT_TYPE"),
+		BaseUrl:   os.Getenv("AGENT_API"),
+		AgentName: os.Getenv("AGENT_NAME"),
+		Key:       os.Getenv("AGENT_KEY"),
+		Timeout:   DefaultTimeout,
+	}
+	if seconds, err := strconv.Atoi(os.Getenv("AGENT_TIMEOUT")); err == nil && seconds > 0 {
+		config.Timeout = time.Duration(seconds) * time.Second
+	}
+	return config
+}
+
+var wallets map[string]WalletProvider
+var walletsMutex sync.RWMutex
+var walletsOnce sync.Once
+
+func loadWallets() {
+	walletsOnce.Do(func() {
+		walletsMutex.Lock()
+		defer walletsMutex.Unlock()
+		if wallets == nil {
+			wallets = make(map[string]WalletProvider)
+		}
+		if _, exist := wallets[MainWallet]; exist {
+			return
+		}
+		wallet, err := NewWallet(LoadConfig())
+		if err != nil {
+			log.Printf("agent wallet %s: %s", MainWallet, err.Error())
+			return
+		}
+		wallets[MainWallet] = wallet
+	})
+}
+
+// Register adds or replaces a wallet, the main one from the environment is
+// loaded first.
+func Register(wallet WalletProvider) {
+	loadWallets()
+	walletsMutex.Lock()
+	defer walletsMutex.Unlock()
+	wallets[wallet.Name()] = wallet
+}
+
+func Wallet(name string) (WalletProvider, error) {
+	loadWallets()
+	walletsMutex.RLock()
+	defer walletsMutex.RUnlock()
+	wallet, ok := wallets[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown agent wallet %q", name)
+	}
+	return wallet, nil
+}
+
+// Default is the wallet of this site. Every member is registered, funded and
+// launched there, a member's credit must stay in one wallet.
+func Default() WalletProvider {
+
+	wallet, err := Wallet(MainWallet)
+	if err != nil {
+		log.Println(err.Error())
+		// an unusable main wallet still answers every call with an *Error
+		return NewClient(MainWallet, "", "", SHA256Signer{}, DefaultTimeout)
+	}
+	return wallet
+}
@@ -0,0 +1,251 @@
+package catalog
+
+import (
+	"cybergame-api/model"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultDir is where the provider game lists ship in this repository.
+const DefaultDir = "json"
+
+// relative ImageIcon paths such as /media/egames/... are served by the same
+// host the absolute ones point at.
+const mediaHost = "https://walletbox88.com"
+
+const (
+	GameTypeSlot    = "slot"
+	GameTypeFishing = "fishing"
+	GameTypeTable   = "table"
+	GameTypePoker   = "poker"
+	GameTypeArcade  = "arcade"
+	GameTypeBingo   = "bingo"
+	GameTypeOther   = "other"
+)
+
+// Load reads and normalizes the game list of every known provider in dir.
+func Load(dir string) ([]model.Game, error) {
+
+	var games []model.Game
+	for _, provider := range providers {
+		list, err := LoadProvider(dir, provider.Code)
+		if err != nil {
+			return nil, err
+		}
+		games = append(games, list...)
+	}
+	return games, nil
+}
+
+// LoadProvider reads and normalizes the game list of a single provider in dir.
+func LoadProvider(dir string, code string) ([]model.Game, error) {
+
+	provider, ok := FindProvider(code)
+	if !ok {
+		return nil, fmt.Errorf("unknown game provider %q", code)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, provider.File))
+	if err != nil {
+		return nil, err
+	}
+	return Parse(*provider, data)
+}
+
+// Parse normalizes the raw content of a provider file. Entries without a game
+// code are skipped and duplicated codes keep their first occurrence.
+func Parse(provider Provider, data []byte) ([]model.Game, error) {
+
+	var raws []rawGame
+	if err := json.Unmarshal(data, &raws); err != nil {
+		return nil, fmt.Errorf("%s: %w", provider.File, err)
+	}
+
+	games := make([]model.Game, 0, len(raws))
+	seen := make(map[string]bool, len(raws))
+	for _, raw := range raws {
+		game := normalize(provider, raw)
+		if game.GameCode == "" || seen[game.GameCode] {
+			continue
+		}
+		seen[game.GameCode] = true
+		games = append(games, game)
+	}
+	return games, nil
+}
+
+func normalize(provider Provider, raw rawGame) model.Game {
+
+	var game model.Game
+	game.Provider = provider.Code
+	game.GameCode = raw.GameCode.Code
+	if len(raw.GameCode.Devices) > 0 {
+		game.DeviceCodes = raw.GameCode.Devices
+	}
+
+	game.Names = normalizeNames(raw)
+	game.Name = game.Names["en"]
+	if game.Name == "" {
+		game.Name = game.GameCode
+	}
+
+	game.ProviderGameType = strings.TrimSpace(raw.GameType)
+	game.GameType = normalizeGameType(game.ProviderGameType, provider.DefaultType)
+	game.ImageIcon = normalizeImageUrl(raw.ImageIcon)
+
+	for _, currency := range raw.Currency {
+		if currency = strings.TrimSpace(currency); currency != "" {
+			game.Currencies = append(game.Currencies, currency)
+		}
+	}
+
+	game.IsDesktop, game.IsMobile = normalizeDevices(raw)
+
+	specials := ""
+	if raw.Specials != nil {
+		specials = strings.ToLower(*raw.Specials)
+	}
+	game.IsHot = bool(raw.IsHot) || strings.Contains(specials, "hot")
+	game.IsNew = bool(raw.IsNew) || strings.Contains(specials, "new")
+	game.IsRecommend = bool(raw.IsRecommend) || bool(raw.IsFeat) || strings.Contains(specials, "recommend")
+	game.IsAvailable = raw.GameStatus == nil || *raw.GameStatus == 1
+	// new games start enabled, an admin switch is never overwritten by a sync
+	game.IsEnabled = true
+
+	game.SortOrder = raw.Order
+	if raw.Orders > 0 {
+		game.SortOrder = raw.Orders
+	}
+	game.ReleasedAt = normalizeReleaseDate(raw)
+	game.SearchNames, game.SearchPhonetic = SearchKeys(game.Name, game.Names)
+
+	return game
+}
+
+// normalizeNames collects every translation of the game name keyed by its
+// lower-case language code (en, zh, th, ...).
+func normalizeNames(raw rawGame) map[string]string {
+
+	names := make(map[string]string)
+	add := func(locale string, name string) {
+		name = strings.TrimSpace(name)
+		lang := normalizeLocale(locale)
+		if name == "" || lang == "" {
+			return
+		}
+		if _, exist := names[lang]; !exist {
+			names[lang] = name
+		}
+	}
+
+	add("en", raw.GameName)
+	add("en", raw.EnUs)
+	add("zh", raw.ZhCn)
+	add("zh", raw.GameNameCN)
+	add("zh", raw.CnGameName)
+	if raw.CnName != nil {
+		add("zh", *raw.CnName)
+	}
+	for locale, name := range raw.I18n {
+		add(locale, name)
+	}
+	for _, translated := range raw.TranslatedNames {
+		add(translated.Locale, translated.Translation)
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+	return names
+}
+
+func normalizeLocale(locale string) string {
+
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if index := strings.IndexAny(locale, "-_"); index > 0 {
+		locale = locale[:index]
+	}
+	return locale
+}
+
+func normalizeGameType(providerType string, defaultType string) string {
+
+	value := strings.ToLower(providerType)
+	switch {
+	case value == "":
+		return defaultType
+	case strings.Contains(value, "fish"), strings.Contains(value, "shoot"):
+		return GameTypeFishing
+	case strings.Contains(value, "poker"):
+		return GameTypePoker
+	case strings.Contains(value, "slot"), strings.Contains(value, "cascade"), strings.Contains(value, "fruit"):
+		return GameTypeSlot
+	case strings.Contains(value, "table"), strings.Contains(value, "blackjack"), strings.Contains(value, "roulette"),
+		strings.Contains(value, "classic"), value == "war":
+		return GameTypeTable
+	case strings.Contains(value, "arcade"):
+		return GameTypeArcade
+	case strings.Contains(value, "bingo"):
+		return GameTypeBingo
+	}
+	return GameTypeOther
+}
+
+func normalizeImageUrl(url string) string {
+
+	url = strings.TrimSpace(url)
+	switch {
+	case url == "":
+		return ""
+	case strings.HasPrefix(url, "//"):
+		return "https:" + url
+	case strings.HasPrefix(url, "/"):
+		return mediaHost + url
+	}
+	return url
+}
+
+// normalizeDevices reads whichever platform hint the provider gives. Games
+// without any hint are assumed to run everywhere.
+func normalizeDevices(raw rawGame) (bool, bool) {
+
+	if len(raw.GameCode.Devices) > 0 {
+		_, desktop := raw.GameCode.Devices["desktop"]
+		_, mobile := raw.GameCode.Devices["mobile"]
+		return desktop, mobile
+	}
+
+	if raw.MobileCapable != nil {
+		return true, *raw.MobileCapable
+	}
+
+	hint := strings.ToLower(raw.Platform + " " + raw.SupportedPlatForms + " " + raw.DeviceType)
+	if strings.TrimSpace(hint) == "" || strings.Contains(hint, "all") {
+		return true, true
+	}
+	desktop := strings.Contains(hint, "desktop") || strings.Contains(hint, "web") || strings.Contains(hint, "pc")
+	mobile := strings.Contains(hint, "mobile")
+	if !desktop && !mobile {
+		return true, true
+	}
+	return desktop, mobile
+}
+
+func normalizeReleaseDate(raw rawGame) *time.Time {
+
+	if raw.DtAdded != "" {
+		if date, err := time.Parse("2006-01-02T15:04:05", raw.DtAdded); err == nil {
+			return &date
+		}
+	}
+	if raw.ReleaseDate != "" {
+		if date, err := time.Parse("2006-01-02", raw.ReleaseDate); err == nil {
+			return &date
+		}
+	}
+	return nil
+}
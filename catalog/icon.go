@@ -0,0 +1,71 @@
+package catalog
+
+import (
+	"context"
+	"crypto/sha1"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// PlaceholderIconName is the storage object served for icons that could not
+// be mirrored.
+const PlaceholderIconName = "games/placeholder.png"
+
+// MaxIconSize caps a downloaded icon, anything larger is not a lobby icon.
+const MaxIconSize = 5 << 20
+
+//go:embed placeholder.png
+var PlaceholderIcon []byte
+
+var iconExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+	"image/bmp":  ".bmp",
+}
+
+// IconObjectName names the mirrored copy of an icon url, stable across runs.
+func IconObjectName(provider string, source string, contentType string) string {
+	sum := sha1.Sum([]byte(source))
+	return fmt.Sprintf("games/%s/%s%s", provider, hex.EncodeToString(sum[:10]), iconExtensions[contentType])
+}
+
+// FetchIcon downloads an icon and checks from its bytes that it is an image.
+func FetchIcon(ctx context.Context, client *http.Client, source string) ([]byte, string, error) {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(res.Body, MaxIconSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > MaxIconSize {
+		return nil, "", errors.New("icon is too large")
+	}
+
+	// the header is not trusted, several hosts answer text/html or octet-stream
+	contentType := http.DetectContentType(data)
+	if _, ok := iconExtensions[contentType]; !ok {
+		return nil, "", fmt.Errorf("not an image (%s)", contentType)
+	}
+
+	return data, contentType, nil
+}
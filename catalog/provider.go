@@ -0,0 +1,52 @@
+package catalog
+
+// Provider describes one game list shipped under json/ and the defaults used
+// when its entries leave a field out.
+type Provider struct {
+	Code        string `json:"code"`
+	File        string `json:"file"`
+	DefaultType string `json:"defaultType"`
+}
+
+var providers = []Provider{
+	{Code: "betsoft", File: "betsoft.json", DefaultType: GameTypeSlot},
+	{Code: "bge", File: "bge.json", DefaultType: GameTypeFishing},
+	{Code: "bng", File: "bng.json", DefaultType: GameTypeSlot},
+	{Code: "cq9", File: "cq9.json", DefaultType: GameTypeSlot},
+	{Code: "cq9v2", File: "cq9v2.json", DefaultType: GameTypeSlot},
+	{Code: "dragongaming", File: "dragongaming.json", DefaultType: GameTypeSlot},
+	{Code: "dreamtech", File: "dreamtech.json", DefaultType: GameTypeSlot},
+	{Code: "fungaming", File: "fungaming.json", DefaultType: GameTypeSlot},
+	{Code: "genesis", File: "genesis.json", DefaultType: GameTypeSlot},
+	{Code: "habanero", File: "habanero.json", DefaultType: GameTypeSlot},
+	{Code: "iconic", File: "iconic.json", DefaultType: GameTypeSlot},
+	{Code: "joker", File: "joker.json", DefaultType: GameTypeSlot},
+	{Code: "mario", File: "mario.json", DefaultType: GameTypeSlot},
+	{Code: "microgaming", File: "microgaming.json", DefaultType: GameTypeSlot},
+	{Code: "mx", File: "mx_gamelist.json", DefaultType: GameTypeSlot},
+	{Code: "png", File: "png.json", DefaultType: GameTypeSlot},
+	{Code: "ptgame", File: "ptgame.json", DefaultType: GameTypeSlot},
+	{Code: "relaxgaming", File: "relaxgaming.json", DefaultType: GameTypeSlot},
+	{Code: "sae", File: "sae.json", DefaultType: GameTypeSlot},
+	{Code: "skywind", File: "skywind.json", DefaultType: GameTypeSlot},
+	{Code: "vt", File: "vt.json", DefaultType: GameTypeSlot},
+	{Code: "ygg", File: "ygg.json", DefaultType: GameTypeSlot},
+}
+
+// Providers returns every provider known to the catalog.
+func Providers() []Provider {
+	list := make([]Provider, len(providers))
+	copy(list, providers)
+	return list
+}
+
+// FindProvider looks a provider up by its code.
+func FindProvider(code string) (*Provider, bool) {
+	for i := range providers {
+		if providers[i].Code == code {
+			p := providers[i]
+			return &p, true
+		}
+	}
+	return nil, false
+}
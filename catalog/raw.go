@@ -0,0 +1,116 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// rawGame is the union of every field the provider files use. Keys are matched
+// exactly first and case-insensitively second, so GameType also picks up the
+// dragongaming "gameType" and Platform the ptgame "platform".
+type rawGame struct {
+	GameCode  rawCode `json:"GameCode"`
+	GameName  string  `json:"GameName"`
+	GameSlug  string  `json:"gameName"`
+	GameType  string  `json:"GameType"`
+	ImageIcon string  `json:"ImageIcon"`
+
+	GameNameCN      string              `json:"GameNameCN"`
+	CnName          *string             `json:"cnName"`
+	CnGameName      string              `json:"cnGameName"`
+	I18n            map[string]string   `json:"i18n"`
+	TranslatedNames []rawTranslatedName `json:"TranslatedNames"`
+	EnUs            string              `json:"en-us"`
+	ZhCn            string              `json:"zh-cn"`
+
+	Platform           string   `json:"Platform"`
+	SupportedPlatForms string   `json:"SupportedPlatForms"`
+	DeviceType         string   `json:"DeviceType"`
+	MobileCapable      *bool    `json:"MobileCapable"`
+	Currency           []string `json:"Currency"`
+
+	IsHot       rawFlag `json:"ishot"`
+	IsNew       rawFlag `json:"IsNew"`
+	IsRecommend rawFlag `json:"isrecommend"`
+	IsFeat      rawFlag `json:"IsFeat"`
+	Specials    *string `json:"Specials"`
+
+	Order       int    `json:"Order"`
+	Orders      int    `json:"orders"`
+	GameStatus  *int   `json:"GameStatus"`
+	ReleaseDate string `json:"ReleaseDate"`
+	DtAdded     string `json:"DtAdded"`
+}
+
+type rawTranslatedName struct {
+	Locale      string `json:"Locale"`
+	Translation string `json:"Translation"`
+}
+
+// rawCode accepts a game code written as a string, a number or, for cq9, an
+// object of codes keyed by device.
+type rawCode struct {
+	Code    string
+	Devices map[string]string
+}
+
+func (c *rawCode) UnmarshalJSON(data []byte) error {
+
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || string(data) == "null" {
+		return nil
+	}
+
+	switch data[0] {
+	case '{':
+		if err := json.Unmarshal(data, &c.Devices); err != nil {
+			return err
+		}
+		c.Code = c.Devices["desktop"]
+		if c.Code == "" {
+			for _, code := range c.Devices {
+				c.Code = code
+				break
+			}
+		}
+	case '"':
+		if err := json.Unmarshal(data, &c.Code); err != nil {
+			return err
+		}
+	default:
+		var number json.Number
+		if err := json.Unmarshal(data, &number); err != nil {
+			return err
+		}
+		c.Code = number.String()
+	}
+
+	c.Code = strings.TrimSpace(c.Code)
+	return nil
+}
+
+// rawFlag accepts both booleans and 0/1 integers.
+type rawFlag bool
+
+func (f *rawFlag) UnmarshalJSON(data []byte) error {
+
+	switch strings.TrimSpace(string(data)) {
+	case "true", "1":
+		*f = true
+	case "false", "0", "null", "":
+		*f = false
+	default:
+		var number json.Number
+		if err := json.Unmarshal(data, &number); err != nil {
+			return err
+		}
+		value, err := number.Int64()
+		if err != nil {
+			return err
+		}
+		*f = value != 0
+	}
+
+	return nil
+}
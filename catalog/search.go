@@ -0,0 +1,246 @@
+package catalog
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// searchSeparator joins the variants stored in search_names and search_phonetic.
+const searchSeparator = "|"
+
+var latinFolding = map[rune]rune{
+	'à': 'a', 'á': 'a', 'â': 'a', 'ã': 'a', 'ä': 'a', 'å': 'a',
+	'ç': 'c',
+	'è': 'e', 'é': 'e', 'ê': 'e', 'ë': 'e',
+	'ì': 'i', 'í': 'i', 'î': 'i', 'ï': 'i',
+	'ñ': 'n',
+	'ò': 'o', 'ó': 'o', 'ô': 'o', 'õ': 'o', 'ö': 'o', 'ø': 'o',
+	'ù': 'u', 'ú': 'u', 'û': 'u', 'ü': 'u',
+	'ý': 'y', 'ÿ': 'y',
+}
+
+// thaiConsonants maps Thai consonants to the latin letter PhoneticKey keeps
+// for the same sound. Vowels, tone marks and the silent อ/ห are dropped.
+var thaiConsonants = map[rune]string{
+	'ก': "k", 'ข': "k", 'ฃ': "k", 'ค': "k", 'ฅ': "k", 'ฆ': "k",
+	'ง': "n",
+	'จ': "c", 'ฉ': "c", 'ช': "c", 'ฌ': "c",
+	'ซ': "s", 'ศ': "s", 'ษ': "s", 'ส': "s",
+	'ญ': "", 'ย': "",
+	'ด': "d", 'ฎ': "d",
+	'ต': "t", 'ฏ': "t", 'ถ': "t", 'ท': "t", 'ธ': "t", 'ฐ': "t", 'ฑ': "t", 'ฒ': "t",
+	'น': "n", 'ณ': "n",
+	'บ': "b",
+	'ป': "p", 'ผ': "p", 'พ': "p", 'ภ': "p",
+	'ฝ': "f", 'ฟ': "f",
+	'ม': "m",
+	'ร': "r", 'ฤ': "r",
+	'ล': "l", 'ฬ': "l", 'ฦ': "l",
+	'ว': "w",
+	'ห': "", 'ฮ': "", 'อ': "",
+}
+
+// latinDigraphs are folded before single letters, longest first.
+var latinDigraphs = []struct{ from, to string }{
+	{"ph", "f"},
+	{"th", "t"},
+	{"sh", "s"},
+	{"ch", "c"},
+	{"kh", "k"},
+	{"ck", "k"},
+	{"ng", "n"},
+}
+
+var latinConsonants = map[rune]string{
+	'b': "b", 'c': "k", 'd': "d", 'f': "f", 'g': "k", 'j': "c", 'k': "k",
+	'l': "l", 'm': "m", 'n': "n", 'p': "p", 'q': "k", 'r': "r", 's': "s",
+	't': "t", 'v': "w", 'w': "w", 'x': "k", 'z': "s",
+}
+
+// thaiToneMarks change the pitch only, members often leave them out.
+var thaiToneMarks = map[rune]bool{'่': true, '้': true, '๊': true, '๋': true}
+
+// NormalizeSearchText lowercases text, strips accents, apostrophes and Thai
+// tone marks and turns punctuation into single spaces, so "Koi Gate!" and
+// "koi  gate" match.
+func NormalizeSearchText(text string) string {
+
+	var builder strings.Builder
+	space := false
+	for _, r := range strings.ToLower(text) {
+		if folded, ok := latinFolding[r]; ok {
+			r = folded
+		}
+		switch {
+		case r == '\'' || r == '’' || thaiToneMarks[r]:
+			continue
+		case unicode.Is(unicode.Mn, r) && !unicode.Is(unicode.Thai, r):
+			// combining accents carry no letter
+			continue
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Thai, r):
+			if space && builder.Len() > 0 {
+				builder.WriteByte(' ')
+			}
+			space = false
+			builder.WriteRune(r)
+		default:
+			space = true
+		}
+	}
+	return builder.String()
+}
+
+// PhoneticKey reduces a name to its consonant sounds so a Thai spelling of an
+// English title lands on the same key, e.g. "Sweet Bonanza" and "สวีทโบนันซ่า"
+// both give "swtbnns".
+func PhoneticKey(text string) string {
+
+	runes := []rune(NormalizeSearchText(text))
+
+	var builder strings.Builder
+	last := ""
+	write := func(sound string) {
+		if sound != "" && sound != last {
+			builder.WriteString(sound)
+		}
+		last = sound
+	}
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		if sound, ok := thaiConsonants[r]; ok {
+			// a consonant under the thanthakhat (์) is not pronounced
+			if i+1 < len(runes) && runes[i+1] == '์' {
+				continue
+			}
+			if sound != "" {
+				write(sound)
+			}
+			continue
+		}
+
+		if r < unicode.MaxASCII && unicode.IsLetter(r) {
+			if i+1 < len(runes) {
+				pair := string(runes[i : i+2])
+				matched := false
+				for _, digraph := range latinDigraphs {
+					if pair == digraph.from {
+						write(digraph.to)
+						i++
+						matched = true
+						break
+					}
+				}
+				if matched {
+					continue
+				}
+			}
+			if sound, ok := latinConsonants[r]; ok {
+				write(sound)
+			} else {
+				// vowels split double letters, "bonanza" keeps both n
+				last = ""
+			}
+			continue
+		}
+
+		if unicode.IsDigit(r) {
+			write(string(r))
+			continue
+		}
+
+		if !unicode.Is(unicode.Thai, r) {
+			// letters of other scripts (Chinese names) are kept as they are
+			if unicode.IsLetter(r) {
+				write(string(r))
+			}
+			continue
+		}
+
+		// Thai vowels
+		last = ""
+	}
+	return builder.String()
+}
+
+// SearchKeys returns every name of the game normalized, and their phonetic
+// keys, ready for the search_names and search_phonetic columns.
+func SearchKeys(name string, names map[string]string) (string, string) {
+
+	variants := []string{name}
+	langs := make([]string, 0, len(names))
+	for lang := range names {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	for _, lang := range langs {
+		variants = append(variants, names[lang])
+	}
+
+	var normalized []string
+	var phonetic []string
+	seen := make(map[string]bool)
+	seenKey := make(map[string]bool)
+	for _, variant := range variants {
+		text := NormalizeSearchText(variant)
+		if text == "" || seen[text] {
+			continue
+		}
+		seen[text] = true
+		normalized = append(normalized, text)
+
+		if key := PhoneticKey(text); key != "" && !seenKey[key] {
+			seenKey[key] = true
+			phonetic = append(phonetic, key)
+		}
+	}
+
+	return strings.Join(normalized, searchSeparator), strings.Join(phonetic, searchSeparator)
+}
+
+// SearchScore ranks how well a query matches the stored keys, 0 is no match.
+// Matches on the written name beat phonetic ones, and whole or leading
+// matches beat a hit in the middle of a name.
+func SearchScore(query string, queryKey string, searchNames string, searchPhonetic string) int {
+
+	score := 0
+	for _, name := range strings.Split(searchNames, searchSeparator) {
+		switch {
+		case name == "" || query == "":
+		case name == query:
+			return 100
+		case strings.HasPrefix(name, query):
+			score = maxScore(score, 80)
+		case strings.Contains(name, " "+query):
+			score = maxScore(score, 60)
+		case strings.Contains(name, query):
+			score = maxScore(score, 40)
+		}
+	}
+
+	if len(queryKey) < 2 {
+		return score
+	}
+
+	for _, key := range strings.Split(searchPhonetic, searchSeparator) {
+		switch {
+		case key == "":
+		case key == queryKey:
+			score = maxScore(score, 30)
+		case strings.HasPrefix(key, queryKey):
+			score = maxScore(score, 20)
+		case strings.Contains(key, queryKey):
+			score = maxScore(score, 10)
+		}
+	}
+	return score
+}
+
+func maxScore(a int, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"cybergame-api/agent"
+	"cybergame-api/agent/fake"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/joho/godotenv"
+)
+
+// go run fakeagent/fakeagent.go -addr :3900
+// then set AGENT_API=http://localhost:3900 in .env
+func main() {
+
+	// the agent name and key come from the same .env the API uses
+	godotenv.Load()
+
+	addr := flag.String("addr", ":3900", "listen address")
+	agentName := flag.String("agent", os.Getenv("AGENT_NAME"), "agent name")
+	key := flag.String("key", os.Getenv("AGENT_KEY"), "agent key")
+	walletType := flag.String("type", os.Getenv("AGENT_TYPE"), "agent wallet type, agc or agc-hmac")
+	flag.Parse()
+
+	if *agentName == "" || *key == "" {
+		log.Fatal("Please set AGENT_NAME and AGENT_KEY or pass -agent and -key")
+	}
+
+	signer, err := agent.NewSigner(*walletType, *key)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	server := fake.NewServer(*agentName, *key)
+	server.Signer = signer
+
+	fmt.Println("Fake agent", *agentName, "listening on", *addr)
+	log.Fatal(http.ListenAndServe(*addr, server))
+}
@@ -0,0 +1,47 @@
+package filestore
+
+import (
+	"context"
+	"io"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/option"
+)
+
+const credentialsFile = "domovie-cb4c3a0a3264.json"
+
+type bucketStorage struct {
+	client *storage.Client
+	bucket string
+}
+
+func newBucketStorage(ctx context.Context, bucket string) (*bucketStorage, error) {
+
+	client, err := storage.NewClient(ctx, option.WithCredentialsFile(credentialsFile))
+	if err != nil {
+		return nil, err
+	}
+
+	return &bucketStorage{client, bucket}, nil
+}
+
+func (s *bucketStorage) Put(ctx context.Context, name string, contentType string, body io.Reader) (string, error) {
+
+	sw := s.client.Bucket(s.bucket).Object(name).NewWriter(ctx)
+	sw.ContentType = contentType
+
+	if _, err := io.Copy(sw, body); err != nil {
+		sw.Close()
+		return "", err
+	}
+
+	if err := sw.Close(); err != nil {
+		return "", err
+	}
+
+	return Pathname(sw.Attrs().Name), nil
+}
+
+func (s *bucketStorage) Close() error {
+	return s.client.Close()
+}
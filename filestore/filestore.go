@@ -0,0 +1,65 @@
+package filestore
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// LocalPrefix is the url path the local backend is served under.
+const LocalPrefix = "/storage"
+
+// Storage keeps uploaded files. The Google Cloud bucket is used unless
+// STORAGE_DRIVER=local, which writes to LOCAL_STORAGE_DIR for dev and tests.
+type Storage interface {
+	// Put writes body as name and returns its pathname, e.g. /bucket/logo/a.png
+	Put(ctx context.Context, name string, contentType string, body io.Reader) (string, error)
+	Close() error
+}
+
+func New(ctx context.Context) (Storage, error) {
+
+	if IsLocal() {
+		return newLocalStorage(LocalDir()), nil
+	}
+	return newBucketStorage(ctx, os.Getenv("NAME_BUCKET"))
+}
+
+func IsLocal() bool {
+	return os.Getenv("STORAGE_DRIVER") == "local"
+}
+
+func LocalDir() string {
+	if dir := os.Getenv("LOCAL_STORAGE_DIR"); dir != "" {
+		return dir
+	}
+	return "storage"
+}
+
+// Pathname is where Put stores an object called name.
+func Pathname(name string) string {
+
+	prefix := "/" + os.Getenv("NAME_BUCKET")
+	if IsLocal() {
+		prefix = LocalPrefix
+	}
+
+	u, err := url.Parse(prefix + "/" + strings.TrimLeft(name, "/"))
+	if err != nil {
+		return prefix + "/" + name
+	}
+	return u.EscapedPath()
+}
+
+// Url turns a pathname into a link browsers can open, prefixed with
+// STORAGE_PUBLIC_URL when set.
+func Url(pathname string) string {
+
+	base := os.Getenv("STORAGE_PUBLIC_URL")
+	if base == "" && !IsLocal() {
+		base = "https://storage.googleapis.com"
+	}
+	return strings.TrimRight(base, "/") + pathname
+}
@@ -0,0 +1,50 @@
+package filestore
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+type localStorage struct {
+	dir string
+}
+
+func newLocalStorage(dir string) *localStorage {
+	return &localStorage{dir}
+}
+
+func (s *localStorage) Put(ctx context.Context, name string, contentType string, body io.Reader) (string, error) {
+
+	// cleaning from the root keeps names like ../x inside dir
+	path := filepath.Join(s.dir, filepath.Clean("/"+name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := io.Copy(file, body); err != nil {
+		file.Close()
+		return "", err
+	}
+
+	if err := file.Close(); err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(file.Name(), path); err != nil {
+		return "", err
+	}
+
+	return Pathname(filepath.ToSlash(filepath.Clean("/" + name))), nil
+}
+
+func (s *localStorage) Close() error {
+	return nil
+}
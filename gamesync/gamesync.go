@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"cybergame-api/catalog"
+	"cybergame-api/model"
+	"cybergame-api/repository"
+	"cybergame-api/service"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// go run gamesync/gamesync.go preview habanero
+// go run gamesync/gamesync.go apply all
+// go run gamesync/gamesync.go icons all
+func main() {
+
+	args := os.Args[1:]
+
+	if len(args) < 2 || (args[0] != "preview" && args[0] != "apply" && args[0] != "icons") {
+		log.Fatal("Please input command preview, apply or icons and a provider code or all")
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Fatal(err)
+	}
+
+	if args[0] == "icons" {
+		mirrorIcons(args[1])
+		return
+	}
+
+	var codes []string
+	if args[1] == "all" {
+		for _, provider := range catalog.Providers() {
+			codes = append(codes, provider.Code)
+		}
+	} else {
+		codes = append(codes, args[1])
+	}
+
+	gameService := service.NewGameService(repository.NewGameRepository(initDatabase()))
+
+	var pending []string
+	for _, code := range codes {
+		result, err := gameService.PreviewGameCatalogSync(model.GameSyncRequest{Provider: code, Dir: catalog.DefaultDir})
+		if err != nil {
+			log.Fatal(code, ": ", err)
+		}
+		printResult(result)
+		if len(result.Added) > 0 || len(result.Removed) > 0 || len(result.Changed) > 0 {
+			pending = append(pending, code)
+		}
+	}
+
+	if args[0] == "preview" {
+		return
+	}
+
+	if len(pending) == 0 {
+		fmt.Println("ไม่มีรายการที่ต้องอัพเดท")
+		return
+	}
+
+	fmt.Println("กด y เพื่ออัพเดทรายการเกม หรือ กดปุ่มอื่นเพื่อยกเลิก")
+
+	reader := bufio.NewReader(os.Stdin)
+	char, _, err := reader.ReadRune()
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	if char != 'y' {
+		os.Exit(0)
+	}
+
+	for _, code := range pending {
+		if _, err := gameService.ApplyGameCatalogSync(model.GameSyncRequest{Provider: code, Dir: catalog.DefaultDir}); err != nil {
+			log.Fatal(code, ": ", err)
+		}
+		fmt.Println(code, "updated")
+	}
+}
+
+func mirrorIcons(provider string) {
+
+	req := model.GameIconMirrorRequest{Provider: provider}
+	if provider == "all" {
+		req.Provider = ""
+	}
+
+	gameService := service.NewGameService(repository.NewGameRepository(initDatabase()))
+
+	result, err := gameService.MirrorGameIcons(context.Background(), req)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("icons total %d, success %d, failed %d\n", result.Total, result.Success, result.Failed)
+}
+
+func printResult(result *model.GameSyncResult) {
+
+	fmt.Printf("[%s] added %d, removed %d, changed %d, unchanged %d\n",
+		result.Provider, len(result.Added), len(result.Removed), len(result.Changed), result.Unchanged)
+
+	for _, item := range result.Added {
+		fmt.Printf("  + %s %s\n", item.GameCode, item.Name)
+	}
+	for _, item := range result.Removed {
+		fmt.Printf("  - %s %s\n", item.GameCode, item.Name)
+	}
+	for _, item := range result.Changed {
+		fmt.Printf("  ~ %s %s %v\n", item.GameCode, item.Name, item.Fields)
+	}
+}
+
+func initDatabase() *gorm.DB {
+
+	dsn := fmt.Sprintf("%v:%v@tcp(%v:%v)/%v?parseTime=true",
+		os.Getenv("DB_USER"),
+		os.Getenv("DB_PASS"),
+		os.Getenv("DB_HOST"),
+		os.Getenv("DB_PORT"),
+		os.Getenv("DB_NAME"),
+	)
+	db, err := gorm.Open(mysql.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	return db
+}
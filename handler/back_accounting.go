@@ -27,8 +27,9 @@ func newAccountingController(
 func AccountingController(r *gin.RouterGroup, db *gorm.DB) {
 
 	repo := repository.NewAccountingRepository(db)
-	service := service.NewAccountingService(repo)
+	service := service.NewAccountingService(repo, repository.NewUnitOfWork(db))
 	handler := newAccountingController(service)
+	idempotency := middleware.Idempotency(db)
 
 	root := r.Group("/accounting")
 	root.GET("/autocreditflags/list", middleware.Authorize, handler.getAutoCreditFlags)
@@ -48,7 +49,7 @@ func AccountingController(r *gin.RouterGroup, db *gorm.DB) {
 	accountRoute := root.Group("/bankaccounts")
 	accountRoute.GET("/list", middleware.Authorize, handler.getBankAccounts)
 	accountRoute.GET("/detail/:id", middleware.Authorize, handler.getBankAccountById)
-	accountRoute.POST("", middleware.Authorize, handler.createBankAccount)
+	accountRoute.POST("", middleware.Authorize, idempotency, handler.createBankAccount)
 	accountRoute.PATCH("/:id", middleware.Authorize, handler.updateBankAccount)
 	accountRoute.DELETE("/:id", middleware.Authorize, handler.deleteBankAccount)
 
@@ -58,14 +59,14 @@ func AccountingController(r *gin.RouterGroup, db *gorm.DB) {
 	account2Route.GET("/list", middleware.Authorize, handler.getExternalAccounts)
 	account2Route.GET("/status/:account", middleware.Authorize, handler.getExternalAccountStatus)
 	account2Route.GET("/balance/:account", middleware.Authorize, handler.getExternalAccountBalance)
-	account2Route.POST("", middleware.Authorize, handler.createExternalAccount)
+	account2Route.POST("", middleware.Authorize, idempotency, handler.createExternalAccount)
 	account2Route.PUT("", middleware.Authorize, handler.updateExternalAccount)
 	account2Route.PUT("/status", middleware.Authorize, handler.EnableExternalAccount)
 	account2Route.DELETE("/:account", middleware.Authorize, handler.deleteExternalAccount)
-	account2Route.POST("/transfer", middleware.Authorize, handler.transferExternalAccount)
+	account2Route.POST("/transfer", middleware.Authorize, idempotency, handler.transferExternalAccount)
 	account2Route.GET("/logs", middleware.Authorize, handler.getExternalAccountLogs)
 	account2Route.GET("/statements", middleware.Authorize, handler.getExternalAccountStatements)
-	account2Route.POST("/config", middleware.Authorize, handler.createBotaccountConfig)
+	account2Route.POST("/config", middleware.Authorize, idempotency, handler.createBotaccountConfig)
 
 	webhookRoute := root.Group("/webhooks")
 	webhookRoute.POST("/action", handler.webhookAction)
@@ -74,20 +75,20 @@ func AccountingController(r *gin.RouterGroup, db *gorm.DB) {
 	transactionRoute := root.Group("/transactions")
 	transactionRoute.GET("/list", middleware.Authorize, handler.getTransactions)
 	transactionRoute.GET("/detail/:id", middleware.Authorize, handler.getTransactionById)
-	transactionRoute.POST("", middleware.Authorize, handler.createTransaction)
+	transactionRoute.POST("", middleware.Authorize, idempotency, handler.createTransaction)
 	transactionRoute.DELETE("/:id", middleware.Authorize, handler.deleteTransaction)
 
 	transferRoute := root.Group("/transfers")
 	transferRoute.GET("/list", middleware.Authorize, handler.getTransfers)
 	transferRoute.GET("/detail/:id", middleware.Authorize, handler.getTransferById)
-	transferRoute.POST("", middleware.Authorize, handler.createTransfer)
-	transferRoute.POST("/confirm/:id", middleware.Authorize, handler.confirmTransfer)
+	transferRoute.POST("", middleware.Authorize, idempotency, handler.createTransfer)
+	transferRoute.POST("/confirm/:id", middleware.Authorize, idempotency, handler.confirmTransfer)
 	transferRoute.DELETE("/:id", middleware.Authorize, handler.deleteTransfer)
 
 	statementRoute := root.Group("/statements")
 	statementRoute.GET("/list", middleware.Authorize, handler.getAccountStatements)
 	statementRoute.GET("/detail/:id", middleware.Authorize, handler.getAccountStatementById)
-	statementRoute.POST("/webhook", middleware.Authorize, handler.addAccountStatementToWebhook)
+	statementRoute.POST("/webhook", middleware.Authorize, idempotency, handler.addAccountStatementToWebhook)
 
 }
 
@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"cybergame-api/middleware"
+	"cybergame-api/model"
+	"cybergame-api/repository"
+	"cybergame-api/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type agentCallController struct {
+	agentCallService service.AgentCallService
+}
+
+func newAgentCallController(
+	agentCallService service.AgentCallService,
+) agentCallController {
+	return agentCallController{agentCallService}
+}
+
+func AgentCallController(r *gin.RouterGroup, db *gorm.DB) {
+
+	repo := repository.NewAgentCallRepository(db)
+	repoAgentConnect := repository.NewAgentConnectRepository(db)
+	service := service.NewAgentCallService(repo, repoAgentConnect)
+	handler := newAgentCallController(service)
+
+	r = r.Group("/agent-calls")
+	r.GET("/list", middleware.Authorize, handler.getAgentCalls)
+	r.GET("/detail/:id", middleware.Authorize, handler.getAgentCallById)
+	r.POST("/retry/:id", middleware.Authorize, handler.retryAgentCall)
+}
+
+// @Summary Get Agent Call Journal
+// @Description Credit transfers sent to the agent with their attempts
+// @Tags Agent Calls
+// @Security BearerAuth
+// @Accept  json
+// @Produce  json
+// @Param _ query model.AgentCallJournalListRequest true "Query Agent Call"
+// @Success 200 {object} model.SuccessWithPagination
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /agent-calls/list [get]
+func (h agentCallController) getAgentCalls(c *gin.Context) {
+
+	var query model.AgentCallJournalListRequest
+	if err := c.ShouldBind(&query); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	data, err := h.agentCallService.GetAgentCallJournals(query)
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	c.JSON(200, model.SuccessWithPagination{Message: "Success", List: data.List, Total: data.Total})
+}
+
+// @Summary Get Agent Call By Id
+// @Description Get Agent Call By Id
+// @Tags Agent Calls
+// @Security BearerAuth
+// @Accept  json
+// @Produce  json
+// @Param id path int true "id"
+// @Success 200 {object} model.AgentCallJournal
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /agent-calls/detail/{id} [get]
+func (h agentCallController) getAgentCallById(c *gin.Context) {
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	data, err := h.agentCallService.GetAgentCallJournalById(id)
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	c.JSON(200, data)
+}
+
+// @Summary Retry Agent Call
+// @Description Send a waiting or failed call again with the same TransactionId
+// @Tags Agent Calls
+// @Security BearerAuth
+// @Accept  json
+// @Produce  json
+// @Param id path int true "id"
+// @Success 200 {object} model.Success
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /agent-calls/retry/{id} [post]
+func (h agentCallController) retryAgentCall(c *gin.Context) {
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	if err := h.agentCallService.RetryAgentCall(c.Request.Context(), id); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	c.JSON(200, model.Success{Message: "Update success"})
+}
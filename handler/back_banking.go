@@ -30,9 +30,12 @@ func BankingController(r *gin.RouterGroup, db *gorm.DB) {
 	repoBanking := repository.NewBankingRepository(db)
 	repoAccounting := repository.NewAccountingRepository(db)
 	repoAgentConnect := repository.NewAgentConnectRepository(db)
-	service1 := service.NewBankingService(repoBanking, repoAccounting, repoAgentConnect)
-	service2 := service.NewAccountingService(repoAccounting)
+	repoAgentCall := repository.NewAgentCallRepository(db)
+	uow := repository.NewUnitOfWork(db)
+	service1 := service.NewBankingService(repoBanking, repoAccounting, repoAgentConnect, repoAgentCall, uow)
+	service2 := service.NewAccountingService(repoAccounting, uow)
 	handler := newBankingController(service1, service2)
+	idempotency := middleware.Idempotency(db)
 
 	root := r.Group("/banking")
 	root.GET("/transactiontypes/list", middleware.Authorize, handler.getTransactionTypes)
@@ -43,9 +46,9 @@ func BankingController(r *gin.RouterGroup, db *gorm.DB) {
 	statementRoute.GET("/list", middleware.Authorize, handler.getBankStatements)
 	statementRoute.GET("/summary", middleware.Authorize, handler.getBankStatementSummary)
 	statementRoute.GET("/detail/:id", middleware.Authorize, handler.getBankStatementById)
-	statementRoute.POST("", middleware.Authorize, handler.createBankStatement)
-	statementRoute.POST("/matchowner/:id", middleware.Authorize, handler.matchStatementOwner)
-	statementRoute.POST("/ignoreowner/:id", middleware.Authorize, handler.ignoreStatementOwner)
+	statementRoute.POST("", middleware.Authorize, idempotency, handler.createBankStatement)
+	statementRoute.POST("/matchowner/:id", middleware.Authorize, idempotency, handler.matchStatementOwner)
+	statementRoute.POST("/ignoreowner/:id", middleware.Authorize, idempotency, handler.ignoreStatementOwner)
 	statementRoute.DELETE("/:id", middleware.Authorize, handler.deleteBankStatement)
 
 	transactionRoute := root.Group("/transactions")
@@ -55,21 +58,29 @@ func BankingController(r *gin.RouterGroup, db *gorm.DB) {
 	transactionRoute.GET("/count_deposit_statuses", middleware.Authorize, handler.getBankDepositTransStatusCounts)
 	transactionRoute.GET("/count_withdraw_statuses", middleware.Authorize, handler.getBankWithdrawTransStatusCounts)
 	transactionRoute.GET("/detail/:id", middleware.Authorize, handler.getBankTransactionById)
-	transactionRoute.POST("", middleware.Authorize, handler.createBankTransaction)
+	transactionRoute.POST("", middleware.Authorize, idempotency, handler.createBankTransaction)
 	transactionRoute.PATCH("/:id", middleware.Authorize, handler.updateBankTransaction)
-	transactionRoute.POST("/bonus", middleware.Authorize, handler.createBonusTransaction)
+	transactionRoute.POST("/bonus", middleware.Authorize, idempotency, handler.createBonusTransaction)
 
 	transactionRoute.GET("/pendingdepositlist", middleware.Authorize, handler.getPendingDepositTransactions)
 	transactionRoute.GET("/pendingwithdrawlist", middleware.Authorize, handler.getPendingWithdrawTransactions)
-	transactionRoute.POST("/cancel/:id", middleware.Authorize, handler.cancelPendingTransaction)
-	transactionRoute.POST("/confirmdeposit/:id", middleware.Authorize, handler.confirmDepositTransaction)
-	transactionRoute.POST("/confirmdepositcredit/:id", middleware.Authorize, handler.confirmDepositCreditTransaction)
-	transactionRoute.POST("/confirmcreditwithdraw/:id", middleware.Authorize, handler.confirmCreditWithdrawTransaction)
-	transactionRoute.POST("/confirmtransferwithdraw/:id", middleware.Authorize, handler.confirmTransferWithdrawTransaction)
-	transactionRoute.POST("/continueautowithdraw/:id", middleware.Authorize, handler.continueAutoWithdrawTransaction)
+	transactionRoute.POST("/cancel/:id", middleware.Authorize, idempotency, handler.cancelPendingTransaction)
+	transactionRoute.POST("/confirmdeposit/:id", middleware.Authorize, idempotency, handler.confirmDepositTransaction)
+	transactionRoute.POST("/confirmdepositcredit/:id", middleware.Authorize, idempotency, handler.confirmDepositCreditTransaction)
+	transactionRoute.POST("/confirmcreditwithdraw/:id", middleware.Authorize, idempotency, handler.confirmCreditWithdrawTransaction)
+	transactionRoute.POST("/confirmtransferwithdraw/:id", middleware.Authorize, idempotency, handler.confirmTransferWithdrawTransaction)
+	transactionRoute.POST("/continueautowithdraw/:id", middleware.Authorize, idempotency, handler.continueAutoWithdrawTransaction)
 	transactionRoute.GET("/finishedlist", middleware.Authorize, handler.getFinishedTransactions)
-	transactionRoute.POST("/remove/:id", middleware.Authorize, handler.removeFinishedTransaction)
+	transactionRoute.POST("/remove/:id", middleware.Authorize, idempotency, handler.removeFinishedTransaction)
 	transactionRoute.GET("/removedlist", middleware.Authorize, handler.getRemovedTransactions)
+	transactionRoute.POST("/bulkconfirmdeposit", middleware.Authorize, idempotency, handler.bulkConfirmDepositTransactions)
+	transactionRoute.POST("/bulkconfirmdepositcredit", middleware.Authorize, idempotency, handler.bulkConfirmDepositCredits)
+	transactionRoute.POST("/bulkconfirmcreditwithdraw", middleware.Authorize, idempotency, handler.bulkConfirmCreditWithdrawTransactions)
+	transactionRoute.POST("/bulkcancel", middleware.Authorize, idempotency, handler.bulkCancelPendingTransactions)
+
+	withdrawRiskRoute := root.Group("/withdrawriskrules")
+	withdrawRiskRoute.GET("/list", middleware.Authorize, handler.getWithdrawRiskRules)
+	withdrawRiskRoute.PATCH("/:id", middleware.Authorize, handler.updateWithdrawRiskRule)
 
 	memberRoute := root.Group("/member")
 	memberRoute.GET("/info/:code", middleware.Authorize, handler.getMemberByCode)
@@ -79,11 +90,12 @@ func BankingController(r *gin.RouterGroup, db *gorm.DB) {
 	memberRoute.GET("/transactions", middleware.Authorize, handler.getMemberTransactions)
 	memberRoute.GET("/statements", middleware.Authorize, handler.getMemberStatements)
 	memberRoute.GET("/statements/detail/:id", middleware.Authorize, handler.getMemberStatementById)
+	memberRoute.POST("/creditsync", middleware.Authorize, idempotency, handler.syncMemberCredits)
 	// TEST
-	memberRoute.POST("/statements1", middleware.Authorize, handler.processMemberDepositCredit)
-	memberRoute.POST("/statements2", middleware.Authorize, handler.processMemberWithdrawCredit)
-	memberRoute.POST("/statements3", middleware.Authorize, handler.processMemberBonusCredit)
-	memberRoute.POST("/statements4", middleware.Authorize, handler.processMemberGetbackCredit)
+	memberRoute.POST("/statements1", middleware.Authorize, idempotency, handler.processMemberDepositCredit)
+	memberRoute.POST("/statements2", middleware.Authorize, idempotency, handler.processMemberWithdrawCredit)
+	memberRoute.POST("/statements3", middleware.Authorize, idempotency, handler.processMemberBonusCredit)
+	memberRoute.POST("/statements4", middleware.Authorize, idempotency, handler.processMemberGetbackCredit)
 
 }
 
@@ -579,7 +591,7 @@ func (h bankingController) createBankTransaction(c *gin.Context) {
 	}
 	banking.CreatedByUserId = *adminId
 	banking.CreatedByUsername = *username
-	if err := h.bankingService.CreateBankTransaction(banking); err != nil {
+	if err := h.bankingService.CreateBankTransaction(c.Request.Context(), banking); err != nil {
 		HandleError(c, err)
 		return
 	}
@@ -750,6 +762,7 @@ func (h bankingController) getPendingWithdrawTransactions(c *gin.Context) {
 // @Param body body model.BankTransactionCancelBody true "body"
 // @Success 201 {object} model.Success
 // @Failure 400 {object} handler.ErrorResponse
+// @Failure 409 {object} handler.ErrorResponse
 // @Router /banking/transactions/cancel/{id} [post]
 func (h bankingController) cancelPendingTransaction(c *gin.Context) {
 
@@ -781,13 +794,13 @@ func (h bankingController) cancelPendingTransaction(c *gin.Context) {
 		return
 	}
 
-	data.Status = "canceled"
+	data.Status = model.BankTransactionCanceled
 	// data.CancelRemark = data.CancelRemark
 	data.CanceledAt = time.Now()
 	data.CanceledByUserId = *adminId
 	data.CanceledByUsername = *username
 
-	actionErr := h.bankingService.CancelPendingTransaction(identifier, data)
+	actionErr := h.bankingService.CancelPendingTransaction(c.Request.Context(), identifier, data)
 	if actionErr != nil {
 		HandleError(c, actionErr)
 		return
@@ -805,6 +818,7 @@ func (h bankingController) cancelPendingTransaction(c *gin.Context) {
 // @Param body body model.BankConfirmDepositRequest true "body"
 // @Success 201 {object} model.Success
 // @Failure 400 {object} handler.ErrorResponse
+// @Failure 409 {object} handler.ErrorResponse
 // @Router /banking/transactions/confirmdeposit/{id} [post]
 func (h bankingController) confirmDepositTransaction(c *gin.Context) {
 
@@ -856,6 +870,7 @@ func (h bankingController) confirmDepositTransaction(c *gin.Context) {
 // @Param body body model.BankConfirmDepositRequest true "body"
 // @Success 201 {object} model.Success
 // @Failure 400 {object} handler.ErrorResponse
+// @Failure 409 {object} handler.ErrorResponse
 // @Router /banking/transactions/confirmdepositcredit/{id} [post]
 func (h bankingController) confirmDepositCreditTransaction(c *gin.Context) {
 
@@ -907,6 +922,7 @@ func (h bankingController) confirmDepositCreditTransaction(c *gin.Context) {
 // @Param body body model.BankConfirmCreditWithdrawRequest true "body"
 // @Success 201 {object} model.Success
 // @Failure 400 {object} handler.ErrorResponse
+// @Failure 409 {object} handler.ErrorResponse
 // @Router /banking/transactions/confirmcreditwithdraw/{id} [post]
 func (h bankingController) confirmCreditWithdrawTransaction(c *gin.Context) {
 
@@ -940,7 +956,7 @@ func (h bankingController) confirmCreditWithdrawTransaction(c *gin.Context) {
 	req.ConfirmedByUserId = *adminId
 	req.ConfirmedByUsername = *username
 
-	actionErr := h.bankingService.ConfirmWithdrawTransaction(identifier, req)
+	actionErr := h.bankingService.ConfirmWithdrawTransaction(c.Request.Context(), identifier, req)
 	if actionErr != nil {
 		HandleError(c, actionErr)
 		return
@@ -958,6 +974,7 @@ func (h bankingController) confirmCreditWithdrawTransaction(c *gin.Context) {
 // @Param body body model.BankConfirmTransferWithdrawRequest true "body"
 // @Success 201 {object} model.Success
 // @Failure 400 {object} handler.ErrorResponse
+// @Failure 409 {object} handler.ErrorResponse
 // @Router /banking/transactions/confirmtransferwithdraw/{id} [post]
 func (h bankingController) confirmTransferWithdrawTransaction(c *gin.Context) {
 
@@ -1041,7 +1058,7 @@ func (h bankingController) continueAutoWithdrawTransaction(c *gin.Context) {
 	req.ConfirmedByUserId = *adminId
 	req.ConfirmedByUsername = *username
 
-	actionErr := h.bankingService.ContinueAutoWithdrawTransaction(identifier)
+	actionErr := h.bankingService.ContinueAutoWithdrawTransaction(c.Request.Context(), identifier)
 	if actionErr != nil {
 		HandleError(c, actionErr)
 		return
@@ -1089,6 +1106,7 @@ func (h bankingController) getFinishedTransactions(c *gin.Context) {
 // @Param id path int true "id"
 // @Success 201 {object} model.Success
 // @Failure 400 {object} handler.ErrorResponse
+// @Failure 409 {object} handler.ErrorResponse
 // @Router /banking/transactions/remove/{id} [post]
 func (h bankingController) removeFinishedTransaction(c *gin.Context) {
 
@@ -1111,7 +1129,7 @@ func (h bankingController) removeFinishedTransaction(c *gin.Context) {
 	}
 
 	var data model.BankTransactionRemoveBody
-	data.Status = "removed"
+	data.Status = model.BankTransactionRemoved
 	data.RemovedAt = time.Now()
 	data.RemovedByUserId = *adminId
 	data.RemovedByUsername = *username
@@ -1155,6 +1173,172 @@ func (h bankingController) getRemovedTransactions(c *gin.Context) {
 	c.JSON(200, model.SuccessWithPagination{List: data.List, Total: data.Total})
 }
 
+// @Summary BulkConfirmDepositTransactions ยืนยันข้อมูลการฝาก หลายรายการ
+// @Description ยืนยันข้อมูลการฝาก เฉยๆ ทีละหลายรายการ ผลของแต่ละรายการอยู่ใน items
+// @Tags Banking - Bank Transaction
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param body body model.BankTransactionBulkRequest true "body"
+// @Success 200 {object} model.BankTransactionBulkResult
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /banking/transactions/bulkconfirmdeposit [post]
+func (h bankingController) bulkConfirmDepositTransactions(c *gin.Context) {
+
+	username, err := h.accountingService.CheckCurrentUsername(c.MustGet("username"))
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+	adminId, err := h.accountingService.CheckCurrentAdminId(c.MustGet("adminId"))
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	var body model.BankTransactionBulkRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		HandleError(c, err)
+		return
+	}
+	if err := validator.New().Struct(body); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	var req model.BankConfirmDepositRequest
+	req.ConfirmedAt = time.Now()
+	req.ConfirmedByUserId = *adminId
+	req.ConfirmedByUsername = *username
+
+	c.JSON(200, h.bankingService.BulkConfirmDepositTransactions(c.Request.Context(), body.Ids, req))
+}
+
+// @Summary BulkConfirmDepositCredits ยืนยันข้อมูลการฝาก เพื่ออนุมัติเครดิต หลายรายการ
+// @Description ยืนยันข้อมูลการฝาก เพื่ออนุมัติเครดิต ทีละหลายรายการ ผลของแต่ละรายการอยู่ใน items
+// @Tags Banking - Bank Transaction
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param body body model.BankTransactionBulkRequest true "body"
+// @Success 200 {object} model.BankTransactionBulkResult
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /banking/transactions/bulkconfirmdepositcredit [post]
+func (h bankingController) bulkConfirmDepositCredits(c *gin.Context) {
+
+	username, err := h.accountingService.CheckCurrentUsername(c.MustGet("username"))
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+	adminId, err := h.accountingService.CheckCurrentAdminId(c.MustGet("adminId"))
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	var body model.BankTransactionBulkRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		HandleError(c, err)
+		return
+	}
+	if err := validator.New().Struct(body); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	var req model.BankConfirmDepositRequest
+	req.ConfirmedAt = time.Now()
+	req.ConfirmedByUserId = *adminId
+	req.ConfirmedByUsername = *username
+
+	c.JSON(200, h.bankingService.BulkConfirmDepositCredits(c.Request.Context(), body.Ids, req))
+}
+
+// @Summary BulkConfirmCreditWithdrawTransactions ยืนยันรายการถอน หลายรายการ
+// @Description ยืนยันรายการถอน ในสถานะ รออนุมัติเครดิต ทีละหลายรายการ ผลของแต่ละรายการอยู่ใน items
+// @Tags Banking - Bank Transaction
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param body body model.BankTransactionBulkRequest true "body"
+// @Success 200 {object} model.BankTransactionBulkResult
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /banking/transactions/bulkconfirmcreditwithdraw [post]
+func (h bankingController) bulkConfirmCreditWithdrawTransactions(c *gin.Context) {
+
+	username, err := h.accountingService.CheckCurrentUsername(c.MustGet("username"))
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+	adminId, err := h.accountingService.CheckCurrentAdminId(c.MustGet("adminId"))
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	var body model.BankTransactionBulkRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		HandleError(c, err)
+		return
+	}
+	if err := validator.New().Struct(body); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	var req model.BankConfirmCreditWithdrawRequest
+	req.ConfirmedAt = time.Now()
+	req.ConfirmedByUserId = *adminId
+	req.ConfirmedByUsername = *username
+
+	c.JSON(200, h.bankingService.BulkConfirmWithdrawTransactions(c.Request.Context(), body.Ids, req))
+}
+
+// @Summary BulkCancelPendingTransactions ยกเลิก ข้อมูลการฝากและถอน ที่รอยืนยัน หลายรายการ
+// @Description ยกเลิก ข้อมูลการฝากและถอน ที่รอยืนยัน ทีละหลายรายการ ผลของแต่ละรายการอยู่ใน items
+// @Tags Banking - Bank Transaction
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param body body model.BankTransactionBulkCancelRequest true "body"
+// @Success 200 {object} model.BankTransactionBulkResult
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /banking/transactions/bulkcancel [post]
+func (h bankingController) bulkCancelPendingTransactions(c *gin.Context) {
+
+	adminId, err := h.accountingService.CheckCurrentAdminId(c.MustGet("adminId"))
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+	username, err := h.accountingService.CheckCurrentUsername(c.MustGet("username"))
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	var body model.BankTransactionBulkCancelRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		HandleError(c, err)
+		return
+	}
+	if err := validator.New().Struct(body); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	var data model.BankTransactionCancelBody
+	data.Status = model.BankTransactionCanceled
+	data.CancelRemark = body.CancelRemark
+	data.CanceledAt = time.Now()
+	data.CanceledByUserId = *adminId
+	data.CanceledByUsername = *username
+
+	c.JSON(200, h.bankingService.BulkCancelPendingTransactions(c.Request.Context(), body.Ids, data))
+}
+
 // @Summary GetMemberByCode
 // @Description ดึงข้อมูลสมาชิกด้วยโค้ด
 // @Tags Banking - Member Transaction
@@ -1444,6 +1628,32 @@ func (h bankingController) processMemberBonusCredit(c *gin.Context) {
 	c.JSON(201, model.Success{Message: "Created success"})
 }
 
+// @Summary SyncMemberCredits
+// @Description เทียบเครดิตสมาชิกกับยอดในเอเย่นต์ และปรับยอดที่ไม่ตรงกัน ไม่ระบุ userId จะตรวจทุกคน
+// @Tags Banking - Member Statement
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param body body model.MemberCreditSyncBody true "body"
+// @Success 200 {object} model.MemberCreditSyncResult
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /banking/member/creditsync [post]
+func (h bankingController) syncMemberCredits(c *gin.Context) {
+
+	var body model.MemberCreditSyncBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	result, err := h.bankingService.SyncMemberCredits(c.Request.Context(), body)
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+	c.JSON(200, result)
+}
+
 // @Summary ProcessMemberGetbackCredit
 // @Description สร้างข้อมูลรายการทางการเงิน
 // @Tags Banking - Member Statement
@@ -1472,3 +1682,72 @@ func (h bankingController) processMemberGetbackCredit(c *gin.Context) {
 	}
 	c.JSON(201, model.Success{Message: "Created success"})
 }
+
+// @Summary GetWithdrawRiskRules กฎตรวจสอบการถอนอัตโนมัติ
+// @Description ดึงข้อมูลกฎที่ทำให้รายการถอนต้องรอแอดมินตรวจสอบ แทนการถอนอัตโนมัติ
+// @Tags Banking - Withdraw Risk Rules
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.SuccessWithPagination
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /banking/withdrawriskrules/list [get]
+func (h bankingController) getWithdrawRiskRules(c *gin.Context) {
+
+	list, err := h.bankingService.GetWithdrawRiskRules()
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+	c.JSON(200, model.SuccessWithPagination{List: list, Total: int64(len(list))})
+}
+
+// @Summary UpdateWithdrawRiskRule แก้ไขกฎตรวจสอบการถอนอัตโนมัติ
+// @Description เปิด/ปิดกฎ และตั้งยอดเงิน (amount) หรือจำนวนชั่วโมง (hours) ของกฎ ส่งมาเฉพาะช่องที่แก้ไข
+// @Tags Banking - Withdraw Risk Rules
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path int true "id"
+// @Param body body model.WithdrawRiskRuleUpdateRequest true "body"
+// @Success 201 {object} model.Success
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /banking/withdrawriskrules/{id} [patch]
+func (h bankingController) updateWithdrawRiskRule(c *gin.Context) {
+
+	adminId, err := h.accountingService.CheckCurrentAdminId(c.MustGet("adminId"))
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+	username, err := h.accountingService.CheckCurrentUsername(c.MustGet("username"))
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	id := c.Param("id")
+	identifier, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	var req model.WithdrawRiskRuleUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		HandleError(c, err)
+		return
+	}
+	if err := validator.New().Struct(req); err != nil {
+		HandleError(c, err)
+		return
+	}
+	req.UpdatedByUserId = *adminId
+	req.UpdatedByUsername = *username
+
+	if err := h.bankingService.UpdateWithdrawRiskRule(identifier, req); err != nil {
+		HandleError(c, err)
+		return
+	}
+	c.JSON(201, model.Success{Message: "Update success"})
+}
@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"cybergame-api/middleware"
+	"cybergame-api/model"
+	"cybergame-api/repository"
+	"cybergame-api/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+type betController struct {
+	betService service.BetService
+}
+
+func newBetController(
+	betService service.BetService,
+) betController {
+	return betController{betService}
+}
+
+func BetController(r *gin.RouterGroup, db *gorm.DB) {
+
+	repo := repository.NewBetRepository(db)
+	repoAgentConnect := repository.NewAgentConnectRepository(db)
+	service := service.NewBetService(repo, repoAgentConnect)
+	handler := newBetController(service)
+
+	r = r.Group("/bets")
+	r.GET("/list", middleware.Authorize, handler.getBets)
+	r.POST("/import", middleware.Authorize, handler.importBets)
+	r.POST("/sync", middleware.Authorize, handler.syncAgentBets)
+	r.GET("/turnover/:id", middleware.Authorize, handler.getMemberTurnover)
+}
+
+// @Summary Get Bet List
+// @Description Get Bet List
+// @Tags Bets
+// @Security BearerAuth
+// @Accept  json
+// @Produce  json
+// @Param _ query model.BetListRequest true "Query Bet"
+// @Success 200 {object} model.SuccessWithPagination
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /bets/list [get]
+func (h betController) getBets(c *gin.Context) {
+
+	var query model.BetListRequest
+	if err := c.ShouldBind(&query); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	data, err := h.betService.GetBets(query)
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	c.JSON(200, model.SuccessWithPagination{Message: "Success", List: data.List, Total: data.Total})
+}
+
+// @Summary Import Bets
+// @Description Save pushed bet records, a bet sent again updates the first one
+// @Tags Bets
+// @Security BearerAuth
+// @Accept  json
+// @Produce  json
+// @Param body body model.BetImportBody true "body"
+// @Success 200 {object} model.BetImportResult
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /bets/import [post]
+func (h betController) importBets(c *gin.Context) {
+
+	var body model.BetImportBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	if err := validator.New().Struct(body); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	result, err := h.betService.ImportBets(body)
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	c.JSON(200, result)
+}
+
+// @Summary Sync Agent Bets
+// @Description Pull the bet history from the agent, by default since the last imported bet
+// @Tags Bets
+// @Security BearerAuth
+// @Accept  json
+// @Produce  json
+// @Param body body model.BetSyncBody true "body"
+// @Success 200 {object} model.BetImportResult
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /bets/sync [post]
+func (h betController) syncAgentBets(c *gin.Context) {
+
+	var body model.BetSyncBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	result, err := h.betService.SyncAgentBets(c.Request.Context(), body)
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	c.JSON(200, result)
+}
+
+// @Summary Get Member Turnover
+// @Description Turnover of the member against its turnover limit
+// @Tags Bets
+// @Security BearerAuth
+// @Accept  json
+// @Produce  json
+// @Param id path int true "user id"
+// @Success 200 {object} model.MemberTurnover
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /bets/turnover/{id} [get]
+func (h betController) getMemberTurnover(c *gin.Context) {
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	data, err := h.betService.GetMemberTurnover(id)
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	c.JSON(200, data)
+}
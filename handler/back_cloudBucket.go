@@ -1,32 +1,23 @@
 package handler
 
 import (
-	"io"
+	"cybergame-api/filestore"
 	"net/http"
-	"net/url"
 	"os"
 
-	"cloud.google.com/go/storage"
 	"github.com/gin-gonic/gin"
-	"google.golang.org/api/option"
 	"google.golang.org/appengine"
 )
 
-var (
-	storageClient *storage.Client
-)
-
 // HandleFileUploadToBucket uploads file to bucket
 func HandleFileUploadToBucket(c *gin.Context) {
-	bucket := os.Getenv("NAME_BUCKET")       //your bucket name
 	maxBytes := os.Getenv("IMAGE_MAXI_SIZE") // 2MB
-	var err error
 
 	if maxBytes >= os.Getenv("IMAGE_MAXI_SIZE") {
 
 		ctx := appengine.NewContext(c.Request)
 
-		storageClient, err = storage.NewClient(ctx, option.WithCredentialsFile("domovie-cb4c3a0a3264.json"))
+		storageClient, err := filestore.New(ctx)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"message": err.Error(),
@@ -35,6 +26,8 @@ func HandleFileUploadToBucket(c *gin.Context) {
 			return
 		}
 
+		defer storageClient.Close()
+
 		f, uploadedFile, err := c.Request.FormFile("file")
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -46,36 +39,18 @@ func HandleFileUploadToBucket(c *gin.Context) {
 
 		defer f.Close()
 
-		sw := storageClient.Bucket(bucket).Object("logo/" + uploadedFile.Filename).NewWriter(ctx)
-
-		if _, err := io.Copy(sw, f); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"message": err.Error(),
-				"error":   true,
-			})
-			return
-		}
-
-		if err := sw.Close(); err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"message": err.Error(),
-				"error":   true,
-			})
-			return
-		}
-
-		u, err := url.Parse("/" + bucket + "/" + sw.Attrs().Name)
+		pathname, err := storageClient.Put(ctx, "logo/"+uploadedFile.Filename, uploadedFile.Header.Get("Content-Type"), f)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"message": err.Error(),
-				"Error":   true,
+				"error":   true,
 			})
 			return
 		}
 
 		c.JSON(http.StatusOK, gin.H{
 			"message":  "file uploaded successfully",
-			"pathname": u.EscapedPath(),
+			"pathname": pathname,
 		})
 	}
 
@@ -0,0 +1,357 @@
+package handler
+
+import (
+	"cybergame-api/catalog"
+	"cybergame-api/middleware"
+	"cybergame-api/model"
+	"cybergame-api/service"
+	"strconv"
+
+	"cybergame-api/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+type gameController struct {
+	gameService       service.GameService
+	accountingService service.AccountingService
+}
+
+func newGameController(
+	gameService service.GameService,
+	accountingService service.AccountingService,
+) gameController {
+	return gameController{gameService, accountingService}
+}
+
+func GameController(r *gin.RouterGroup, db *gorm.DB) {
+
+	repo := repository.NewGameRepository(db)
+	repoAccounting := repository.NewAccountingRepository(db)
+	service1 := service.NewGameService(repo)
+	service2 := service.NewAccountingService(repoAccounting, repository.NewUnitOfWork(db))
+	handler := newGameController(service1, service2)
+
+	r = r.Group("/games")
+	r.GET("/list", middleware.Authorize, handler.getGames)
+	r.PATCH("/:id", middleware.Authorize, handler.updateGame)
+	r.POST("/sync/preview", middleware.Authorize, handler.previewGameSync)
+	r.POST("/sync/apply", middleware.Authorize, handler.applyGameSync)
+
+	providerRoute := r.Group("/providers")
+	providerRoute.GET("/list", middleware.Authorize, handler.getGameProviders)
+	providerRoute.PATCH("/:code", middleware.Authorize, handler.updateGameProvider)
+
+	r.POST("/icons/mirror", middleware.Authorize, handler.mirrorGameIcons)
+
+	maintenanceRoute := r.Group("/maintenances")
+	maintenanceRoute.GET("/list", middleware.Authorize, handler.getGameMaintenances)
+	maintenanceRoute.POST("", middleware.Authorize, handler.createGameMaintenance)
+	maintenanceRoute.DELETE("/:id", middleware.Authorize, handler.deleteGameMaintenance)
+}
+
+// @Summary Get Game List
+// @Description Get Game List
+// @Tags Games
+// @Security BearerAuth
+// @Accept  json
+// @Produce  json
+// @Param _ query model.GameAdminListRequest true "Query Game"
+// @Success 200 {object} model.SuccessWithPagination
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /games/list [get]
+func (h gameController) getGames(c *gin.Context) {
+
+	var query model.GameAdminListRequest
+	if err := c.ShouldBind(&query); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	data, err := h.gameService.GetGames(query)
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	c.JSON(200, model.SuccessWithPagination{Message: "Success", List: data.List, Total: data.Total})
+}
+
+// @Summary Update Game
+// @Description Update sort order, visibility or enable a game
+// @Tags Games
+// @Security BearerAuth
+// @Accept  json
+// @Produce  json
+// @Param id path int true "id"
+// @Param body body model.GameUpdateBody true "body"
+// @Success 200 {object} model.Success
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /games/{id} [patch]
+func (h gameController) updateGame(c *gin.Context) {
+
+	id := c.Param("id")
+	identifier, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	var body model.GameUpdateBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	if err := h.gameService.UpdateGame(identifier, body); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	c.JSON(200, model.Success{Message: "Update success"})
+}
+
+// @Summary Preview Game Catalog Sync
+// @Description Compare the deployed json/<provider>.json with the Games table, nothing is uploaded
+// @Tags Games
+// @Security BearerAuth
+// @Accept  json
+// @Produce  json
+// @Param body body model.GameSyncRequest true "body"
+// @Success 200 {object} model.GameSyncResult
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /games/sync/preview [post]
+func (h gameController) previewGameSync(c *gin.Context) {
+
+	var body model.GameSyncRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	if err := validator.New().Struct(body); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	body.Dir = catalog.DefaultDir
+	data, err := h.gameService.PreviewGameCatalogSync(body)
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	c.JSON(200, data)
+}
+
+// @Summary Apply Game Catalog Sync
+// @Description Write the deployed json/<provider>.json into the Games table, nothing is uploaded
+// @Tags Games
+// @Security BearerAuth
+// @Accept  json
+// @Produce  json
+// @Param body body model.GameSyncRequest true "body"
+// @Success 201 {object} model.GameSyncResult
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /games/sync/apply [post]
+func (h gameController) applyGameSync(c *gin.Context) {
+
+	var body model.GameSyncRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	if err := validator.New().Struct(body); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	body.Dir = catalog.DefaultDir
+	data, err := h.gameService.ApplyGameCatalogSync(body)
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	c.JSON(201, data)
+}
+
+// @Summary Get Game Provider List
+// @Description Get Game Provider List with enable and maintenance status
+// @Tags Games
+// @Security BearerAuth
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} model.SuccessWithList
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /games/providers/list [get]
+func (h gameController) getGameProviders(c *gin.Context) {
+
+	list, err := h.gameService.GetGameProviders()
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	c.JSON(200, model.SuccessWithList{Message: "Success", List: list})
+}
+
+// @Summary Update Game Provider
+// @Description Enable or disable a whole provider
+// @Tags Games
+// @Security BearerAuth
+// @Accept  json
+// @Produce  json
+// @Param code path string true "Provider"
+// @Param body body model.GameProviderUpdateBody true "body"
+// @Success 200 {object} model.Success
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /games/providers/{code} [patch]
+func (h gameController) updateGameProvider(c *gin.Context) {
+
+	adminId, err := h.accountingService.CheckCurrentAdminId(c.MustGet("adminId"))
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	var body model.GameProviderUpdateBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	if err := validator.New().Struct(body); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	body.UpdatedBy = *adminId
+	if err := h.gameService.UpdateGameProvider(c.Param("code"), body); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	c.JSON(200, model.Success{Message: "Update success"})
+}
+
+// @Summary Get Game Maintenance List
+// @Description Get Game Maintenance List
+// @Tags Games
+// @Security BearerAuth
+// @Accept  json
+// @Produce  json
+// @Param _ query model.GameMaintenanceListRequest true "Query Game Maintenance"
+// @Success 200 {object} model.SuccessWithPagination
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /games/maintenances/list [get]
+func (h gameController) getGameMaintenances(c *gin.Context) {
+
+	var query model.GameMaintenanceListRequest
+	if err := c.ShouldBind(&query); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	data, err := h.gameService.GetGameMaintenances(query)
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	c.JSON(200, model.SuccessWithPagination{Message: "Success", List: data.List, Total: data.Total})
+}
+
+// @Summary Create Game Maintenance
+// @Description Schedule a maintenance window for a provider, or one game when gameId is set
+// @Tags Games
+// @Security BearerAuth
+// @Accept  json
+// @Produce  json
+// @Param body body model.GameMaintenanceBody true "body"
+// @Success 201 {object} model.Success
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /games/maintenances [post]
+func (h gameController) createGameMaintenance(c *gin.Context) {
+
+	adminId, err := h.accountingService.CheckCurrentAdminId(c.MustGet("adminId"))
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	var body model.GameMaintenanceBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	if err := validator.New().Struct(body); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	body.CreatedBy = *adminId
+	if err := h.gameService.CreateGameMaintenance(body); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	c.JSON(201, model.Success{Message: "Created success"})
+}
+
+// @Summary Delete Game Maintenance
+// @Description Delete Game Maintenance
+// @Tags Games
+// @Security BearerAuth
+// @Accept  json
+// @Produce  json
+// @Param id path int true "id"
+// @Success 200 {object} model.Success
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /games/maintenances/{id} [delete]
+func (h gameController) deleteGameMaintenance(c *gin.Context) {
+
+	id := c.Param("id")
+	identifier, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	if err := h.gameService.DeleteGameMaintenance(identifier); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	c.JSON(200, model.Success{Message: "Delete success"})
+}
+
+// @Summary Mirror Game Icons
+// @Description Start copying the game icons into our storage in the background
+// @Tags Games
+// @Security BearerAuth
+// @Accept  json
+// @Produce  json
+// @Param body body model.GameIconMirrorRequest true "body"
+// @Success 201 {object} model.Success
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /games/icons/mirror [post]
+func (h gameController) mirrorGameIcons(c *gin.Context) {
+
+	var body model.GameIconMirrorRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	if err := h.gameService.StartGameIconMirror(body); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	c.JSON(201, model.Success{Message: "Mirror started"})
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"cybergame-api/middleware"
+	"cybergame-api/model"
+	"cybergame-api/repository"
+	"cybergame-api/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ledgerController struct {
+	ledgerService service.LedgerService
+}
+
+func newLedgerController(
+	ledgerService service.LedgerService,
+) ledgerController {
+	return ledgerController{ledgerService}
+}
+
+func LedgerController(r *gin.RouterGroup, db *gorm.DB) {
+
+	repo := repository.NewLedgerRepository(db)
+	service := service.NewLedgerService(repo)
+	handler := newLedgerController(service)
+
+	r = r.Group("/ledger")
+	r.GET("/accounts", middleware.Authorize, handler.getLedgerAccounts)
+	r.GET("/accounts/:id", middleware.Authorize, handler.getLedgerAccountById)
+	r.GET("/entries", middleware.Authorize, handler.getLedgerEntries)
+	r.GET("/verify", middleware.Authorize, handler.verifyLedger)
+}
+
+// @Summary Get Ledger Accounts
+// @Description Member wallets, bank accounts, bonus, house and game accounts with their balances
+// @Tags Ledger
+// @Security BearerAuth
+// @Accept  json
+// @Produce  json
+// @Param _ query model.LedgerAccountListRequest true "Query Ledger Account"
+// @Success 200 {object} model.SuccessWithPagination
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /ledger/accounts [get]
+func (h ledgerController) getLedgerAccounts(c *gin.Context) {
+
+	var query model.LedgerAccountListRequest
+	if err := c.ShouldBind(&query); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	data, err := h.ledgerService.GetLedgerAccounts(query)
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	c.JSON(200, model.SuccessWithPagination{Message: "Success", List: data.List, Total: data.Total})
+}
+
+// @Summary Get Ledger Account By Id
+// @Description Get Ledger Account By Id
+// @Tags Ledger
+// @Security BearerAuth
+// @Accept  json
+// @Produce  json
+// @Param id path int true "id"
+// @Success 200 {object} model.LedgerAccount
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /ledger/accounts/{id} [get]
+func (h ledgerController) getLedgerAccountById(c *gin.Context) {
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	data, err := h.ledgerService.GetLedgerAccountById(id)
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	c.JSON(200, data)
+}
+
+// @Summary Get Ledger Entries
+// @Description Debit and credit entries, by account, transaction or member
+// @Tags Ledger
+// @Security BearerAuth
+// @Accept  json
+// @Produce  json
+// @Param _ query model.LedgerEntryListRequest true "Query Ledger Entry"
+// @Success 200 {object} model.SuccessWithPagination
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /ledger/entries [get]
+func (h ledgerController) getLedgerEntries(c *gin.Context) {
+
+	var query model.LedgerEntryListRequest
+	if err := c.ShouldBind(&query); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	data, err := h.ledgerService.GetLedgerEntries(query)
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	c.JSON(200, model.SuccessWithPagination{Message: "Success", List: data.List, Total: data.Total})
+}
+
+// @Summary Verify Ledger
+// @Description Recompute every balance from the entries and compare with the stored balances and the member credit
+// @Tags Ledger
+// @Security BearerAuth
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} model.LedgerVerifyResult
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /ledger/verify [get]
+func (h ledgerController) verifyLedger(c *gin.Context) {
+
+	data, err := h.ledgerService.VerifyLedger()
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	c.JSON(200, data)
+}
@@ -178,7 +178,7 @@ func (h userController) create(c *gin.Context) {
 		return
 	}
 
-	err := h.userService.Create(data)
+	err := h.userService.Create(c.Request.Context(), data)
 	if err != nil {
 		HandleError(c, err)
 		return
@@ -195,7 +195,7 @@ func (h frontAuthController) resetPassword(c *gin.Context) {
 		return
 	}
 
-	if err := h.frontUserService.FrontUserResetPassword(int64(toInt), body); err != nil {
+	if err := h.frontUserService.FrontUserResetPassword(c.Request.Context(), int64(toInt), body); err != nil {
 		HandleError(c, err)
 		return
 	}
@@ -226,7 +226,7 @@ func (h frontAuthController) frontLogin(c *gin.Context) {
 		return
 	}
 
-	token, err := h.frontUserService.FrontUserLogin(body)
+	token, err := h.frontUserService.FrontUserLogin(c.Request.Context(), body)
 	if err != nil {
 		HandleError(c, err)
 		return
@@ -265,7 +265,7 @@ func (h frontAuthController) updateInfo(c *gin.Context) {
 		return
 	}
 
-	err = h.frontUserService.FrontUserUpdateInfo(int64(toInt), data)
+	err = h.frontUserService.FrontUserUpdateInfo(c.Request.Context(), int64(toInt), data)
 	if err != nil {
 		HandleError(c, err)
 		return
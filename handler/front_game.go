@@ -0,0 +1,341 @@
+package handler
+
+import (
+	"cybergame-api/middleware"
+	"cybergame-api/model"
+	"cybergame-api/service"
+
+	"cybergame-api/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+type frontGameController struct {
+	frontGameService service.FrontGameService
+}
+
+func newFrontGameController(
+	frontGameService service.FrontGameService,
+) frontGameController {
+	return frontGameController{frontGameService}
+}
+
+func FrontGameController(r *gin.RouterGroup, db *gorm.DB) {
+
+	repo := repository.NewFrontGameRepository(db)
+	userRepo := repository.NewFrontUserRepository(db)
+	agentConnectRepo := repository.NewAgentConnectRepository(db)
+	service := service.NewFrontGameService(repo, userRepo, agentConnectRepo)
+	handler := newFrontGameController(service)
+
+	r = r.Group("/games")
+	r.GET("/list", handler.getFrontGames)
+	r.GET("/providers", handler.getFrontGameProviders)
+	r.GET("/search", handler.searchFrontGames)
+	r.GET("/favorites", middleware.UserAuthorize, handler.getFrontGameFavorites)
+	r.GET("/recent", middleware.UserAuthorize, handler.getFrontGameRecentPlays)
+	r.GET("/:provider/:code", handler.getFrontGame)
+	r.POST("/:provider/:code/launch", middleware.UserAuthorize, handler.launchFrontGame)
+	r.POST("/:provider/:code/favorite", middleware.UserAuthorize, handler.addFrontGameFavorite)
+	r.DELETE("/:provider/:code/favorite", middleware.UserAuthorize, handler.removeFrontGameFavorite)
+}
+
+// @Summary รายการเกม
+// @Description Get Game List
+// @Tags Front - Games
+// @Accept  json
+// @Produce  json
+// @Param _ query model.GameListRequest true "Query Game"
+// @Success 200 {object} model.SuccessWithPagination
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /v1/frontend/games/list [get]
+func (h frontGameController) getFrontGames(c *gin.Context) {
+
+	var query model.GameListRequest
+	if err := c.ShouldBind(&query); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	if err := validator.New().Struct(query); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	list, total, err := h.frontGameService.GetFrontGames(query)
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	c.JSON(200, model.SuccessWithPagination{Message: "Success", List: list, Total: total})
+}
+
+// @Summary ค้นหาเกม
+// @Description Search games by name in any language, Thai spelling of English names included
+// @Tags Front - Games
+// @Accept  json
+// @Produce  json
+// @Param _ query model.GameSearchRequest true "Search Game"
+// @Success 200 {object} model.SuccessWithPagination
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /v1/frontend/games/search [get]
+func (h frontGameController) searchFrontGames(c *gin.Context) {
+
+	var query model.GameSearchRequest
+	if err := c.ShouldBind(&query); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	if err := validator.New().Struct(query); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	list, total, err := h.frontGameService.SearchFrontGames(query)
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	c.JSON(200, model.SuccessWithPagination{Message: "Success", List: list, Total: total})
+}
+
+// @Summary รายการค่ายเกม
+// @Description Get Game Provider List
+// @Tags Front - Games
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} model.SuccessWithList
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /v1/frontend/games/providers [get]
+func (h frontGameController) getFrontGameProviders(c *gin.Context) {
+
+	list, err := h.frontGameService.GetFrontGameProviders()
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	c.JSON(200, model.SuccessWithList{Message: "Success", List: list})
+}
+
+// @Summary รายละเอียดเกม
+// @Description Get Game Detail
+// @Tags Front - Games
+// @Accept  json
+// @Produce  json
+// @Param provider path string true "Provider"
+// @Param code path string true "Game Code"
+// @Success 200 {object} model.SuccessWithData
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /v1/frontend/games/{provider}/{code} [get]
+func (h frontGameController) getFrontGame(c *gin.Context) {
+
+	var req model.GameDetailRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	data, err := h.frontGameService.GetFrontGame(req)
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	c.JSON(200, model.SuccessWithData{Message: "Success", Data: data})
+}
+
+// @Summary เข้าเล่นเกม
+// @Description Launch Game
+// @Tags Front - Games
+// @Security BearerAuth
+// @Accept  json
+// @Produce  json
+// @Param provider path string true "Provider"
+// @Param code path string true "Game Code"
+// @Param body body model.GameLaunchRequest true "Launch Game"
+// @Success 201 {object} model.SuccessWithData
+// @Failure 400 {object} handler.ErrorResponse
+// @Failure 503 {object} handler.ErrorResponse
+// @Router /v1/frontend/games/{provider}/{code}/launch [post]
+func (h frontGameController) launchFrontGame(c *gin.Context) {
+
+	userId, err := h.frontGameService.CheckCurrentUserId(c.MustGet("userId"))
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	var req model.GameDetailRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	var body model.GameLaunchRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	if err := validator.New().Struct(body); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	body.UserId = *userId
+	body.Ip = c.ClientIP()
+	if body.Domain == "" {
+		body.Domain = c.Request.Header.Get("Origin")
+	}
+
+	data, err := h.frontGameService.LaunchFrontGame(c.Request.Context(), req, body)
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	c.JSON(201, model.SuccessWithData{Message: "Success", Data: data})
+}
+
+// @Summary เกมโปรด
+// @Description Get Favorite Game List
+// @Tags Front - Games
+// @Security BearerAuth
+// @Accept  json
+// @Produce  json
+// @Param _ query model.GameFavoriteListRequest true "Query Favorite"
+// @Success 200 {object} model.SuccessWithPagination
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /v1/frontend/games/favorites [get]
+func (h frontGameController) getFrontGameFavorites(c *gin.Context) {
+
+	userId, err := h.frontGameService.CheckCurrentUserId(c.MustGet("userId"))
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	var query model.GameFavoriteListRequest
+	if err := c.ShouldBind(&query); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	list, total, err := h.frontGameService.GetFrontGameFavorites(*userId, query)
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	c.JSON(200, model.SuccessWithPagination{Message: "Success", List: list, Total: total})
+}
+
+// @Summary เพิ่มเกมโปรด
+// @Description Add Favorite Game
+// @Tags Front - Games
+// @Security BearerAuth
+// @Accept  json
+// @Produce  json
+// @Param provider path string true "Provider"
+// @Param code path string true "Game Code"
+// @Success 201 {object} model.Success
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /v1/frontend/games/{provider}/{code}/favorite [post]
+func (h frontGameController) addFrontGameFavorite(c *gin.Context) {
+
+	userId, err := h.frontGameService.CheckCurrentUserId(c.MustGet("userId"))
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	var req model.GameDetailRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	if err := h.frontGameService.AddFrontGameFavorite(*userId, req); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	c.JSON(201, model.Success{Message: "Created success"})
+}
+
+// @Summary ลบเกมโปรด
+// @Description Remove Favorite Game
+// @Tags Front - Games
+// @Security BearerAuth
+// @Accept  json
+// @Produce  json
+// @Param provider path string true "Provider"
+// @Param code path string true "Game Code"
+// @Success 200 {object} model.Success
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /v1/frontend/games/{provider}/{code}/favorite [delete]
+func (h frontGameController) removeFrontGameFavorite(c *gin.Context) {
+
+	userId, err := h.frontGameService.CheckCurrentUserId(c.MustGet("userId"))
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	var req model.GameDetailRequest
+	if err := c.ShouldBindUri(&req); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	if err := h.frontGameService.RemoveFrontGameFavorite(*userId, req); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	c.JSON(200, model.Success{Message: "Delete success"})
+}
+
+// @Summary เกมที่เล่นล่าสุด
+// @Description Get Recently Played Game List
+// @Tags Front - Games
+// @Security BearerAuth
+// @Accept  json
+// @Produce  json
+// @Param _ query model.GameRecentListRequest true "Query Recent"
+// @Success 200 {object} model.SuccessWithList
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /v1/frontend/games/recent [get]
+func (h frontGameController) getFrontGameRecentPlays(c *gin.Context) {
+
+	userId, err := h.frontGameService.CheckCurrentUserId(c.MustGet("userId"))
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	var query model.GameRecentListRequest
+	if err := c.ShouldBind(&query); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	if err := validator.New().Struct(query); err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	list, err := h.frontGameService.GetFrontGameRecentPlays(*userId, query)
+	if err != nil {
+		HandleError(c, err)
+		return
+	}
+
+	c.JSON(200, model.SuccessWithList{Message: "Success", List: list})
+}
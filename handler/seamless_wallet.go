@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"cybergame-api/model"
+	"cybergame-api/repository"
+	"cybergame-api/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+type seamlessWalletController struct {
+	seamlessWalletService service.SeamlessWalletService
+}
+
+func newSeamlessWalletController(
+	seamlessWalletService service.SeamlessWalletService,
+) seamlessWalletController {
+	return seamlessWalletController{seamlessWalletService}
+}
+
+// SeamlessWalletController serves the providers that keep no wallet and call
+// us for every bet. The body is signed, there is no bearer token.
+func SeamlessWalletController(r *gin.RouterGroup, db *gorm.DB) {
+
+	repo := repository.NewSeamlessWalletRepository(db)
+	service := service.NewSeamlessWalletService(repo)
+	handler := newSeamlessWalletController(service)
+
+	r.POST("/balance", handler.callback(service.Balance))
+	r.POST("/bet", handler.callback(service.Bet))
+	r.POST("/win", handler.callback(service.Win))
+	r.POST("/refund", handler.callback(service.Refund))
+	r.POST("/rollback", handler.callback(service.Rollback))
+}
+
+// @Summary Seamless Wallet Callback
+// @Description balance, bet, win, refund and rollback share this body. Business errors answer 200 with a non zero code.
+// @Tags Seamless Wallet
+// @Accept  json
+// @Produce  json
+// @Param action path string true "balance, bet, win, refund or rollback"
+// @Param body body model.SeamlessRequest true "body"
+// @Success 200 {object} model.SeamlessResponse
+// @Failure 400 {object} handler.ErrorResponse
+// @Router /v1/seamless/{action} [post]
+func (h seamlessWalletController) callback(handle func(req model.SeamlessRequest) model.SeamlessResponse) gin.HandlerFunc {
+	return func(c *gin.Context) {
+
+		var body model.SeamlessRequest
+		if err := c.ShouldBindJSON(&body); err != nil {
+			HandleError(c, err)
+			return
+		}
+
+		if err := validator.New().Struct(body); err != nil {
+			HandleError(c, err)
+			return
+		}
+
+		c.JSON(200, handle(body))
+	}
+}
@@ -21,7 +21,6 @@ func Post(url string, body interface{}) (interface{}, error) {
 	jsonBody, _ := json.Marshal(body)
 	reqBody := bytes.NewBuffer(jsonBody)
 
-	call := http.Client{}
 	req, err := http.NewRequest("POST", url, reqBody)
 	if err != nil {
 		fmt.Println(err)
@@ -31,7 +30,7 @@ func Post(url string, body interface{}) (interface{}, error) {
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
 
-	resp, err := call.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		fmt.Println(err)
 		return nil, err
@@ -55,7 +54,6 @@ func PostAndAuthorize(url, token string, body interface{}) PostResult {
 	jsonBody, _ := json.Marshal(body)
 	reqBody := bytes.NewBuffer(jsonBody)
 
-	call := http.Client{}
 	req, err := http.NewRequest("POST", url, reqBody)
 	if err != nil {
 		fmt.Println(err)
@@ -69,7 +67,7 @@ func PostAndAuthorize(url, token string, body interface{}) PostResult {
 	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("Accept", "application/json")
 
-	resp, err := call.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		fmt.Println(err)
 		return PostResult{
@@ -11,11 +11,15 @@ import (
 
 func CreateSign(data string, timeNow time.Time) string {
 
+	return CreateSignWithKey(data, timeNow, os.Getenv("AGENT_KEY"))
+}
+
+// CreateSignWithKey is CreateSign with an explicit agent key.
+func CreateSignWithKey(data string, timeNow time.Time, apiKey string) string {
+
 	timeStamp := fmt.Sprintf("%d", timeNow.Unix())
-	apiKey := os.Getenv("AGENT_KEY")
 
 	word := strings.ToLower(data) + timeStamp + strings.ToLower(apiKey)
-	fmt.Println("word", word)
 	hasher := sha256.New()
 
 	if _, err := hasher.Write([]byte(word)); err != nil {
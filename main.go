@@ -1,11 +1,16 @@
 package main
 
 import (
+	"cybergame-api/catalog"
 	docs "cybergame-api/docs"
+	"cybergame-api/filestore"
 	handler "cybergame-api/handler"
 	"cybergame-api/middleware"
+	"cybergame-api/repository"
+	"cybergame-api/service"
 	"fmt"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -32,6 +37,11 @@ func main() {
 
 	initTimeZone()
 	db := initDatabase()
+	initGameCatalog(db)
+	initAgentCallRetrier(db)
+	initMemberCreditSync(db)
+	initAgentBetSync(db)
+	initIdempotencyPurge(db)
 
 	r := gin.Default()
 
@@ -40,6 +50,9 @@ func main() {
 	// Register the middleware
 	r.Use(middleware.CORSMiddleware())
 	r.POST("/api/cloud-storage-bucket", handler.HandleFileUploadToBucket)
+	if filestore.IsLocal() {
+		r.Static(filestore.LocalPrefix, filestore.LocalDir())
+	}
 
 	path := "/api"
 	route := r.Group(path)
@@ -63,11 +76,21 @@ func main() {
 	handler.LineNotifyController(backRoute, db)
 	handler.RecommendController(backRoute, db)
 	handler.MenuController(backRoute, db)
+	handler.GameController(backRoute, db)
+	handler.AgentCallController(backRoute, db)
+	handler.BetController(backRoute, db)
+	handler.LedgerController(backRoute, db)
 
 	frontPath := "/api/v1/frontend"
 	frontRoute := r.Group(frontPath)
 	handler.FrontAuthController(frontRoute, db)
 	handler.FrontUserController(frontRoute, db)
+	handler.FrontGameController(frontRoute, db)
+
+	// providers on the seamless model call these instead of holding credit at the agent
+	if os.Getenv("SEAMLESS_WALLET") == "true" {
+		handler.SeamlessWalletController(r.Group("/api/v1/seamless"), db)
+	}
 
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerfiles.Handler))
 
@@ -135,6 +158,85 @@ func initDatabase() *gorm.DB {
 	return db
 }
 
+func initGameCatalog(db *gorm.DB) {
+
+	repo := repository.NewGameRepository(db)
+
+	total, err := repo.CountGames()
+	if err != nil {
+		println(fmt.Sprintf("\033[31m%s\033[0m ", "Error: "+err.Error()))
+		return
+	}
+
+	gameService := service.NewGameService(repo)
+
+	if total > 0 {
+		if _, err := gameService.BuildGameSearchKeys(); err != nil {
+			println(fmt.Sprintf("\033[31m%s\033[0m ", "Error: "+err.Error()))
+		}
+		return
+	}
+
+	imported, err := gameService.ImportGameCatalog(catalog.DefaultDir)
+	if err != nil {
+		println(fmt.Sprintf("\033[31m%s\033[0m ", "Error: "+err.Error()))
+		return
+	}
+
+	println("Game catalog imported", imported)
+}
+
+// initAgentCallRetrier sends again the agent credit transfers that ended
+// without a clear answer, see service.AgentCallService.
+func initAgentCallRetrier(db *gorm.DB) {
+
+	repo := repository.NewAgentCallRepository(db)
+	repoAgentConnect := repository.NewAgentConnectRepository(db)
+	service.NewAgentCallService(repo, repoAgentConnect).StartAgentCallRetrier(30 * time.Second)
+}
+
+// initMemberCreditSync aligns Users.credit with the agent wallets every
+// AGENT_CREDIT_SYNC_MINUTES (default 15, 0 turns it off).
+func initMemberCreditSync(db *gorm.DB) {
+
+	minutes := 15
+	if value, err := strconv.Atoi(os.Getenv("AGENT_CREDIT_SYNC_MINUTES")); err == nil {
+		minutes = value
+	}
+	if minutes <= 0 {
+		return
+	}
+
+	repoBanking := repository.NewBankingRepository(db)
+	repoAccounting := repository.NewAccountingRepository(db)
+	repoAgentConnect := repository.NewAgentConnectRepository(db)
+	repoAgentCall := repository.NewAgentCallRepository(db)
+	uow := repository.NewUnitOfWork(db)
+	service.NewBankingService(repoBanking, repoAccounting, repoAgentConnect, repoAgentCall, uow).StartMemberCreditSync(time.Duration(minutes) * time.Minute)
+}
+
+// initAgentBetSync imports the agent bet history every
+// AGENT_BET_SYNC_MINUTES (default 5, 0 turns it off).
+func initAgentBetSync(db *gorm.DB) {
+
+	minutes := 5
+	if value, err := strconv.Atoi(os.Getenv("AGENT_BET_SYNC_MINUTES")); err == nil {
+		minutes = value
+	}
+	if minutes <= 0 {
+		return
+	}
+
+	repo := repository.NewBetRepository(db)
+	repoAgentConnect := repository.NewAgentConnectRepository(db)
+	service.NewBetService(repo, repoAgentConnect).StartAgentBetSync(time.Duration(minutes) * time.Minute)
+}
+
+// initIdempotencyPurge deletes the expired Idempotency_keys every hour.
+func initIdempotencyPurge(db *gorm.DB) {
+	middleware.StartIdempotencyPurge(db, time.Hour)
+}
+
 // func initFirebase() (*firebase.App, context.Context) {
 
 // 	ctx := context.Background()
@@ -0,0 +1,255 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+const idempotencyHeader = "Idempotency-Key"
+
+// expired keys are deleted this many at a time by the purge
+const idempotencyPurgeBatch = 1000
+
+type idempotencyKey struct {
+	Id             int64     `gorm:"primaryKey"`
+	Scope          string    `gorm:"column:scope"`
+	IdempotencyKey string    `gorm:"column:idempotency_key"`
+	Method         string    `gorm:"column:method"`
+	Path           string    `gorm:"column:path"`
+	RequestHash    string    `gorm:"column:request_hash"`
+	ResponseStatus *int      `gorm:"column:response_status"`
+	ResponseBody   *string   `gorm:"column:response_body"`
+	ExpiresAt      time.Time `gorm:"column:expires_at"`
+}
+
+// idempotencyWriter keeps a copy of the response to answer the replays.
+type idempotencyWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotencyWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *idempotencyWriter) WriteString(data string) (int, error) {
+	w.body.WriteString(data)
+	return w.ResponseWriter.WriteString(data)
+}
+
+// Idempotency answers a request sent again with the same Idempotency-Key
+// header with the response of the first one, without running the handler
+// again. The key is per admin, reusing it for another path or body is 422.
+// A response of 5xx or a panic lets the key go so the request can be retried.
+// Requests without the header, or without an admin, run as before. Keys
+// expire after IDEMPOTENCY_TTL_HOURS, 24 by default. A key is only claimed
+// for IDEMPOTENCY_LEASE_MINUTES, 5 by default, until its response is kept, a
+// claim left by a process that died is taken over after that. Put it after
+// Authorize.
+func Idempotency(db *gorm.DB) gin.HandlerFunc {
+
+	ttl := 24 * time.Hour
+	if hours, err := strconv.Atoi(os.Getenv("IDEMPOTENCY_TTL_HOURS")); err == nil && hours > 0 {
+		ttl = time.Duration(hours) * time.Hour
+	}
+	lease := 5 * time.Minute
+	if minutes, err := strconv.Atoi(os.Getenv("IDEMPOTENCY_LEASE_MINUTES")); err == nil && minutes > 0 {
+		lease = time.Duration(minutes) * time.Minute
+	}
+
+	return func(c *gin.Context) {
+
+		key := c.GetHeader(idempotencyHeader)
+		scope, ok := idempotencyScope(c)
+		if key == "" || !ok {
+			c.Next()
+			return
+		}
+		if len(key) > 255 {
+			c.AbortWithStatusJSON(400, gin.H{
+				"message": "Idempotency-Key is too long",
+			})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(400, gin.H{
+				"message": "Cannot read request body",
+			})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		hash := sha256.New()
+		hash.Write([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n"))
+		hash.Write(body)
+
+		record := idempotencyKey{
+			Scope:          scope,
+			IdempotencyKey: key,
+			Method:         c.Request.Method,
+			Path:           c.Request.URL.Path,
+			RequestHash:    hex.EncodeToString(hash.Sum(nil)),
+			ExpiresAt:      time.Now().Add(lease),
+		}
+
+		created, err := createIdempotencyKey(db, &record)
+		if err != nil {
+			c.AbortWithStatusJSON(500, gin.H{
+				"message": "Internal Server Error",
+			})
+			return
+		}
+		if !created {
+			replayIdempotencyKey(db, c, record)
+			return
+		}
+
+		// a 5xx or a panic lets the key go, the lease ends it if this fails too
+		stored := false
+		defer func() {
+			if stored {
+				return
+			}
+			if err := db.Table("Idempotency_keys").Where("id = ?", record.Id).Delete(&idempotencyKey{}).Error; err != nil {
+				log.Printf("idempotency key %d release: %s", record.Id, err.Error())
+			}
+		}()
+
+		writer := &idempotencyWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		status := writer.Status()
+		if status >= 500 {
+			return
+		}
+		// the row is gone when the lease ran out and another request took the key
+		if err := db.Table("Idempotency_keys").Where("id = ?", record.Id).Updates(map[string]interface{}{
+			"response_status": status,
+			"response_body":   writer.body.String(),
+			"expires_at":      time.Now().Add(ttl),
+		}).Error; err == nil {
+			stored = true
+		}
+	}
+}
+
+// idempotencyScope is the admin of the token. Callers without one cannot be
+// told apart, they get no scope and their keys are not kept.
+func idempotencyScope(c *gin.Context) (string, bool) {
+	if adminId, ok := c.Get("adminId"); ok && adminId != nil {
+		return fmt.Sprintf("admin:%v", adminId), true
+	}
+	return "", false
+}
+
+// StartIdempotencyPurge deletes the expired keys every interval in the
+// background, a key that is never sent again would stay otherwise.
+func StartIdempotencyPurge(db *gorm.DB, interval time.Duration) {
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := purgeIdempotencyKeys(db, time.Now()); err != nil {
+				log.Println("idempotency purge:", err.Error())
+			}
+		}
+	}()
+}
+
+func purgeIdempotencyKeys(db *gorm.DB, now time.Time) error {
+
+	for {
+		query := db.Table("Idempotency_keys").
+			Where("expires_at < ?", now).
+			Limit(idempotencyPurgeBatch).
+			Delete(&idempotencyKey{})
+		if err := query.Error; err != nil {
+			return err
+		}
+		if query.RowsAffected < idempotencyPurgeBatch {
+			return nil
+		}
+	}
+}
+
+// createIdempotencyKey claims the key, false when another request holds it.
+// An expired key, or a claim whose lease ran out, is taken over.
+func createIdempotencyKey(db *gorm.DB, record *idempotencyKey) (bool, error) {
+
+	if err := db.Table("Idempotency_keys").
+		Where("scope = ? AND idempotency_key = ?", record.Scope, record.IdempotencyKey).
+		Where("expires_at < ?", time.Now()).
+		Delete(&idempotencyKey{}).
+		Error; err != nil {
+		return false, err
+	}
+
+	if err := db.Table("Idempotency_keys").Create(record).Error; err != nil {
+		var dup *mysql.MySQLError
+		if errors.As(err, &dup) && dup.Number == 1062 {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func replayIdempotencyKey(db *gorm.DB, c *gin.Context, request idempotencyKey) {
+
+	var record idempotencyKey
+	if err := db.Table("Idempotency_keys").
+		Where("scope = ? AND idempotency_key = ?", request.Scope, request.IdempotencyKey).
+		First(&record).
+		Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// the first request failed and let the key go
+			c.AbortWithStatusJSON(409, gin.H{
+				"message": "Request with this Idempotency-Key failed, please try again",
+			})
+			return
+		}
+		c.AbortWithStatusJSON(500, gin.H{
+			"message": "Internal Server Error",
+		})
+		return
+	}
+
+	if record.RequestHash != request.RequestHash {
+		c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+			"message": "Idempotency-Key was used with a different request",
+		})
+		return
+	}
+	if record.ResponseStatus == nil {
+		c.AbortWithStatusJSON(409, gin.H{
+			"message": "Request with this Idempotency-Key is in progress",
+		})
+		return
+	}
+
+	body := ""
+	if record.ResponseBody != nil {
+		body = *record.ResponseBody
+	}
+	c.Header("Idempotent-Replayed", "true")
+	c.Data(*record.ResponseStatus, "application/json; charset=utf-8", []byte(body))
+	c.Abort()
+}
@@ -0,0 +1,30 @@
+CREATE Table
+    Games (
+        id BIGINT PRIMARY KEY AUTO_INCREMENT,
+        provider VARCHAR(50) NOT NULL,
+        game_code VARCHAR(100) NOT NULL,
+        device_codes JSON NULL,
+        name VARCHAR(255) NOT NULL,
+        names JSON NULL,
+        game_type VARCHAR(50) NOT NULL,
+        provider_game_type VARCHAR(100) NOT NULL DEFAULT '',
+        image_icon VARCHAR(500) NOT NULL DEFAULT '',
+        currencies JSON NULL,
+        is_desktop TINYINT NOT NULL DEFAULT 1,
+        is_mobile TINYINT NOT NULL DEFAULT 1,
+        is_hot TINYINT NOT NULL DEFAULT 0,
+        is_new TINYINT NOT NULL DEFAULT 0,
+        is_recommend TINYINT NOT NULL DEFAULT 0,
+        is_available TINYINT NOT NULL DEFAULT 1,
+        is_hidden TINYINT NOT NULL DEFAULT 0,
+        sort_order INT NOT NULL DEFAULT 0,
+        released_at DATETIME NULL,
+        created_at DATETIME DEFAULT NOW(),
+        updated_at DATETIME NULL ON UPDATE NOW(),
+        deleted_at DATETIME NULL
+    );
+
+ALTER TABLE `Games`
+    ADD UNIQUE INDEX `uni_provider_game_code` (`provider`, `game_code`),
+    ADD INDEX `idx_game_type` (`game_type`),
+    ADD INDEX `idx_sort_order` (`sort_order`);
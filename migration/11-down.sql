@@ -0,0 +1 @@
+DROP TABLE IF EXISTS `Game_launch_logs`;
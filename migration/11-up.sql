@@ -0,0 +1,19 @@
+CREATE Table
+    Game_launch_logs (
+        id BIGINT PRIMARY KEY AUTO_INCREMENT,
+        user_id BIGINT NOT NULL,
+        game_id BIGINT NOT NULL,
+        provider VARCHAR(50) NOT NULL,
+        game_code VARCHAR(100) NOT NULL,
+        is_mobile TINYINT NOT NULL DEFAULT 0,
+        ip VARCHAR(50) NULL,
+        game_url TEXT NULL,
+        status VARCHAR(20) NOT NULL,
+        error_message VARCHAR(255) NULL,
+        created_at DATETIME DEFAULT NOW()
+    );
+
+ALTER TABLE `Game_launch_logs`
+    ADD INDEX `idx_user_id` (`user_id`),
+    ADD INDEX `idx_game_id` (`game_id`),
+    ADD INDEX `idx_created_at` (`created_at`);
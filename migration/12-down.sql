@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS `Game_maintenances`;
+
+DROP TABLE IF EXISTS `Game_providers`;
+
+ALTER TABLE `Games` DROP COLUMN `is_enabled`;
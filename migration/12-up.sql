@@ -0,0 +1,35 @@
+ALTER TABLE `Games`
+    ADD COLUMN `is_enabled` TINYINT NOT NULL DEFAULT 1 AFTER `is_hidden`;
+
+CREATE Table
+    Game_providers (
+        id BIGINT PRIMARY KEY AUTO_INCREMENT,
+        code VARCHAR(50) NOT NULL,
+        is_enabled TINYINT NOT NULL DEFAULT 1,
+        updated_by BIGINT NULL,
+        created_at DATETIME DEFAULT NOW(),
+        updated_at DATETIME NULL ON UPDATE NOW()
+    );
+
+ALTER TABLE `Game_providers`
+    ADD UNIQUE INDEX `uni_code` (`code`);
+
+CREATE Table
+    Game_maintenances (
+        id BIGINT PRIMARY KEY AUTO_INCREMENT,
+        provider VARCHAR(50) NOT NULL,
+        game_id BIGINT NULL,
+        start_at DATETIME NOT NULL,
+        end_at DATETIME NOT NULL,
+        message_th VARCHAR(255) NULL,
+        message_en VARCHAR(255) NULL,
+        created_by BIGINT NULL,
+        created_at DATETIME DEFAULT NOW(),
+        updated_at DATETIME NULL ON UPDATE NOW(),
+        deleted_at DATETIME NULL
+    );
+
+ALTER TABLE `Game_maintenances`
+    ADD INDEX `idx_provider` (`provider`),
+    ADD INDEX `idx_game_id` (`game_id`),
+    ADD INDEX `idx_start_end` (`start_at`, `end_at`);
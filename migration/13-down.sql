@@ -0,0 +1,5 @@
+ALTER TABLE `Games`
+    DROP COLUMN `image_mirror`,
+    DROP COLUMN `image_mirror_source`,
+    DROP COLUMN `image_mirror_status`,
+    DROP COLUMN `image_mirrored_at`;
@@ -0,0 +1,5 @@
+ALTER TABLE `Games`
+    ADD COLUMN `image_mirror` VARCHAR(500) NULL AFTER `image_icon`,
+    ADD COLUMN `image_mirror_source` VARCHAR(500) NULL AFTER `image_mirror`,
+    ADD COLUMN `image_mirror_status` VARCHAR(20) NULL AFTER `image_mirror_source`,
+    ADD COLUMN `image_mirrored_at` DATETIME NULL AFTER `image_mirror_status`;
@@ -0,0 +1,3 @@
+ALTER TABLE `Games`
+    DROP COLUMN `search_names`,
+    DROP COLUMN `search_phonetic`;
@@ -0,0 +1,3 @@
+ALTER TABLE `Games`
+    ADD COLUMN `search_names` TEXT NULL AFTER `sort_order`,
+    ADD COLUMN `search_phonetic` TEXT NULL AFTER `search_names`;
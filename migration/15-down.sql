@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS `Game_recent_plays`;
+
+DROP TABLE IF EXISTS `Game_favorites`;
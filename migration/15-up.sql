@@ -0,0 +1,25 @@
+CREATE Table
+    Game_favorites (
+        id BIGINT PRIMARY KEY AUTO_INCREMENT,
+        user_id BIGINT NOT NULL,
+        game_id BIGINT NOT NULL,
+        created_at DATETIME DEFAULT NOW()
+    );
+
+ALTER TABLE `Game_favorites`
+    ADD UNIQUE INDEX `uni_user_game` (`user_id`, `game_id`),
+    ADD INDEX `idx_user_created` (`user_id`, `created_at`);
+
+CREATE Table
+    Game_recent_plays (
+        id BIGINT PRIMARY KEY AUTO_INCREMENT,
+        user_id BIGINT NOT NULL,
+        game_id BIGINT NOT NULL,
+        play_count INT NOT NULL DEFAULT 1,
+        last_played_at DATETIME NOT NULL,
+        created_at DATETIME DEFAULT NOW()
+    );
+
+ALTER TABLE `Game_recent_plays`
+    ADD UNIQUE INDEX `uni_user_game` (`user_id`, `game_id`),
+    ADD INDEX `idx_user_last_played` (`user_id`, `last_played_at`);
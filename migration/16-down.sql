@@ -0,0 +1 @@
+DROP TABLE IF EXISTS `Agent_call_journals`;
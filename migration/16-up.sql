@@ -0,0 +1,26 @@
+CREATE Table
+    Agent_call_journals (
+        id BIGINT PRIMARY KEY AUTO_INCREMENT,
+        transaction_id VARCHAR(64) NOT NULL,
+        action VARCHAR(20) NOT NULL,
+        path VARCHAR(100) NOT NULL,
+        user_id BIGINT NULL,
+        ref_id BIGINT NULL,
+        player_name VARCHAR(100) NOT NULL,
+        amount DECIMAL(14, 2) NOT NULL DEFAULT 0,
+        request_body TEXT NOT NULL,
+        sign VARCHAR(255) NOT NULL DEFAULT '',
+        response_body TEXT NULL,
+        status VARCHAR(20) NOT NULL DEFAULT 'running',
+        attempt_count INT NOT NULL DEFAULT 0,
+        last_error VARCHAR(255) NULL,
+        next_retry_at DATETIME NULL,
+        completed_at DATETIME NULL,
+        created_at DATETIME DEFAULT NOW(),
+        updated_at DATETIME NULL ON UPDATE NOW()
+    );
+
+ALTER TABLE `Agent_call_journals`
+    ADD UNIQUE INDEX `uni_transaction_id` (`transaction_id`),
+    ADD INDEX `idx_status_next_retry` (`status`, `next_retry_at`),
+    ADD INDEX `idx_ref_id` (`ref_id`);
@@ -0,0 +1 @@
+DELETE FROM `User_statement_types` WHERE `code` = 'adjustment';
@@ -0,0 +1,2 @@
+INSERT INTO `User_statement_types` (`code`, `name`) VALUES
+    ('adjustment', 'ปรับยอดตามเอเย่นต์');
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS `Seamless_transactions`;
+
+DELETE FROM `User_statement_types` WHERE `code` IN ('seamless_bet', 'seamless_win', 'seamless_refund', 'seamless_rollback');
@@ -0,0 +1,29 @@
+INSERT INTO `User_statement_types` (`code`, `name`) VALUES
+    ('seamless_bet', 'เดิมพัน'),
+    ('seamless_win', 'ชนะเดิมพัน'),
+    ('seamless_refund', 'คืนเงินเดิมพัน'),
+    ('seamless_rollback', 'ยกเลิกรายการเดิมพัน');
+
+CREATE Table
+    Seamless_transactions (
+        id BIGINT PRIMARY KEY AUTO_INCREMENT,
+        provider VARCHAR(50) NOT NULL,
+        transaction_id VARCHAR(100) NOT NULL,
+        round_id VARCHAR(100) NOT NULL DEFAULT '',
+        ref_transaction_id VARCHAR(100) NULL,
+        user_id BIGINT NOT NULL,
+        game_code VARCHAR(100) NOT NULL DEFAULT '',
+        action VARCHAR(20) NOT NULL,
+        amount DECIMAL(14, 2) NOT NULL,
+        before_balance DECIMAL(14, 2) NOT NULL,
+        after_balance DECIMAL(14, 2) NOT NULL,
+        status VARCHAR(20) NOT NULL DEFAULT 'applied',
+        statement_id BIGINT NULL,
+        created_at DATETIME DEFAULT NOW(),
+        updated_at DATETIME NULL ON UPDATE NOW()
+    );
+
+ALTER TABLE `Seamless_transactions`
+    ADD UNIQUE INDEX `uni_provider_transaction` (`provider`, `transaction_id`),
+    ADD INDEX `idx_provider_round` (`provider`, `round_id`),
+    ADD INDEX `idx_user_created` (`user_id`, `created_at`);
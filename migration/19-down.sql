@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS `Bets`;
+
+ALTER TABLE `Users`
+    DROP COLUMN `turnover`;
@@ -0,0 +1,28 @@
+ALTER TABLE `Users`
+    ADD COLUMN `turnover` DECIMAL(14, 2) NOT NULL DEFAULT 0.00 AFTER `turnover_limit`;
+
+CREATE Table
+    Bets (
+        id BIGINT PRIMARY KEY AUTO_INCREMENT,
+        provider VARCHAR(50) NOT NULL,
+        bet_ref VARCHAR(100) NOT NULL,
+        user_id BIGINT NOT NULL,
+        username VARCHAR(255) NOT NULL,
+        game_code VARCHAR(100) NOT NULL DEFAULT '',
+        round_id VARCHAR(100) NOT NULL DEFAULT '',
+        bet_amount DECIMAL(14, 2) NOT NULL DEFAULT 0,
+        valid_amount DECIMAL(14, 2) NOT NULL DEFAULT 0,
+        payout_amount DECIMAL(14, 2) NOT NULL DEFAULT 0,
+        win_loss DECIMAL(14, 2) NOT NULL DEFAULT 0,
+        status VARCHAR(20) NOT NULL,
+        source VARCHAR(20) NOT NULL,
+        bet_at DATETIME NOT NULL,
+        settled_at DATETIME NULL,
+        created_at DATETIME DEFAULT NOW(),
+        updated_at DATETIME NULL ON UPDATE NOW()
+    );
+
+ALTER TABLE `Bets`
+    ADD UNIQUE INDEX `uni_provider_bet_ref` (`provider`, `bet_ref`),
+    ADD INDEX `idx_user_bet_at` (`user_id`, `bet_at`),
+    ADD INDEX `idx_source_settled_at` (`source`, `settled_at`);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS `Ledger_entries`;
+
+DROP TABLE IF EXISTS `Ledger_transactions`;
+
+DROP TABLE IF EXISTS `Ledger_accounts`;
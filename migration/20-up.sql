@@ -0,0 +1,85 @@
+CREATE Table
+    Ledger_accounts (
+        id BIGINT PRIMARY KEY AUTO_INCREMENT,
+        code VARCHAR(50) NOT NULL,
+        name VARCHAR(255) NOT NULL DEFAULT '',
+        type VARCHAR(20) NOT NULL,
+        user_id BIGINT NULL,
+        bank_account_id BIGINT NULL,
+        balance DECIMAL(14, 2) NOT NULL DEFAULT 0.00,
+        created_at DATETIME DEFAULT NOW(),
+        updated_at DATETIME NULL ON UPDATE NOW()
+    );
+
+ALTER TABLE `Ledger_accounts`
+    ADD UNIQUE INDEX `uni_code` (`code`),
+    ADD INDEX `idx_type` (`type`),
+    ADD INDEX `idx_user_id` (`user_id`);
+
+CREATE Table
+    Ledger_transactions (
+        id BIGINT PRIMARY KEY AUTO_INCREMENT,
+        code VARCHAR(50) NOT NULL,
+        user_id BIGINT NULL,
+        statement_id BIGINT NULL,
+        info VARCHAR(255) NOT NULL DEFAULT '',
+        created_at DATETIME DEFAULT NOW()
+    );
+
+ALTER TABLE `Ledger_transactions`
+    ADD INDEX `idx_code_created` (`code`, `created_at`),
+    ADD INDEX `idx_user_created` (`user_id`, `created_at`);
+
+CREATE Table
+    Ledger_entries (
+        id BIGINT PRIMARY KEY AUTO_INCREMENT,
+        transaction_id BIGINT NOT NULL,
+        account_id BIGINT NOT NULL,
+        debit DECIMAL(14, 2) NOT NULL DEFAULT 0.00,
+        credit DECIMAL(14, 2) NOT NULL DEFAULT 0.00,
+        created_at DATETIME DEFAULT NOW()
+    );
+
+ALTER TABLE `Ledger_entries`
+    ADD INDEX `idx_transaction_id` (`transaction_id`),
+    ADD INDEX `idx_account_created` (`account_id`, `created_at`);
+
+INSERT INTO `Ledger_accounts` (`code`, `name`, `type`) VALUES
+    ('opening', 'ยอดยกมา', 'equity'),
+    ('bank_clearing', 'บัญชีธนาคารที่ไม่ระบุ', 'asset'),
+    ('bonus', 'โบนัส', 'expense'),
+    ('house', 'รายได้เว็บ', 'income'),
+    ('game', 'ผลเล่นเกม', 'income');
+
+INSERT INTO `Ledger_accounts` (`code`, `name`, `type`, `bank_account_id`)
+    SELECT CONCAT('bank:', id), CONCAT(account_name, ' ', account_number), 'asset', id
+    FROM `Bank_accounts`;
+
+INSERT INTO `Ledger_accounts` (`code`, `name`, `type`, `user_id`, `balance`)
+    SELECT CONCAT('member:', id), COALESCE(member_code, username, ''), 'member', id, COALESCE(credit, 0)
+    FROM `Users`;
+
+INSERT INTO `Ledger_transactions` (`code`, `info`) VALUES
+    ('opening', 'ยอดเครดิตสมาชิกก่อนเปิดใช้บัญชีคู่');
+
+INSERT INTO `Ledger_entries` (`transaction_id`, `account_id`, `debit`, `credit`)
+    SELECT opening.id, accounts.id, GREATEST(-accounts.balance, 0), GREATEST(accounts.balance, 0)
+    FROM `Ledger_accounts` AS accounts
+    JOIN `Ledger_transactions` AS opening ON opening.code = 'opening'
+    WHERE accounts.type = 'member' AND accounts.balance != 0;
+
+INSERT INTO `Ledger_entries` (`transaction_id`, `account_id`, `debit`, `credit`)
+    SELECT opening.id, equity.id, GREATEST(SUM(entries.credit - entries.debit), 0), GREATEST(SUM(entries.debit - entries.credit), 0)
+    FROM `Ledger_entries` AS entries
+    JOIN `Ledger_transactions` AS opening ON opening.id = entries.transaction_id AND opening.code = 'opening'
+    JOIN `Ledger_accounts` AS equity ON equity.code = 'opening'
+    GROUP BY opening.id, equity.id;
+
+UPDATE `Ledger_accounts` AS equity
+    JOIN (
+        SELECT entries.account_id, SUM(entries.credit - entries.debit) AS balance
+        FROM `Ledger_entries` AS entries
+        GROUP BY entries.account_id
+    ) AS total ON total.account_id = equity.id
+    SET equity.balance = total.balance
+    WHERE equity.code = 'opening';
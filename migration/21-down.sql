@@ -0,0 +1,12 @@
+ALTER TABLE `Bank_accounts`
+    CHANGE COLUMN `auto_withdraw_max_amount` `auto_withdraw_max_amount` VARCHAR(255) NOT NULL,
+    CHANGE COLUMN `auto_transfer_max_amount` `auto_transfer_max_amount` VARCHAR(255) NOT NULL;
+
+ALTER TABLE `Line_notify`
+    CHANGE COLUMN `start_credit` `start_credit` DECIMAL(14, 2) NULL DEFAULT 0.00;
+
+ALTER TABLE `Bank_confirm_transactions`
+    CHANGE COLUMN `credit_amount` `credit_amount` DECIMAL(14, 2) NULL DEFAULT NULL;
+
+ALTER TABLE `Users`
+    CHANGE COLUMN `credit` `credit` DECIMAL(14, 2) NULL DEFAULT 0.00;
@@ -0,0 +1,28 @@
+UPDATE `Users` SET `credit` = 0 WHERE `credit` IS NULL;
+
+ALTER TABLE `Users`
+    CHANGE COLUMN `credit` `credit` DECIMAL(14, 2) NOT NULL DEFAULT 0.00;
+
+UPDATE `Bank_confirm_transactions` SET `credit_amount` = 0 WHERE `credit_amount` IS NULL;
+
+ALTER TABLE `Bank_confirm_transactions`
+    CHANGE COLUMN `credit_amount` `credit_amount` DECIMAL(14, 2) NOT NULL DEFAULT 0.00;
+
+UPDATE `Line_notify` SET `start_credit` = 0 WHERE `start_credit` IS NULL;
+
+ALTER TABLE `Line_notify`
+    CHANGE COLUMN `start_credit` `start_credit` DECIMAL(14, 2) NOT NULL DEFAULT 0.00;
+
+UPDATE `Bank_accounts` SET `auto_withdraw_max_amount` = '0'
+    WHERE TRIM(REPLACE(`auto_withdraw_max_amount`, ',', '')) NOT REGEXP '^[0-9]+([.][0-9]+)?$';
+
+UPDATE `Bank_accounts` SET `auto_transfer_max_amount` = '0'
+    WHERE TRIM(REPLACE(`auto_transfer_max_amount`, ',', '')) NOT REGEXP '^[0-9]+([.][0-9]+)?$';
+
+UPDATE `Bank_accounts` SET
+    `auto_withdraw_max_amount` = TRIM(REPLACE(`auto_withdraw_max_amount`, ',', '')),
+    `auto_transfer_max_amount` = TRIM(REPLACE(`auto_transfer_max_amount`, ',', ''));
+
+ALTER TABLE `Bank_accounts`
+    CHANGE COLUMN `auto_withdraw_max_amount` `auto_withdraw_max_amount` DECIMAL(14, 2) NOT NULL DEFAULT 0.00,
+    CHANGE COLUMN `auto_transfer_max_amount` `auto_transfer_max_amount` DECIMAL(14, 2) NOT NULL DEFAULT 0.00;
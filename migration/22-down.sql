@@ -0,0 +1 @@
+DROP TABLE IF EXISTS `Idempotency_keys`;
@@ -0,0 +1,18 @@
+CREATE Table
+    Idempotency_keys (
+        id BIGINT PRIMARY KEY AUTO_INCREMENT,
+        scope VARCHAR(100) NOT NULL,
+        idempotency_key VARCHAR(255) NOT NULL,
+        method VARCHAR(10) NOT NULL,
+        path VARCHAR(255) NOT NULL,
+        request_hash CHAR(64) NOT NULL,
+        response_status INT NULL,
+        response_body MEDIUMTEXT NULL,
+        expires_at DATETIME NOT NULL,
+        created_at DATETIME DEFAULT NOW(),
+        updated_at DATETIME NULL ON UPDATE NOW()
+    );
+
+ALTER TABLE `Idempotency_keys`
+    ADD UNIQUE INDEX `uni_scope_key` (`scope`, `idempotency_key`),
+    ADD INDEX `idx_expires_at` (`expires_at`);
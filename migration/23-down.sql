@@ -0,0 +1 @@
+DROP TABLE IF EXISTS `Withdraw_risk_rules`;
@@ -0,0 +1,25 @@
+CREATE Table
+    Withdraw_risk_rules (
+        id BIGINT PRIMARY KEY AUTO_INCREMENT,
+        rule_key VARCHAR(50) NOT NULL,
+        name VARCHAR(255) NOT NULL,
+        is_enabled TINYINT NOT NULL DEFAULT 0,
+        amount DECIMAL(14, 2) NOT NULL DEFAULT 0.00,
+        hours INT NOT NULL DEFAULT 0,
+        updated_by_user_id BIGINT NULL,
+        updated_by_username VARCHAR(255) NULL,
+        created_at DATETIME DEFAULT NOW(),
+        updated_at DATETIME NULL ON UPDATE NOW()
+    );
+
+ALTER TABLE `Withdraw_risk_rules`
+    ADD UNIQUE INDEX `uni_rule_key` (`rule_key`);
+
+INSERT INTO `Withdraw_risk_rules` (`rule_key`, `name`, `amount`, `hours`) VALUES
+('max_per_transaction', 'ยอดถอนสูงสุดต่อครั้ง', 50000.00, 0),
+('max_per_day', 'ยอดถอนสูงสุดต่อวัน', 100000.00, 0),
+('first_withdraw', 'ถอนครั้งแรก', 0.00, 0),
+('turnover_not_met', 'ยอดเทิร์นโอเวอร์ยังไม่ถึงกำหนด', 0.00, 0),
+('recent_bonus', 'ได้รับโบนัสล่าสุด', 0.00, 24),
+('scammer', 'ตรงกับรายชื่อมิจฉาชีพ', 0.00, 0),
+('new_bank_account', 'บัญชีธนาคารใหม่', 0.00, 72);
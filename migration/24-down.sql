@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS `Credit_reservations`;
+
+ALTER TABLE `Users` DROP COLUMN `credit_reserved`;
@@ -0,0 +1,17 @@
+ALTER TABLE `Users`
+    ADD COLUMN `credit_reserved` DECIMAL(14, 2) NOT NULL DEFAULT 0.00 AFTER `credit`;
+
+CREATE Table
+    Credit_reservations (
+        id BIGINT PRIMARY KEY AUTO_INCREMENT,
+        user_id BIGINT NOT NULL,
+        transaction_id BIGINT NOT NULL,
+        amount DECIMAL(14, 2) NOT NULL,
+        status VARCHAR(20) NOT NULL DEFAULT 'reserved',
+        created_at DATETIME DEFAULT NOW(),
+        updated_at DATETIME NULL ON UPDATE NOW()
+    );
+
+ALTER TABLE `Credit_reservations`
+    ADD UNIQUE INDEX `uni_transaction_id` (`transaction_id`),
+    ADD INDEX `idx_user_id_status` (`user_id`, `status`);
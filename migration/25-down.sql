@@ -0,0 +1,2 @@
+INSERT INTO `Withdraw_risk_rules` (`rule_key`, `name`, `amount`, `hours`) VALUES
+('turnover_not_met', 'ยอดเทิร์นโอเวอร์ยังไม่ถึงกำหนด', 0.00, 0);
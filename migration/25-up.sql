@@ -0,0 +1 @@
+DELETE FROM `Withdraw_risk_rules` WHERE `rule_key` = 'turnover_not_met';
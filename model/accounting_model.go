@@ -75,7 +75,7 @@ type BankAccount struct {
 	AccountTypeName         string         `json:"accountTypeName"`
 	AccountName             string         `json:"accountName"`
 	AccountNumber           string         `json:"accountNumber"`
-	AccountBalance          float64        `json:"accountBalance" sql:"type:decimal(14,2);"`
+	AccountBalance          Money          `json:"accountBalance" sql:"type:decimal(14,2);"`
 	AccountPriority         string         `json:"accountPriority"`
 	AccountPriorityId       int64          `json:"accountPriorityId"`
 	AccountStatus           string         `json:"accountStatus"`
@@ -89,8 +89,8 @@ type BankAccount struct {
 	AutoWithdrawFlag        string         `json:"autoWithdrawFlag"`
 	AutoWithdrawCreditFlag  string         `json:"autoWithdrawCreditFlag"`
 	AutoWithdrawConfirmFlag string         `json:"autoWithdrawConfirmFlag"`
-	AutoWithdrawMaxAmount   string         `json:"autoWithdrawMaxAmount"`
-	AutoTransferMaxAmount   string         `json:"autoTransferMaxAmount"`
+	AutoWithdrawMaxAmount   Money          `json:"autoWithdrawMaxAmount"`
+	AutoTransferMaxAmount   Money          `json:"autoTransferMaxAmount"`
 	QrWalletStatus          string         `json:"qrWalletStatus"`
 	CreatedAt               time.Time      `json:"createdAt"`
 	UpdatedAt               *time.Time     `json:"updatedAt"`
@@ -112,24 +112,24 @@ type BankAccountListRequest struct {
 }
 
 type BankAccountCreateBody struct {
-	BankId                  int64   `json:"bankId" validate:"required"`
-	AccountTypeId           int64   `json:"accounTypeId" validate:"required"`
-	AccountName             string  `json:"accountName" validate:"required"`
-	AccountNumber           string  `json:"accountNumber" validate:"required"`
-	AccountBalance          float64 `json:"-"`
-	DeviceUid               string  `json:"deviceUid"`
-	PinCode                 string  `json:"pinCode"`
-	AutoCreditFlag          string  `json:"autoCreditFlag"`
-	IsMainWithdraw          bool    `json:"isMainWithdraw"`
-	AutoWithdrawFlag        string  `json:"autoWithdrawFlag"`
-	AutoWithdrawCreditFlag  string  `json:"autoWithdrawCreditFlag"`
-	AutoWithdrawConfirmFlag string  `json:"autoWithdrawConfirmFlag"`
-	AutoWithdrawMaxAmount   string  `json:"autoWithdrawMaxAmount"`
-	AutoTransferMaxAmount   string  `json:"autoTransferMaxAmount"`
-	AccountPriorityId       int64   `json:"accountPriorityId"`
-	QrWalletStatus          string  `json:"qrWalletStatus"`
-	AccountStatus           string  `json:"accountStatus"`
-	ConnectionStatus        string  `json:"-"`
+	BankId                  int64  `json:"bankId" validate:"required"`
+	AccountTypeId           int64  `json:"accounTypeId" validate:"required"`
+	AccountName             string `json:"accountName" validate:"required"`
+	AccountNumber           string `json:"accountNumber" validate:"required"`
+	AccountBalance          Money  `json:"-"`
+	DeviceUid               string `json:"deviceUid"`
+	PinCode                 string `json:"pinCode"`
+	AutoCreditFlag          string `json:"autoCreditFlag"`
+	IsMainWithdraw          bool   `json:"isMainWithdraw"`
+	AutoWithdrawFlag        string `json:"autoWithdrawFlag"`
+	AutoWithdrawCreditFlag  string `json:"autoWithdrawCreditFlag"`
+	AutoWithdrawConfirmFlag string `json:"autoWithdrawConfirmFlag"`
+	AutoWithdrawMaxAmount   Money  `json:"autoWithdrawMaxAmount"`
+	AutoTransferMaxAmount   Money  `json:"autoTransferMaxAmount"`
+	AccountPriorityId       int64  `json:"accountPriorityId"`
+	QrWalletStatus          string `json:"qrWalletStatus"`
+	AccountStatus           string `json:"accountStatus"`
+	ConnectionStatus        string `json:"-"`
 }
 
 type BankAccountUpdateRequest struct {
@@ -144,8 +144,8 @@ type BankAccountUpdateRequest struct {
 	AutoWithdrawFlag        *string `json:"autoWithdrawFlag"`
 	AutoWithdrawCreditFlag  *string `json:"autoWithdrawCreditFlag"`
 	AutoWithdrawConfirmFlag *string `json:"autoWithdrawConfirmFlag"`
-	AutoWithdrawMaxAmount   *string `json:"autoWithdrawMaxAmount"`
-	AutoTransferMaxAmount   *string `json:"autoTransferMaxAmount"`
+	AutoWithdrawMaxAmount   *Money  `json:"autoWithdrawMaxAmount"`
+	AutoTransferMaxAmount   *Money  `json:"autoTransferMaxAmount"`
 	AccountPriorityId       *int64  `json:"accountPriorityId"`
 	QrWalletStatus          *string `json:"qrWalletStatus"`
 	AccountStatus           *string `json:"accountStatus"`
@@ -164,14 +164,14 @@ type BankAccountUpdateBody struct {
 	AutoWithdrawFlag        *string    `json:"autoWithdrawFlag"`
 	AutoWithdrawCreditFlag  *string    `json:"autoWithdrawCreditFlag"`
 	AutoWithdrawConfirmFlag *string    `json:"autoWithdrawConfirmFlag"`
-	AutoWithdrawMaxAmount   *string    `json:"autoWithdrawMaxAmount"`
-	AutoTransferMaxAmount   *string    `json:"autoTransferMaxAmount"`
+	AutoWithdrawMaxAmount   *Money     `json:"autoWithdrawMaxAmount"`
+	AutoTransferMaxAmount   *Money     `json:"autoTransferMaxAmount"`
 	AccountPriorityId       *int64     `json:"accountPriorityId"`
 	QrWalletStatus          *string    `json:"qrWalletStatus"`
 	AccountStatus           *string    `json:"accountStatus"`
 	LastConnUpdateAt        *time.Time `json:"-"`
 	ConnectionStatus        *string    `json:"-"`
-	AccountBalance          *float64   `json:"-"`
+	AccountBalance          *Money     `json:"-"`
 }
 
 type BankAccountDeleteBody struct {
@@ -189,7 +189,7 @@ type BankAccountResponse struct {
 	AccountTypeName   string         `json:"accountTypeName"`
 	AccountName       string         `json:"accountName"`
 	AccountNumber     string         `json:"accountNumber"`
-	AccountBalance    float64        `json:"accountBalance"`
+	AccountBalance    Money          `json:"accountBalance"`
 	AccountPriority   string         `json:"accountPriority"`
 	AccountPriorityId int64          `json:"accountPriorityId"`
 	AccountStatus     string         `json:"accountStatus"`
@@ -206,7 +206,7 @@ type BankAccountTransaction struct {
 	AccountId         int64          `json:"accountId"`
 	Description       string         `json:"description"`
 	TransferType      string         `json:"transferType"`
-	Amount            float64        `json:"amount" sql:"type:decimal(14,2);"`
+	Amount            Money          `json:"amount" sql:"type:decimal(14,2);"`
 	TransferAt        time.Time      `json:"transferAt"`
 	CreatedByUsername string         `json:"createdByUsername"`
 	CreatedAt         time.Time      `json:"createdAt"`
@@ -230,7 +230,7 @@ type BankAccountTransactionBody struct {
 	AccountId         int64     `json:"accountId" validate:"required"`
 	Description       string    `json:"description"`
 	TransferType      string    `json:"transferType" validate:"required"`
-	Amount            float64   `json:"amount" validate:"required"`
+	Amount            Money     `json:"amount" validate:"required,gt=0,max=99999999999999"`
 	TransferAt        time.Time `json:"transferAt" validate:"required"`
 	CreatedByUsername string    `json:"-"`
 }
@@ -243,7 +243,7 @@ type BankAccountTransactionResponse struct {
 	AccountNumber     string         `json:"accountNumber"`
 	Description       string         `json:"description"`
 	TransferType      string         `json:"transferType"`
-	Amount            float64        `json:"amount" sql:"type:decimal(14,2);"`
+	Amount            Money          `json:"amount" sql:"type:decimal(14,2);"`
 	TransferAt        time.Time      `json:"transferAt"`
 	CreatedByUsername string         `json:"createdByUsername"`
 	CreatedAt         time.Time      `json:"createdAt"`
@@ -263,7 +263,7 @@ type BankAccountTransfer struct {
 	ToBankName        string         `json:"toBankName"`
 	ToAccountName     string         `json:"toAccountName"`
 	ToAccountNumber   string         `json:"toAccountNumber"`
-	Amount            float64        `json:"amount" sql:"type:decimal(14,2);"`
+	Amount            Money          `json:"amount" sql:"type:decimal(14,2);"`
 	TransferAt        time.Time      `json:"transferAt"`
 	CreatedByUsername string         `json:"createdByUsername"`
 	Status            string         `json:"status"`
@@ -297,7 +297,7 @@ type BankAccountTransferBody struct {
 	ToBankId          int64     `json:"-"`
 	ToAccountName     string    `json:"-"`
 	ToAccountNumber   string    `json:"-"`
-	Amount            float64   `json:"amount" validate:"required"`
+	Amount            Money     `json:"amount" validate:"required,gt=0,max=99999999999999"`
 	TransferAt        time.Time `json:"transferAt" validate:"required"`
 	CreatedByUsername string    `json:"-"`
 }
@@ -320,7 +320,7 @@ type BankAccountTransferResponse struct {
 	ToBankName        string         `json:"toBankName"`
 	ToAccountName     string         `json:"toAccountName"`
 	ToAccountNumber   string         `json:"toAccountNumber"`
-	Amount            float64        `json:"amount" sql:"type:decimal(14,2);"`
+	Amount            Money          `json:"amount" sql:"type:decimal(14,2);"`
 	TransferAt        time.Time      `json:"transferAt"`
 	CreatedByUsername string         `json:"createdByUsername"`
 	Status            string         `json:"status"`
@@ -398,19 +398,19 @@ type ExternalAccountEnableRequest struct {
 }
 
 type ExternalAccountBalance struct {
-	LimitUsed            float64 `json:"limitUsed"`
-	BranchId             string  `json:"branchId"`
-	AccountName          string  `json:"accountName"`
-	DailyLimitOtherBanks float64 `json:"dailyLimitOtherBanks"`
-	DailyLimitPromptPay  float64 `json:"dailyLimitPromptPay"`
-	AccruedInterest      float64 `json:"accruedInterest"`
-	OverdraftLimit       float64 `json:"overdraftLimit"`
-	DailyLimitSCBOther   float64 `json:"dailyLimitSCBOther"`
-	DailyLimitSCBOwn     float64 `json:"dailyLimitSCBOwn"`
-	AvailableBalance     string  `json:"availableBalance"`
-	AccountNo            string  `json:"accountNo"`
-	Currency             string  `json:"currency"`
-	AccountBalance       string  `json:"accountBalance"`
+	LimitUsed            Money  `json:"limitUsed"`
+	BranchId             string `json:"branchId"`
+	AccountName          string `json:"accountName"`
+	DailyLimitOtherBanks Money  `json:"dailyLimitOtherBanks"`
+	DailyLimitPromptPay  Money  `json:"dailyLimitPromptPay"`
+	AccruedInterest      Money  `json:"accruedInterest"`
+	OverdraftLimit       Money  `json:"overdraftLimit"`
+	DailyLimitSCBOther   Money  `json:"dailyLimitSCBOther"`
+	DailyLimitSCBOwn     Money  `json:"dailyLimitSCBOwn"`
+	AvailableBalance     string `json:"availableBalance"`
+	AccountNo            string `json:"accountNo"`
+	Currency             string `json:"currency"`
+	AccountBalance       string `json:"accountBalance"`
 	Status               struct {
 		Code        int    `json:"code"`
 		Header      string `json:"header"`
@@ -504,24 +504,24 @@ type ExternalStatementListWithPagination struct {
 }
 
 type ExternalStatement struct {
-	Id                 int64   `json:"id"`
-	Amount             float64 `json:"amount"`
-	BankAccountId      int64   `json:"bankAccountId"`
-	BankCode           string  `json:"bankCode"`
-	ChannelCode        string  `json:"channelCode"`
-	ChannelDescription string  `json:"channelDescription"`
-	Checksum           string  `json:"checksum"`
-	CreatedDate        string  `json:"createdDate"`
-	DateTime           string  `json:"dateTime"`
-	Info               string  `json:"info"`
-	IsRead             bool    `json:"isRead"`
-	RawDateTime        string  `json:"rawDateTime"`
-	AccountDetail      string  `json:"accountDetail"`
-	StatementType      string  `json:"statementType"`
-	Status             string  `json:"status"`
-	TxnCode            string  `json:"txnCode"`
-	TxnDescription     string  `json:"txnDescription"`
-	UpdatedDate        string  `json:"updatedDate"`
+	Id                 int64  `json:"id"`
+	Amount             Money  `json:"amount"`
+	BankAccountId      int64  `json:"bankAccountId"`
+	BankCode           string `json:"bankCode"`
+	ChannelCode        string `json:"channelCode"`
+	ChannelDescription string `json:"channelDescription"`
+	Checksum           string `json:"checksum"`
+	CreatedDate        string `json:"createdDate"`
+	DateTime           string `json:"dateTime"`
+	Info               string `json:"info"`
+	IsRead             bool   `json:"isRead"`
+	RawDateTime        string `json:"rawDateTime"`
+	AccountDetail      string `json:"accountDetail"`
+	StatementType      string `json:"statementType"`
+	Status             string `json:"status"`
+	TxnCode            string `json:"txnCode"`
+	TxnDescription     string `json:"txnDescription"`
+	UpdatedDate        string `json:"updatedDate"`
 }
 
 type ExternalAccountStatementEx struct {
@@ -529,7 +529,7 @@ type ExternalAccountStatementEx struct {
 	ExternalId         int64          `json:"externalId"`
 	BankAccountId      int64          `json:"bankAccountId"`
 	BankCode           string         `json:"bankCode"`
-	Amount             float64        `json:"amount"`
+	Amount             Money          `json:"amount"`
 	DateTime           time.Time      `json:"dateTime"`
 	RawDateTime        time.Time      `json:"rawDateTime"`
 	Info               string         `json:"info"`
@@ -547,21 +547,21 @@ type ExternalAccountStatementEx struct {
 }
 
 type ExternalAccountStatementCreateBody struct {
-	ExternalId         int64   `json:"externalId"`
-	BankAccountId      int64   `json:"bankAccountId"`
-	BankCode           string  `json:"bankCode"`
-	Amount             float64 `json:"amount"`
-	DateTime           string  `json:"dateTime"`
-	RawDateTime        string  `json:"rawDateTime"`
-	Info               string  `json:"info"`
-	ChannelCode        string  `json:"channelCode"`
-	ChannelDescription string  `json:"channelDescription"`
-	TxnCode            string  `json:"txnCode"`
-	TxnDescription     string  `json:"txnDescription"`
-	Checksum           string  `json:"checksum"`
-	IsRead             bool    `json:"isRead"`
-	ExternalCreateDate string  `json:"externalCreateDate"`
-	ExternalUpdateDate string  `json:"externalUpdateDate"`
+	ExternalId         int64  `json:"externalId"`
+	BankAccountId      int64  `json:"bankAccountId"`
+	BankCode           string `json:"bankCode"`
+	Amount             Money  `json:"amount"`
+	DateTime           string `json:"dateTime"`
+	RawDateTime        string `json:"rawDateTime"`
+	Info               string `json:"info"`
+	ChannelCode        string `json:"channelCode"`
+	ChannelDescription string `json:"channelDescription"`
+	TxnCode            string `json:"txnCode"`
+	TxnDescription     string `json:"txnDescription"`
+	Checksum           string `json:"checksum"`
+	IsRead             bool   `json:"isRead"`
+	ExternalCreateDate string `json:"externalCreateDate"`
+	ExternalUpdateDate string `json:"externalUpdateDate"`
 }
 
 type ExternalAccountTransferRequest struct {
@@ -626,7 +626,7 @@ type WebhookStatement struct {
 	ClientName         string    `json:"clientName"`
 	BankAccountId      int64     `json:"bankAccountId"`
 	BankCode           string    `json:"bankCode"`
-	Amount             float64   `json:"amount"`
+	Amount             Money     `json:"amount"`
 	DateTime           time.Time `json:"dateTime"`
 	RawDateTime        time.Time `json:"rawDateTime"`
 	Info               string    `json:"info"`
@@ -665,7 +665,7 @@ type BankAccountPriority struct {
 	Name            string     `json:"name"`
 	ConditionType   string     `json:"conditionType"`
 	MinDepositCount int        `json:"minDepositCount"`
-	MinDepositTotal float64    `json:"minDepositTotal"`
+	MinDepositTotal Money      `json:"minDepositTotal"`
 	CreatedAt       time.Time  `json:"createdAt"`
 	UpdatedAt       *time.Time `json:"updatedAt"`
 }
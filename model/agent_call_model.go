@@ -0,0 +1,52 @@
+package model
+
+import "time"
+
+// AgentCallJournal keeps every credit transfer sent to the agent, one row per
+// TransactionId so a replay never moves the credit twice.
+type AgentCallJournal struct {
+	Id            int64      `json:"id" gorm:"primaryKey"`
+	TransactionId string     `json:"transactionId"`
+	Action        string     `json:"action"`
+	Path          string     `json:"path"`
+	UserId        *int64     `json:"userId"`
+	RefId         *int64     `json:"refId"`
+	PlayerName    string     `json:"playerName"`
+	Amount        Money      `json:"amount"`
+	RequestBody   string     `json:"requestBody"`
+	Sign          string     `json:"sign"`
+	ResponseBody  *string    `json:"responseBody"`
+	Status        string     `json:"status"`
+	AttemptCount  int        `json:"attemptCount"`
+	LastError     *string    `json:"lastError"`
+	NextRetryAt   *time.Time `json:"nextRetryAt"`
+	CompletedAt   *time.Time `json:"completedAt"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     *time.Time `json:"updatedAt"`
+}
+
+type AgentCallJournalListRequest struct {
+	Status string `form:"status" extensions:"x-order:1"`
+	Action string `form:"action" extensions:"x-order:2"`
+	Search string `form:"search" extensions:"x-order:3"`
+	Page   int    `form:"page" extensions:"x-order:4" default:"1" min:"1"`
+	Limit  int    `form:"limit" extensions:"x-order:5" default:"10" min:"1" max:"100"`
+}
+
+// AgentCallResultBody is what an attempt leaves on its journal row.
+type AgentCallResultBody struct {
+	Status       string
+	Sign         string
+	RequestBody  string
+	ResponseBody *string
+	LastError    *string
+	NextRetryAt  *time.Time
+	CompletedAt  *time.Time
+}
+
+type AgentCallRetryResult struct {
+	Total     int `json:"total"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+	Retrying  int `json:"retrying"`
+}
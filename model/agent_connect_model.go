@@ -26,6 +26,8 @@ type AGCLogin struct {
 	Lang      string `json:"Lang" validate:"required"`
 	IsMobile  bool   `json:"IsMobile" validate:"required"`
 	Ip        string `json:"Ip" validate:"required"`
+	Provider  string `json:"Provider,omitempty"`
+	GameCode  string `json:"GameCode,omitempty"`
 }
 
 type AGCChangePassword struct {
@@ -53,3 +55,94 @@ type AGCWithdraw struct {
 	Sign          string  `json:"Sign" validate:"required"`
 	TransactionId string  `json:"TransactionId" validate:"required"`
 }
+
+type AGCBalance struct {
+	Agentname  string `json:"Agentname" validate:"required"`
+	PlayerName string `json:"PlayerName" validate:"required"`
+	Timestamp  int64  `json:"TimeStamp" validate:"required"`
+	Sign       string `json:"Sign" validate:"required"`
+}
+
+type AGCBetHistory struct {
+	Agentname string `json:"Agentname" validate:"required"`
+	StartTime string `json:"StartTime" validate:"required"`
+	EndTime   string `json:"EndTime" validate:"required"`
+	Page      int    `json:"Page" validate:"required"`
+	PageSize  int    `json:"PageSize" validate:"required"`
+	Timestamp int64  `json:"TimeStamp" validate:"required"`
+	Sign      string `json:"Sign" validate:"required"`
+}
+
+type AGCBetRecord struct {
+	BetId        string  `json:"BetId"`
+	PlayerName   string  `json:"PlayerName"`
+	Provider     string  `json:"Provider"`
+	GameCode     string  `json:"GameCode"`
+	RoundId      string  `json:"RoundId"`
+	BetAmount    float64 `json:"BetAmount"`
+	ValidAmount  float64 `json:"ValidAmount"`
+	PayoutAmount float64 `json:"PayoutAmount"`
+	WinLoss      float64 `json:"WinLoss"`
+	Status       string  `json:"Status"`
+	BetTime      string  `json:"BetTime"`
+	SettleTime   string  `json:"SettleTime"`
+}
+
+type AGCResponseError struct {
+	Code    int    `json:"Code"`
+	Message string `json:"Message"`
+}
+
+type AGCResponse struct {
+	Success bool              `json:"Success"`
+	Error   *AGCResponseError `json:"Error"`
+}
+
+type AGCLoginResponse struct {
+	Success bool              `json:"Success"`
+	Error   *AGCResponseError `json:"Error"`
+	Url     string            `json:"Url"`
+	Data    struct {
+		Url   string `json:"Url"`
+		Token string `json:"Token"`
+	} `json:"Data"`
+}
+
+// GameUrl returns the launch url whether the agent puts it at the top level
+// or inside Data.
+func (r AGCLoginResponse) GameUrl() string {
+	if r.Data.Url != "" {
+		return r.Data.Url
+	}
+	return r.Url
+}
+
+type AGCTransferResponse struct {
+	Success bool              `json:"Success"`
+	Error   *AGCResponseError `json:"Error"`
+	Data    struct {
+		TransactionId string  `json:"TransactionId"`
+		Amount        float64 `json:"Amount"`
+		BeforeBalance float64 `json:"BeforeBalance"`
+		Balance       float64 `json:"Balance"`
+	} `json:"Data"`
+}
+
+type AGCBalanceResponse struct {
+	Success bool              `json:"Success"`
+	Error   *AGCResponseError `json:"Error"`
+	Data    struct {
+		PlayerName string  `json:"PlayerName"`
+		Balance    float64 `json:"Balance"`
+	} `json:"Data"`
+}
+
+type AGCBetHistoryResponse struct {
+	Success bool              `json:"Success"`
+	Error   *AGCResponseError `json:"Error"`
+	Data    struct {
+		Total   int            `json:"Total"`
+		Page    int            `json:"Page"`
+		Records []AGCBetRecord `json:"Records"`
+	} `json:"Data"`
+}
@@ -0,0 +1,26 @@
+package model
+
+type BankTransactionBulkRequest struct {
+	Ids []int64 `json:"ids" validate:"required,min=1,max=100,dive,gt=0"`
+}
+
+type BankTransactionBulkCancelRequest struct {
+	Ids          []int64 `json:"ids" validate:"required,min=1,max=100,dive,gt=0"`
+	CancelRemark string  `json:"cancelRemark" validate:"required"`
+}
+
+// BankTransactionBulkItem is the result of one id, Message and Code are the
+// error the single endpoint would have answered.
+type BankTransactionBulkItem struct {
+	Id      int64  `json:"id"`
+	Success bool   `json:"success"`
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type BankTransactionBulkResult struct {
+	Total     int                       `json:"total"`
+	Succeeded int                       `json:"succeeded"`
+	Failed    int                       `json:"failed"`
+	Items     []BankTransactionBulkItem `json:"items"`
+}
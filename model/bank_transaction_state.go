@@ -0,0 +1,72 @@
+package model
+
+// Bank transaction statuses.
+const (
+	BankTransactionPending         = "pending"
+	BankTransactionPendingCredit   = "pending_credit"
+	BankTransactionPendingAgent    = "pending_agent"
+	BankTransactionPendingTransfer = "pending_transfer"
+	BankTransactionFinished        = "finished"
+	BankTransactionCanceled        = "canceled"
+	BankTransactionRemoved         = "removed"
+)
+
+// Actions moving a bank transaction to its next status.
+const (
+	BankTransactionActionConfirm         = "confirm"
+	BankTransactionActionConfirmCredit   = "confirm_credit"
+	BankTransactionActionDebitAgent      = "debit_agent"
+	BankTransactionActionAgentDebited    = "agent_debited"
+	BankTransactionActionConfirmTransfer = "confirm_transfer"
+	BankTransactionActionCancel          = "cancel"
+	BankTransactionActionRemove          = "remove"
+)
+
+type BankTransactionTransition struct {
+	From []string
+	To   string
+}
+
+// BankTransactionTransitions is the status machine of each transfer type, an
+// action missing from a type is not allowed on it.
+var BankTransactionTransitions = map[string]map[string]BankTransactionTransition{
+	"deposit": {
+		BankTransactionActionConfirm:       {From: []string{BankTransactionPending}, To: BankTransactionPendingCredit},
+		BankTransactionActionConfirmCredit: {From: []string{BankTransactionPendingCredit}, To: BankTransactionFinished},
+		BankTransactionActionCancel:        {From: []string{BankTransactionPending, BankTransactionPendingCredit}, To: BankTransactionCanceled},
+		BankTransactionActionRemove:        {From: []string{BankTransactionFinished}, To: BankTransactionRemoved},
+	},
+	"bonus": {
+		BankTransactionActionConfirm:       {From: []string{BankTransactionPending}, To: BankTransactionPendingCredit},
+		BankTransactionActionConfirmCredit: {From: []string{BankTransactionPendingCredit}, To: BankTransactionFinished},
+		BankTransactionActionCancel:        {From: []string{BankTransactionPending, BankTransactionPendingCredit}, To: BankTransactionCanceled},
+		BankTransactionActionRemove:        {From: []string{BankTransactionFinished}, To: BankTransactionRemoved},
+	},
+	// a withdraw waits in pending_agent while the agent is debited, on request
+	// or on the confirm of one requested before the agent debit
+	"withdraw": {
+		BankTransactionActionDebitAgent:      {From: []string{BankTransactionPending, BankTransactionPendingCredit}, To: BankTransactionPendingAgent},
+		BankTransactionActionAgentDebited:    {From: []string{BankTransactionPendingAgent}, To: BankTransactionPendingCredit},
+		BankTransactionActionConfirmCredit:   {From: []string{BankTransactionPending, BankTransactionPendingCredit}, To: BankTransactionPendingTransfer},
+		BankTransactionActionConfirmTransfer: {From: []string{BankTransactionPendingTransfer}, To: BankTransactionFinished},
+		BankTransactionActionCancel:          {From: []string{BankTransactionPending, BankTransactionPendingCredit, BankTransactionPendingAgent, BankTransactionPendingTransfer}, To: BankTransactionCanceled},
+		BankTransactionActionRemove:          {From: []string{BankTransactionFinished}, To: BankTransactionRemoved},
+	},
+	"getcreditback": {
+		BankTransactionActionConfirmCredit: {From: []string{BankTransactionPending, BankTransactionPendingCredit}, To: BankTransactionFinished},
+		BankTransactionActionCancel:        {From: []string{BankTransactionPending, BankTransactionPendingCredit}, To: BankTransactionCanceled},
+		BankTransactionActionRemove:        {From: []string{BankTransactionFinished}, To: BankTransactionRemoved},
+	},
+}
+
+// AgentDebitStatuses are the statuses a transaction of transferType may still
+// be debited at the agent in, pending_agent for a withdraw and the confirm
+// credit ones for getcreditback, which is debited on its confirm.
+func AgentDebitStatuses(transferType string) []string {
+
+	transitions := BankTransactionTransitions[transferType]
+	if transition, ok := transitions[BankTransactionActionAgentDebited]; ok {
+		return transition.From
+	}
+	return transitions[BankTransactionActionConfirmCredit].From
+}
@@ -0,0 +1,118 @@
+package model
+
+import "testing"
+
+func canMove(transferType string, action string, from string) (string, bool) {
+	transition, ok := BankTransactionTransitions[transferType][action]
+	if !ok {
+		return "", false
+	}
+	for _, status := range transition.From {
+		if status == from {
+			return transition.To, true
+		}
+	}
+	return "", false
+}
+
+func TestBankTransactionTransitions(t *testing.T) {
+
+	allowed := []struct {
+		transferType string
+		action       string
+		from         string
+		to           string
+	}{
+		{"deposit", BankTransactionActionConfirm, BankTransactionPending, BankTransactionPendingCredit},
+		{"deposit", BankTransactionActionConfirmCredit, BankTransactionPendingCredit, BankTransactionFinished},
+		{"deposit", BankTransactionActionCancel, BankTransactionPending, BankTransactionCanceled},
+		{"deposit", BankTransactionActionCancel, BankTransactionPendingCredit, BankTransactionCanceled},
+		{"deposit", BankTransactionActionRemove, BankTransactionFinished, BankTransactionRemoved},
+		{"bonus", BankTransactionActionConfirm, BankTransactionPending, BankTransactionPendingCredit},
+		{"bonus", BankTransactionActionConfirmCredit, BankTransactionPendingCredit, BankTransactionFinished},
+		{"withdraw", BankTransactionActionDebitAgent, BankTransactionPending, BankTransactionPendingAgent},
+		{"withdraw", BankTransactionActionDebitAgent, BankTransactionPendingCredit, BankTransactionPendingAgent},
+		{"withdraw", BankTransactionActionAgentDebited, BankTransactionPendingAgent, BankTransactionPendingCredit},
+		{"withdraw", BankTransactionActionConfirmCredit, BankTransactionPendingCredit, BankTransactionPendingTransfer},
+		{"withdraw", BankTransactionActionCancel, BankTransactionPendingAgent, BankTransactionCanceled},
+		{"withdraw", BankTransactionActionConfirmTransfer, BankTransactionPendingTransfer, BankTransactionFinished},
+		{"withdraw", BankTransactionActionCancel, BankTransactionPendingTransfer, BankTransactionCanceled},
+		{"withdraw", BankTransactionActionRemove, BankTransactionFinished, BankTransactionRemoved},
+		{"getcreditback", BankTransactionActionConfirmCredit, BankTransactionPending, BankTransactionFinished},
+		{"getcreditback", BankTransactionActionCancel, BankTransactionPendingCredit, BankTransactionCanceled},
+	}
+
+	for _, test := range allowed {
+		to, ok := canMove(test.transferType, test.action, test.from)
+		if !ok || to != test.to {
+			t.Errorf("%s %s from %s = %q %v, want %s", test.transferType, test.action, test.from, to, ok, test.to)
+		}
+	}
+
+	rejected := []struct {
+		transferType string
+		action       string
+		from         string
+	}{
+		// a finished, canceled or removed transaction does not move again
+		{"deposit", BankTransactionActionConfirm, BankTransactionFinished},
+		{"deposit", BankTransactionActionCancel, BankTransactionFinished},
+		{"deposit", BankTransactionActionCancel, BankTransactionCanceled},
+		{"withdraw", BankTransactionActionConfirmCredit, BankTransactionCanceled},
+		{"withdraw", BankTransactionActionCancel, BankTransactionFinished},
+		{"withdraw", BankTransactionActionRemove, BankTransactionRemoved},
+		{"getcreditback", BankTransactionActionCancel, BankTransactionFinished},
+		// steps are not skipped or repeated
+		{"deposit", BankTransactionActionConfirmCredit, BankTransactionPending},
+		{"deposit", BankTransactionActionConfirm, BankTransactionPendingCredit},
+		{"withdraw", BankTransactionActionConfirmCredit, BankTransactionPendingTransfer},
+		{"withdraw", BankTransactionActionConfirmCredit, BankTransactionPendingAgent},
+		{"withdraw", BankTransactionActionAgentDebited, BankTransactionPendingCredit},
+		{"withdraw", BankTransactionActionDebitAgent, BankTransactionPendingAgent},
+		{"withdraw", BankTransactionActionConfirmTransfer, BankTransactionPending},
+		{"deposit", BankTransactionActionRemove, BankTransactionPending},
+		// actions a type does not have
+		{"deposit", BankTransactionActionConfirmTransfer, BankTransactionPendingTransfer},
+		{"deposit", BankTransactionActionCancel, BankTransactionPendingTransfer},
+		{"withdraw", BankTransactionActionConfirm, BankTransactionPending},
+		{"getcreditback", BankTransactionActionConfirm, BankTransactionPending},
+		{"getcreditback", BankTransactionActionDebitAgent, BankTransactionPending},
+		{"getcreditback", BankTransactionActionAgentDebited, BankTransactionPendingAgent},
+		{"getcreditback", BankTransactionActionConfirmTransfer, BankTransactionPendingTransfer},
+		{"transfer", BankTransactionActionConfirm, BankTransactionPending},
+	}
+
+	for _, test := range rejected {
+		if to, ok := canMove(test.transferType, test.action, test.from); ok {
+			t.Errorf("%s %s from %s moves to %s, want rejected", test.transferType, test.action, test.from, to)
+		}
+	}
+}
+
+// TestBankTransactionTransitionsEnd checks every transition leads to a known
+// status and nothing leaves removed.
+func TestBankTransactionTransitionsEnd(t *testing.T) {
+
+	known := map[string]bool{
+		BankTransactionPending:         true,
+		BankTransactionPendingCredit:   true,
+		BankTransactionPendingAgent:    true,
+		BankTransactionPendingTransfer: true,
+		BankTransactionFinished:        true,
+		BankTransactionCanceled:        true,
+		BankTransactionRemoved:         true,
+	}
+
+	for transferType, transitions := range BankTransactionTransitions {
+		for action, transition := range transitions {
+			if !known[transition.To] || len(transition.From) == 0 {
+				t.Errorf("%s %s: %v -> %q", transferType, action, transition.From, transition.To)
+			}
+			for _, from := range transition.From {
+				if !known[from] || from == BankTransactionRemoved || from == BankTransactionCanceled {
+					t.Errorf("%s %s starts from %q", transferType, action, from)
+				}
+			}
+		}
+	}
+}
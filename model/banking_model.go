@@ -10,7 +10,7 @@ type BankStatement struct {
 	Id                int64          `json:"id" gorm:"primaryKey"`
 	AccountId         int64          `json:"accountId"`
 	ExternalId        int64          `json:"externalId"`
-	Amount            float64        `json:"amount" sql:"type:decimal(14,2);"`
+	Amount            Money          `json:"amount" sql:"type:decimal(14,2);"`
 	Detail            string         `json:"detail"`
 	BankId            int64          `json:"bankId"`
 	StatementType     string         `json:"statementType"`
@@ -61,7 +61,7 @@ type BankStatementCreateBody struct {
 	Id                int64     `json:"id"`
 	AccountId         int64     `json:"accountId"`
 	ExternalId        int64     `json:"externalId"`
-	Amount            float64   `json:"amount" sql:"type:decimal(14,2);"`
+	Amount            Money     `json:"amount" sql:"type:decimal(14,2);"`
 	Detail            string    `json:"detail"`
 	FromBankId        int64     `json:"fromBankId"`
 	FromAccountNumber string    `json:"fromAccountNumber"`
@@ -88,7 +88,7 @@ type BankStatementResponse struct {
 	AccountName     string         `json:"accountName"`
 	AccountNumber   string         `json:"accountNumber"`
 	BankName        string         `json:"bankName"`
-	Amount          float64        `json:"amount" sql:"type:decimal(14,2);"`
+	Amount          Money          `json:"amount" sql:"type:decimal(14,2);"`
 	Detail          string         `json:"detail"`
 	FromBankId      int64          `json:"fromBankId"`
 	FromBankName    string         `json:"fromBankName"`
@@ -119,15 +119,15 @@ type BankTransaction struct {
 	ToBankCode          string         `json:"toBankCode"`
 	ToAccountName       string         `json:"toAccountName"`
 	ToAccountNumber     string         `json:"toAccountNumber"`
-	CreditAmount        float64        `json:"creditAmount" sql:"type:decimal(14,2);"`
-	PaidAmount          float64        `json:"paidAmount" sql:"type:decimal(14,2);"`
+	CreditAmount        Money          `json:"creditAmount" sql:"type:decimal(14,2);"`
+	PaidAmount          Money          `json:"paidAmount" sql:"type:decimal(14,2);"`
 	DepositChannel      string         `json:"depositChannel"`
-	OverAmount          float64        `json:"overAmount" sql:"type:decimal(14,2);"`
-	BonusAmount         float64        `json:"bonusAmount" sql:"type:decimal(14,2);"`
+	OverAmount          Money          `json:"overAmount" sql:"type:decimal(14,2);"`
+	BonusAmount         Money          `json:"bonusAmount" sql:"type:decimal(14,2);"`
 	BonusReason         string         `json:"bonusReason"`
-	BeforeAmount        float64        `json:"beforeAmount" sql:"type:decimal(14,2);"`
-	AfterAmount         float64        `json:"afterAmount" sql:"type:decimal(14,2);"`
-	BankChargeAmount    float64        `json:"bankChargeAmount" sql:"type:decimal(14,2);"`
+	BeforeAmount        Money          `json:"beforeAmount" sql:"type:decimal(14,2);"`
+	AfterAmount         Money          `json:"afterAmount" sql:"type:decimal(14,2);"`
+	BankChargeAmount    Money          `json:"bankChargeAmount" sql:"type:decimal(14,2);"`
 	TransferAt          *time.Time     `json:"transferAt"`
 	CreatedByUserId     int64          `json:"createdByUserId"`
 	CreatedByUsername   string         `json:"createdByUsername"`
@@ -181,17 +181,18 @@ type BankTransactionCreateBody struct {
 	ToBankId          *int64     `json:"-"`
 	ToAccountName     *string    `json:"-"`
 	ToAccountNumber   *string    `json:"-"`
-	CreditAmount      float64    `json:"creditAmount" validate:"required"`
-	PaidAmount        float64    `json:"-"`
+	CreditAmount      Money      `json:"creditAmount" validate:"required,gt=0,max=99999999999999"`
+	PaidAmount        Money      `json:"-"`
 	DepositChannel    string     `json:"depositChannel"`
-	OverAmount        float64    `json:"overAmount"`
-	BonusAmount       float64    `json:"bonusAmount"`
-	BeforeAmount      float64    `json:"-"`
-	AfterAmount       float64    `json:"-"`
+	OverAmount        Money      `json:"overAmount" validate:"gte=0,max=99999999999999"`
+	BonusAmount       Money      `json:"bonusAmount" validate:"gte=0,max=99999999999999"`
+	BeforeAmount      Money      `json:"-"`
+	AfterAmount       Money      `json:"-"`
 	TransferAt        *time.Time `json:"transferAt" example:"2023-05-31T22:33:44+07:00"`
 	CreatedByUserId   int64      `json:"-"`
 	CreatedByUsername string     `json:"-"`
 	Status            string     `json:"-"`
+	StatusDetail      string     `json:"-"`
 	IsAutoCredit      bool       `json:"isAutoCredit"`
 }
 
@@ -203,10 +204,10 @@ type BonusTransactionCreateBody struct {
 	ToBankId          int64     `json:"-"`
 	ToAccountName     string    `json:"-"`
 	ToAccountNumber   string    `json:"-"`
-	BonusAmount       float64   `json:"bonusAmount" validate:"required"`
+	BonusAmount       Money     `json:"bonusAmount" validate:"required,gt=0,max=99999999999999"`
 	BonusReason       string    `json:"bonusReason"`
-	BeforeAmount      float64   `json:"-"`
-	AfterAmount       float64   `json:"-"`
+	BeforeAmount      Money     `json:"-"`
+	AfterAmount       Money     `json:"-"`
 	TransferAt        time.Time `json:"transferAt" validate:"required" example:"2023-05-31T22:33:44+07:00"`
 	CreatedByUserId   int64     `json:"-"`
 	CreatedByUsername string    `json:"-"`
@@ -245,15 +246,15 @@ type BankTransactionResponse struct {
 	ToBankName          string         `json:"toBankName"`
 	ToAccountName       string         `json:"toAccountName"`
 	ToAccountNumber     string         `json:"toAccountNumber"`
-	CreditAmount        float64        `json:"creditAmount" sql:"type:decimal(14,2);"`
-	PaidAmount          float64        `json:"paidAmount" sql:"type:decimal(14,2);"`
+	CreditAmount        Money          `json:"creditAmount" sql:"type:decimal(14,2);"`
+	PaidAmount          Money          `json:"paidAmount" sql:"type:decimal(14,2);"`
 	DepositChannel      string         `json:"depositChannel"`
-	OverAmount          float64        `json:"overAmount" sql:"type:decimal(14,2);"`
-	BonusAmount         float64        `json:"bonusAmount" sql:"type:decimal(14,2);"`
+	OverAmount          Money          `json:"overAmount" sql:"type:decimal(14,2);"`
+	BonusAmount         Money          `json:"bonusAmount" sql:"type:decimal(14,2);"`
 	BonusReason         string         `json:"bonusReason"`
-	BeforeAmount        float64        `json:"beforeAmount" sql:"type:decimal(14,2);"`
-	AfterAmount         float64        `json:"afterAmount" sql:"type:decimal(14,2);"`
-	BankChargeAmount    float64        `json:"bankChargeAmount" sql:"type:decimal(14,2);"`
+	BeforeAmount        Money          `json:"beforeAmount" sql:"type:decimal(14,2);"`
+	AfterAmount         Money          `json:"afterAmount" sql:"type:decimal(14,2);"`
+	BankChargeAmount    Money          `json:"bankChargeAmount" sql:"type:decimal(14,2);"`
 	TransferAt          *time.Time     `json:"transferAt"`
 	CreatedByUserId     int64          `json:"createdByUserId"`
 	CreatedByUsername   string         `json:"createdByUsername"`
@@ -290,6 +291,7 @@ type BankDepositTransStatusCounts struct {
 type BankWithdrawTransStatusCounts struct {
 	AllCount             int64 `json:"allCount"`
 	PendingCreditCount   int64 `json:"pendingCreditCount"`
+	PendingAgentCount    int64 `json:"pendingAgentCount"`
 	PendingTransferCount int64 `json:"pendingTransferCount"`
 	FinishedCount        int64 `json:"finishedCount"`
 	FailedCount          int64 `json:"failedCount"`
@@ -350,7 +352,7 @@ type BankTransactionCancelBody struct {
 type BankConfirmDepositRequest struct {
 	TransferAt          *time.Time `json:"transferAt"`
 	SlipUrl             *string    `json:"slipUrl"`
-	BonusAmount         *float64   `json:"bonusAmount"`
+	BonusAmount         *Money     `json:"bonusAmount" validate:"omitempty,gte=0,max=99999999999999"`
 	ConfirmedAt         time.Time  `json:"-"`
 	ConfirmedByUserId   int64      `json:"-"`
 	ConfirmedByUsername string     `json:"-"`
@@ -358,15 +360,15 @@ type BankConfirmDepositRequest struct {
 
 type BankConfirmCreditWithdrawRequest struct {
 	FromAccountId       *int64    `json:"fromAccountId"`
-	CreditAmount        *float64  `json:"creditAmount"`
-	BankChargeAmount    *float64  `json:"bankChargeAmount"`
+	CreditAmount        *Money    `json:"creditAmount" validate:"omitempty,gt=0,max=99999999999999"`
+	BankChargeAmount    *Money    `json:"bankChargeAmount" validate:"omitempty,gte=0,max=99999999999999"`
 	ConfirmedAt         time.Time `json:"-"`
 	ConfirmedByUserId   int64     `json:"-"`
 	ConfirmedByUsername string    `json:"-"`
 }
 type BankConfirmTransferWithdrawRequest struct {
 	FromAccountId       *int64    `json:"fromAccountId"`
-	BankChargeAmount    *float64  `json:"bankChargeAmount"`
+	BankChargeAmount    *Money    `json:"bankChargeAmount" validate:"omitempty,gte=0,max=99999999999999"`
 	ConfirmedAt         time.Time `json:"-"`
 	ConfirmedByUserId   int64     `json:"-"`
 	ConfirmedByUsername string    `json:"-"`
@@ -374,7 +376,7 @@ type BankConfirmTransferWithdrawRequest struct {
 
 type BankDepositTransactionConfirmBody struct {
 	TransferAt          time.Time `json:"transferAt"`
-	BonusAmount         float64   `json:"bonusAmount"`
+	BonusAmount         Money     `json:"bonusAmount"`
 	Status              string    `json:"status"`
 	ConfirmedAt         time.Time `json:"confirmedAt"`
 	ConfirmedByUserId   int64     `json:"confirmedByUserId"`
@@ -384,8 +386,8 @@ type BankDepositTransactionConfirmBody struct {
 type BankWithdrawTransactionConfirmBody struct {
 	FromAccountId       *int64    `json:"fromAccountId"`
 	TransferAt          time.Time `json:"transferAt"`
-	CreditAmount        float64   `json:"creditAmount"`
-	BankChargeAmount    float64   `json:"bankChargeAmount"`
+	CreditAmount        Money     `json:"creditAmount"`
+	BankChargeAmount    Money     `json:"bankChargeAmount"`
 	Status              string    `json:"status"`
 	ConfirmedAt         time.Time `json:"confirmedAt"`
 	ConfirmedByUserId   int64     `json:"confirmedByUserId"`
@@ -403,9 +405,9 @@ type CreateBankTransactionActionBody struct {
 	JsonBefore          string     `json:"jsonBefore"`
 	TransferAt          *time.Time `json:"transferAt"`
 	SlipUrl             string     `json:"slipUrl"`
-	BonusAmount         float64    `json:"bonusAmount"`
-	CreditAmount        float64    `json:"creditAmount"`
-	BankChargeAmount    float64    `json:"bankChargeAmount"`
+	BonusAmount         Money      `json:"bonusAmount"`
+	CreditAmount        Money      `json:"creditAmount"`
+	BankChargeAmount    Money      `json:"bankChargeAmount"`
 	ConfirmedAt         time.Time  `json:"confirmedAt"`
 	ConfirmedByUserId   int64      `json:"confirmedByUserId"`
 	ConfirmedByUsername string     `json:"confirmedByUsername"`
@@ -428,36 +430,39 @@ type BankTransactionRemoveBody struct {
 	RemovedByUsername string    `json:"removedByUsername"`
 }
 type BankAutoWithdrawCondition struct {
-	TransId                 int64   `json:"TransId"`
-	TransStatus             string  `json:"TransStatus"`
-	UserId                  int64   `json:"UserId"`
-	FromAccountId           int64   `json:"toAccountId"`
-	CreditAmount            float64 `json:"CreditAmount"`
-	BankChargeAmount        float64 `json:"BankChargeAmount"`
-	MinCreditAmount         float64 `json:"minCreditAmount"`
-	MaxCreditAmount         float64 `json:"maxCreditAmount"`
-	AutoWithdrawCreditFlag  string  `json:"autoWithdrawCreditFlag"`
-	AutoWithdrawConfirmFlag string  `json:"autoWithdrawConfirmFlag"`
+	TransId                 int64  `json:"TransId"`
+	TransStatus             string `json:"TransStatus"`
+	UserId                  int64  `json:"UserId"`
+	FromAccountId           int64  `json:"toAccountId"`
+	CreditAmount            Money  `json:"CreditAmount"`
+	BankChargeAmount        Money  `json:"BankChargeAmount"`
+	MinCreditAmount         Money  `json:"minCreditAmount"`
+	MaxCreditAmount         Money  `json:"maxCreditAmount"`
+	AutoWithdrawCreditFlag  string `json:"autoWithdrawCreditFlag"`
+	AutoWithdrawConfirmFlag string `json:"autoWithdrawConfirmFlag"`
+	StatusDetail            string `json:"statusDetail"`
 }
 
 type Member struct {
-	Id            int64     `json:"id"`
-	MemberCode    string    `json:"memberCode"`
-	Username      string    `json:"username"`
-	Phone         string    `json:"phone"`
-	Firstname     string    `json:"firstname"`
-	Lastname      string    `json:"lastname"`
-	Fullname      string    `json:"fullname"`
-	Credit        float64   `json:"credit"`
-	Bankname      string    `json:"bankname"`
-	BankAccount   string    `json:"bankAccount"`
-	Promotion     string    `json:"promotion"`
-	Status        string    `json:"status"`
-	Channel       string    `json:"channel"`
-	TrueWallet    string    `json:"trueWallet"`
-	Note          string    `json:"note"`
-	TurnoverLimit int       `json:"turnoverLimit"`
-	CreatedAt     time.Time `json:"createdAt"`
+	Id             int64     `json:"id"`
+	MemberCode     string    `json:"memberCode"`
+	Username       string    `json:"username"`
+	Phone          string    `json:"phone"`
+	Firstname      string    `json:"firstname"`
+	Lastname       string    `json:"lastname"`
+	Fullname       string    `json:"fullname"`
+	Credit         Money     `json:"credit"`
+	CreditReserved Money     `json:"creditReserved"`
+	Bankname       string    `json:"bankname"`
+	BankAccount    string    `json:"bankAccount"`
+	Promotion      string    `json:"promotion"`
+	Status         string    `json:"status"`
+	Channel        string    `json:"channel"`
+	TrueWallet     string    `json:"trueWallet"`
+	Note           string    `json:"note"`
+	TurnoverLimit  int       `json:"turnoverLimit"`
+	Turnover       float64   `json:"turnover"`
+	CreatedAt      time.Time `json:"createdAt"`
 }
 type MemberListRequest struct {
 	Search  string `form:"search" extensions:"x-order:1"`
@@ -493,15 +498,15 @@ type MemberTransaction struct {
 	ToBankName          string         `json:"toBankName"`
 	ToAccountName       string         `json:"toAccountName"`
 	ToAccountNumber     string         `json:"toAccountNumber"`
-	CreditAmount        float64        `json:"creditAmount" sql:"type:decimal(14,2);"`
-	PaidAmount          float64        `json:"paidAmount" sql:"type:decimal(14,2);"`
+	CreditAmount        Money          `json:"creditAmount" sql:"type:decimal(14,2);"`
+	PaidAmount          Money          `json:"paidAmount" sql:"type:decimal(14,2);"`
 	DepositChannel      string         `json:"depositChannel"`
-	OverAmount          float64        `json:"overAmount" sql:"type:decimal(14,2);"`
-	BonusAmount         float64        `json:"bonusAmount" sql:"type:decimal(14,2);"`
+	OverAmount          Money          `json:"overAmount" sql:"type:decimal(14,2);"`
+	BonusAmount         Money          `json:"bonusAmount" sql:"type:decimal(14,2);"`
 	BonusReason         string         `json:"bonusReason"`
-	BeforeAmount        float64        `json:"beforeAmount" sql:"type:decimal(14,2);"`
-	AfterAmount         float64        `json:"afterAmount" sql:"type:decimal(14,2);"`
-	BankChargeAmount    float64        `json:"bankChargeAmount" sql:"type:decimal(14,2);"`
+	BeforeAmount        Money          `json:"beforeAmount" sql:"type:decimal(14,2);"`
+	AfterAmount         Money          `json:"afterAmount" sql:"type:decimal(14,2);"`
+	BankChargeAmount    Money          `json:"bankChargeAmount" sql:"type:decimal(14,2);"`
 	TransferAt          time.Time      `json:"transferAt"`
 	CreatedByUserId     int64          `json:"createdByUserId"`
 	CreatedByUsername   string         `json:"createdByUsername"`
@@ -534,9 +539,9 @@ type MemberTransactionListRequest struct {
 	SortAsc          string `form:"sortAsc" extensions:"x-order:9"`
 }
 type MemberTransactionSummary struct {
-	TotalDepositAmount  float64 `json:"totalDepositAmount"`
-	TotalWithdrawAmount float64 `json:"totalWithdrawAmount"`
-	TotalBonusAmount    float64 `json:"totalBonusAmount"`
+	TotalDepositAmount  Money `json:"totalDepositAmount"`
+	TotalWithdrawAmount Money `json:"totalWithdrawAmount"`
+	TotalBonusAmount    Money `json:"totalBonusAmount"`
 }
 
 type MemberStatementType struct {
@@ -553,16 +558,16 @@ type MemberStatement struct {
 	StatementTypeId int64          `json:"statementTypeId"`
 	TransferAt      time.Time      `json:"transferAt"`
 	Info            string         `json:"info"`
-	BeforeBalance   float64        `json:"beforeBalance" sql:"type:decimal(14,2);"`
-	Amount          float64        `json:"amount" sql:"type:decimal(14,2);"`
-	AfterBalance    float64        `json:"afterBalance" sql:"type:decimal(14,2);"`
+	BeforeBalance   Money          `json:"beforeBalance" sql:"type:decimal(14,2);"`
+	Amount          Money          `json:"amount" sql:"type:decimal(14,2);"`
+	AfterBalance    Money          `json:"afterBalance" sql:"type:decimal(14,2);"`
 	CreatedAt       time.Time      `json:"createAt"`
 	UpdatedAt       *time.Time     `json:"updateAt"`
 	DeletedAt       gorm.DeletedAt `json:"deleteAt"`
 }
 type MemberStatementCreateRequest struct {
-	UserId int64   `json:"userId"`
-	Amount float64 `json:"amount"`
+	UserId int64 `json:"userId"`
+	Amount Money `json:"amount"`
 }
 type MemberStatementCreateBody struct {
 	Id              int64 `json:"id"`
@@ -570,9 +575,34 @@ type MemberStatementCreateBody struct {
 	StatementTypeId int64 `json:"statementTypeId"`
 	// TransferAt      time.Time `json:"transferAt"`
 	Info string `json:"info"`
-	// BeforeBalance   float64   `json:"beforeBalance" sql:"type:decimal(14,2);"`
-	Amount float64 `json:"amount" sql:"type:decimal(14,2);"`
-	// AfterBalance    float64   `json:"afterBalance" sql:"type:decimal(14,2);"`
+	// BeforeBalance   Money   `json:"beforeBalance" sql:"type:decimal(14,2);"`
+	Amount Money `json:"amount" sql:"type:decimal(14,2);"`
+	// AfterBalance    Money   `json:"afterBalance" sql:"type:decimal(14,2);"`
+	// BankAccountId is the system bank account of a deposit or withdraw, the
+	// ledger posts against it
+	BankAccountId *int64 `json:"-" gorm:"-"`
+}
+type MemberCreditSyncBody struct {
+	UserId *int64 `json:"userId"`
+	DryRun bool   `json:"dryRun"`
+}
+type MemberCreditSyncItem struct {
+	UserId       int64  `json:"userId"`
+	MemberCode   string `json:"memberCode"`
+	Username     string `json:"username"`
+	Credit       Money  `json:"credit"`
+	AgentBalance Money  `json:"agentBalance"`
+	Difference   Money  `json:"difference"`
+	Adjusted     bool   `json:"adjusted"`
+	Error        string `json:"error,omitempty"`
+}
+type MemberCreditSyncResult struct {
+	Checked    int                    `json:"checked"`
+	Matched    int                    `json:"matched"`
+	Adjusted   int                    `json:"adjusted"`
+	Skipped    int                    `json:"skipped"`
+	Failed     int                    `json:"failed"`
+	Mismatches []MemberCreditSyncItem `json:"mismatches"`
 }
 type MemberStatementListRequest struct {
 	UserId           string `form:"userId" extensions:"x-order:1"`
@@ -595,9 +625,9 @@ type MemberStatementResponse struct {
 	StatementTypeName string         `json:"statementTypeName"`
 	TransferAt        time.Time      `json:"transferAt"`
 	Info              string         `json:"info"`
-	BeforeBalance     float64        `json:"beforeBalance" sql:"type:decimal(14,2);"`
-	Amount            float64        `json:"amount" sql:"type:decimal(14,2);"`
-	AfterBalance      float64        `json:"afterBalance" sql:"type:decimal(14,2);"`
+	BeforeBalance     Money          `json:"beforeBalance" sql:"type:decimal(14,2);"`
+	Amount            Money          `json:"amount" sql:"type:decimal(14,2);"`
+	AfterBalance      Money          `json:"afterBalance" sql:"type:decimal(14,2);"`
 	CreatedAt         time.Time      `json:"createAt"`
 	UpdatedAt         *time.Time     `json:"updateAt"`
 	DeletedAt         gorm.DeletedAt `json:"deleteAt"`
@@ -0,0 +1,80 @@
+package model
+
+import "time"
+
+type Bet struct {
+	Id           int64      `json:"id" gorm:"primaryKey"`
+	Provider     string     `json:"provider"`
+	BetRef       string     `json:"betRef"`
+	UserId       int64      `json:"userId"`
+	Username     string     `json:"username"`
+	GameCode     string     `json:"gameCode"`
+	RoundId      string     `json:"roundId"`
+	BetAmount    float64    `json:"betAmount"`
+	ValidAmount  float64    `json:"validAmount"`
+	PayoutAmount float64    `json:"payoutAmount"`
+	WinLoss      float64    `json:"winLoss"`
+	Status       string     `json:"status"`
+	Source       string     `json:"source"`
+	BetAt        time.Time  `json:"betAt"`
+	SettledAt    *time.Time `json:"settledAt"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    *time.Time `json:"updatedAt"`
+}
+
+type BetListRequest struct {
+	UserId      int64  `form:"userId" extensions:"x-order:1"`
+	Provider    string `form:"provider" extensions:"x-order:2"`
+	Status      string `form:"status" extensions:"x-order:3"`
+	FromBetDate string `form:"fromBetDate" extensions:"x-order:4"`
+	ToBetDate   string `form:"toBetDate" extensions:"x-order:5"`
+	Page        int    `form:"page" extensions:"x-order:6" default:"1" min:"1"`
+	Limit       int    `form:"limit" extensions:"x-order:7" default:"10" min:"1" max:"100"`
+}
+
+type BetImportItem struct {
+	BetId        string     `json:"betId" validate:"required,max=100"`
+	Username     string     `json:"username" validate:"required"`
+	Provider     string     `json:"provider" validate:"required,max=50"`
+	GameCode     string     `json:"gameCode"`
+	RoundId      string     `json:"roundId"`
+	BetAmount    float64    `json:"betAmount" validate:"gte=0"`
+	ValidAmount  float64    `json:"validAmount" validate:"gte=0"`
+	PayoutAmount float64    `json:"payoutAmount" validate:"gte=0"`
+	WinLoss      float64    `json:"winLoss"`
+	Status       string     `json:"status" validate:"required,oneof=pending settled canceled"`
+	BetAt        time.Time  `json:"betAt" validate:"required"`
+	SettledAt    *time.Time `json:"settledAt"`
+}
+
+type BetImportBody struct {
+	Bets []BetImportItem `json:"bets" validate:"required,min=1,max=1000,dive"`
+}
+
+type BetSyncBody struct {
+	From *time.Time `json:"from"`
+	To   *time.Time `json:"to"`
+}
+
+// BetSaveResult counts what SaveBets did, TurnoverAdded may be negative when
+// settled bets were canceled.
+type BetSaveResult struct {
+	Created       int     `json:"created"`
+	Updated       int     `json:"updated"`
+	Unchanged     int     `json:"unchanged"`
+	TurnoverAdded float64 `json:"turnoverAdded"`
+}
+
+type BetImportResult struct {
+	BetSaveResult
+	Received       int      `json:"received"`
+	UnknownMembers []string `json:"unknownMembers"`
+}
+
+type MemberTurnover struct {
+	UserId        int64   `json:"userId"`
+	TurnoverLimit int     `json:"turnoverLimit"`
+	Turnover      float64 `json:"turnover"`
+	Remaining     float64 `json:"remaining"`
+	IsPassed      bool    `json:"isPassed"`
+}
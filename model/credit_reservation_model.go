@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// Credit reservation statuses. A reservation holds member credit from the
+// withdraw request until the withdraw is confirmed (captured) or canceled
+// (released).
+const (
+	CreditReservationReserved = "reserved"
+	CreditReservationReleased = "released"
+	CreditReservationCaptured = "captured"
+)
+
+type CreditReservation struct {
+	Id            int64      `json:"id" gorm:"primaryKey"`
+	UserId        int64      `json:"userId"`
+	TransactionId int64      `json:"transactionId"`
+	Amount        Money      `json:"amount"`
+	Status        string     `json:"status"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     *time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,284 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Game struct {
+	Id                int64             `json:"id" gorm:"primaryKey"`
+	Provider          string            `json:"provider"`
+	GameCode          string            `json:"gameCode"`
+	DeviceCodes       map[string]string `json:"deviceCodes" gorm:"serializer:json"`
+	Name              string            `json:"name"`
+	Names             map[string]string `json:"names" gorm:"serializer:json"`
+	GameType          string            `json:"gameType"`
+	ProviderGameType  string            `json:"providerGameType"`
+	ImageIcon         string            `json:"imageIcon"`
+	ImageMirror       *string           `json:"imageMirror"`
+	ImageMirrorSource *string           `json:"imageMirrorSource"`
+	ImageMirrorStatus *string           `json:"imageMirrorStatus"`
+	ImageMirroredAt   *time.Time        `json:"imageMirroredAt"`
+	Currencies        []string          `json:"currencies" gorm:"serializer:json"`
+	IsDesktop         bool              `json:"isDesktop"`
+	IsMobile          bool              `json:"isMobile"`
+	IsHot             bool              `json:"isHot"`
+	IsNew             bool              `json:"isNew"`
+	IsRecommend       bool              `json:"isRecommend"`
+	IsAvailable       bool              `json:"isAvailable"`
+	IsHidden          bool              `json:"isHidden"`
+	IsEnabled         bool              `json:"isEnabled"`
+	SortOrder         int               `json:"sortOrder"`
+	SearchNames       string            `json:"-"`
+	SearchPhonetic    string            `json:"-"`
+	ReleasedAt        *time.Time        `json:"releasedAt"`
+	CreatedAt         time.Time         `json:"createAt"`
+	UpdatedAt         *time.Time        `json:"updateAt"`
+	DeletedAt         gorm.DeletedAt    `json:"deleteAt"`
+}
+
+type GameListRequest struct {
+	Provider    string `form:"provider" extensions:"x-order:1"`
+	GameType    string `form:"gameType" extensions:"x-order:2" enums:"slot,fishing,table,poker,arcade,bingo,other"`
+	Device      string `form:"device" extensions:"x-order:3" enums:"desktop,mobile" validate:"omitempty,oneof=desktop mobile"`
+	Currency    string `form:"currency" extensions:"x-order:4" example:"THB"`
+	IsHot       bool   `form:"isHot" extensions:"x-order:5"`
+	IsNew       bool   `form:"isNew" extensions:"x-order:6"`
+	IsRecommend bool   `form:"isRecommend" extensions:"x-order:7"`
+	Page        int    `form:"page" extensions:"x-order:8" default:"1" min:"1"`
+	Limit       int    `form:"limit" extensions:"x-order:9" default:"10" min:"1" max:"100"`
+}
+
+type GameDetailRequest struct {
+	Provider string `uri:"provider" binding:"required"`
+	GameCode string `uri:"code" binding:"required"`
+}
+
+type GameResponse struct {
+	Id          int64             `json:"id"`
+	Provider    string            `json:"provider"`
+	GameCode    string            `json:"gameCode"`
+	DeviceCodes map[string]string `json:"deviceCodes" gorm:"serializer:json"`
+	Name        string            `json:"name"`
+	Names       map[string]string `json:"names" gorm:"serializer:json"`
+	GameType    string            `json:"gameType"`
+	ImageIcon   string            `json:"imageIcon"`
+	// the mirror columns pick which icon url members get
+	ImageMirror       *string  `json:"-"`
+	ImageMirrorSource *string  `json:"-"`
+	ImageMirrorStatus *string  `json:"-"`
+	Currencies        []string `json:"currencies" gorm:"serializer:json"`
+	IsDesktop         bool     `json:"isDesktop"`
+	IsMobile          bool     `json:"isMobile"`
+	IsHot             bool     `json:"isHot"`
+	IsNew             bool     `json:"isNew"`
+	IsRecommend       bool     `json:"isRecommend"`
+	// Maintenance is set while a maintenance window covers the game
+	Maintenance *GameMaintenanceResponse `json:"maintenance" gorm:"-"`
+}
+
+type GameProviderResponse struct {
+	Provider    string                   `json:"provider"`
+	Total       int64                    `json:"total"`
+	Maintenance *GameMaintenanceResponse `json:"maintenance" gorm:"-"`
+}
+
+type GameLaunchRequest struct {
+	IsMobile bool   `json:"isMobile"`
+	Lang     string `json:"lang" example:"th-th" validate:"max=10"`
+	Domain   string `json:"domain" example:"https://www.example.com" validate:"omitempty,url,max=255"`
+	UserId   int64  `json:"-"`
+	Ip       string `json:"-"`
+}
+
+type GameLaunchResponse struct {
+	Provider string `json:"provider"`
+	GameCode string `json:"gameCode"`
+	Url      string `json:"url"`
+}
+
+type GameLaunchLog struct {
+	Id           int64     `json:"id" gorm:"primaryKey"`
+	UserId       int64     `json:"userId"`
+	GameId       int64     `json:"gameId"`
+	Provider     string    `json:"provider"`
+	GameCode     string    `json:"gameCode"`
+	IsMobile     bool      `json:"isMobile"`
+	Ip           string    `json:"ip"`
+	GameUrl      string    `json:"gameUrl"`
+	Status       string    `json:"status"`
+	ErrorMessage string    `json:"errorMessage"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+type GameAdminListRequest struct {
+	Provider string `form:"provider" extensions:"x-order:1"`
+	GameType string `form:"gameType" extensions:"x-order:2"`
+	Search   string `form:"search" extensions:"x-order:3"`
+	Page     int    `form:"page" extensions:"x-order:4" default:"1" min:"1"`
+	Limit    int    `form:"limit" extensions:"x-order:5" default:"10" min:"1" max:"100"`
+}
+
+type GameUpdateBody struct {
+	SortOrder *int  `json:"sortOrder"`
+	IsHidden  *bool `json:"isHidden"`
+	IsEnabled *bool `json:"isEnabled"`
+}
+
+// GameSyncRequest names the provider whose list, as deployed with the API in
+// json/, is compared or written. A new list is shipped by a deploy, the
+// endpoints do not take one.
+type GameSyncRequest struct {
+	Provider string `json:"provider" validate:"required" example:"habanero"`
+	Dir      string `json:"-"`
+}
+
+type GameSyncItem struct {
+	GameCode string `json:"gameCode"`
+	Name     string `json:"name"`
+}
+
+type GameSyncChange struct {
+	GameCode string   `json:"gameCode"`
+	Name     string   `json:"name"`
+	Fields   []string `json:"fields"`
+}
+
+type GameSyncResult struct {
+	Provider  string           `json:"provider"`
+	Added     []GameSyncItem   `json:"added"`
+	Removed   []GameSyncItem   `json:"removed"`
+	Changed   []GameSyncChange `json:"changed"`
+	Unchanged int              `json:"unchanged"`
+	Applied   bool             `json:"applied"`
+}
+
+type GameProvider struct {
+	Id        int64      `json:"id" gorm:"primaryKey"`
+	Code      string     `json:"code"`
+	IsEnabled bool       `json:"isEnabled"`
+	UpdatedBy *int64     `json:"updatedBy"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt *time.Time `json:"updatedAt"`
+}
+
+type GameProviderStatusResponse struct {
+	Code          string `json:"code"`
+	IsEnabled     bool   `json:"isEnabled"`
+	Total         int64  `json:"total"`
+	IsMaintenance bool   `json:"isMaintenance"`
+}
+
+type GameProviderUpdateBody struct {
+	IsEnabled *bool `json:"isEnabled" validate:"required"`
+	UpdatedBy int64 `json:"-"`
+}
+
+type GameMaintenance struct {
+	Id        int64          `json:"id" gorm:"primaryKey"`
+	Provider  string         `json:"provider"`
+	GameId    *int64         `json:"gameId"`
+	StartAt   time.Time      `json:"startAt"`
+	EndAt     time.Time      `json:"endAt"`
+	MessageTh string         `json:"messageTh"`
+	MessageEn string         `json:"messageEn"`
+	CreatedBy *int64         `json:"createdBy"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt *time.Time     `json:"updatedAt"`
+	DeletedAt gorm.DeletedAt `json:"deletedAt"`
+}
+
+type GameMaintenanceListRequest struct {
+	Provider string `form:"provider" extensions:"x-order:1"`
+	IsActive bool   `form:"isActive" extensions:"x-order:2"`
+	Page     int    `form:"page" extensions:"x-order:3" default:"1" min:"1"`
+	Limit    int    `form:"limit" extensions:"x-order:4" default:"10" min:"1" max:"100"`
+}
+
+type GameMaintenanceBody struct {
+	Provider  string    `json:"provider" validate:"required" example:"habanero"`
+	GameId    *int64    `json:"gameId"`
+	StartAt   time.Time `json:"startAt" validate:"required"`
+	EndAt     time.Time `json:"endAt" validate:"required,gtfield=StartAt"`
+	MessageTh string    `json:"messageTh" validate:"max=255" example:"ปิดปรับปรุงระบบ"`
+	MessageEn string    `json:"messageEn" validate:"max=255" example:"Under maintenance"`
+	CreatedBy int64     `json:"-"`
+}
+
+type GameMaintenanceResponse struct {
+	StartAt   time.Time `json:"startAt"`
+	EndAt     time.Time `json:"endAt"`
+	MessageTh string    `json:"messageTh"`
+	MessageEn string    `json:"messageEn"`
+}
+
+type GameIconMirrorRequest struct {
+	Provider    string `json:"provider" example:"habanero"`
+	RetryFailed bool   `json:"retryFailed"`
+}
+
+type GameIconMirror struct {
+	Id        int64
+	ImageIcon string
+	Provider  string
+}
+
+type GameIconMirrorResult struct {
+	Total   int `json:"total"`
+	Success int `json:"success"`
+	Failed  int `json:"failed"`
+}
+
+type GameSearchRequest struct {
+	Q        string `form:"q" extensions:"x-order:1" example:"bonanza" validate:"required,max=100"`
+	Provider string `form:"provider" extensions:"x-order:2"`
+	GameType string `form:"gameType" extensions:"x-order:3" enums:"slot,fishing,table,poker,arcade,bingo,other"`
+	Device   string `form:"device" extensions:"x-order:4" enums:"desktop,mobile" validate:"omitempty,oneof=desktop mobile"`
+	Page     int    `form:"page" extensions:"x-order:5" default:"1" min:"1"`
+	Limit    int    `form:"limit" extensions:"x-order:6" default:"10" min:"1" max:"100"`
+}
+
+type GameSearchCandidate struct {
+	GameResponse
+	SearchNames    string
+	SearchPhonetic string
+	SortOrder      int
+}
+
+type GameSearchKeys struct {
+	Id    int64
+	Name  string
+	Names map[string]string `gorm:"serializer:json"`
+}
+
+type GameFavorite struct {
+	Id        int64     `json:"id" gorm:"primaryKey"`
+	UserId    int64     `json:"userId"`
+	GameId    int64     `json:"gameId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type GameRecentPlay struct {
+	Id           int64     `json:"id" gorm:"primaryKey"`
+	UserId       int64     `json:"userId"`
+	GameId       int64     `json:"gameId"`
+	PlayCount    int       `json:"playCount"`
+	LastPlayedAt time.Time `json:"lastPlayedAt"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+type GameFavoriteListRequest struct {
+	Page  int `form:"page" extensions:"x-order:1" default:"1" min:"1"`
+	Limit int `form:"limit" extensions:"x-order:2" default:"10" min:"1" max:"100"`
+}
+
+type GameRecentListRequest struct {
+	Limit int `form:"limit" extensions:"x-order:1" default:"20" min:"1" max:"50" validate:"omitempty,min=1,max=50"`
+}
+
+type GameRecentResponse struct {
+	GameResponse
+	PlayCount    int       `json:"playCount"`
+	LastPlayedAt time.Time `json:"lastPlayedAt"`
+}
@@ -0,0 +1,127 @@
+package model
+
+import "time"
+
+// Ledger account types. Members, equity and income are credit-normal, their
+// balance is credit - debit. Assets and expenses are debit-normal.
+const (
+	LedgerAccountMember  = "member"
+	LedgerAccountAsset   = "asset"
+	LedgerAccountExpense = "expense"
+	LedgerAccountIncome  = "income"
+	LedgerAccountEquity  = "equity"
+)
+
+type LedgerAccount struct {
+	Id            int64      `json:"id" gorm:"primaryKey"`
+	Code          string     `json:"code"`
+	Name          string     `json:"name"`
+	Type          string     `json:"type"`
+	UserId        *int64     `json:"userId"`
+	BankAccountId *int64     `json:"bankAccountId"`
+	Balance       Money      `json:"balance"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     *time.Time `json:"updatedAt"`
+}
+
+type LedgerAccountListRequest struct {
+	Type   string `form:"type" extensions:"x-order:1"`
+	UserId int64  `form:"userId" extensions:"x-order:2"`
+	Search string `form:"search" extensions:"x-order:3"`
+	Page   int    `form:"page" extensions:"x-order:4" default:"1" min:"1"`
+	Limit  int    `form:"limit" extensions:"x-order:5" default:"10" min:"1" max:"100"`
+}
+
+type LedgerTransaction struct {
+	Id          int64     `json:"id" gorm:"primaryKey"`
+	Code        string    `json:"code"`
+	UserId      *int64    `json:"userId"`
+	StatementId *int64    `json:"statementId"`
+	Info        string    `json:"info"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type LedgerEntry struct {
+	Id            int64     `json:"id" gorm:"primaryKey"`
+	TransactionId int64     `json:"transactionId"`
+	AccountId     int64     `json:"accountId"`
+	Debit         Money     `json:"debit"`
+	Credit        Money     `json:"credit"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+type LedgerEntryResponse struct {
+	Id              int64     `json:"id"`
+	TransactionId   int64     `json:"transactionId"`
+	TransactionCode string    `json:"transactionCode"`
+	Info            string    `json:"info"`
+	AccountId       int64     `json:"accountId"`
+	AccountCode     string    `json:"accountCode"`
+	AccountName     string    `json:"accountName"`
+	Debit           Money     `json:"debit"`
+	Credit          Money     `json:"credit"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+type LedgerEntryListRequest struct {
+	AccountId     int64  `form:"accountId" extensions:"x-order:1"`
+	TransactionId int64  `form:"transactionId" extensions:"x-order:2"`
+	UserId        int64  `form:"userId" extensions:"x-order:3"`
+	FromDate      string `form:"fromDate" extensions:"x-order:4"`
+	ToDate        string `form:"toDate" extensions:"x-order:5"`
+	Page          int    `form:"page" extensions:"x-order:6" default:"1" min:"1"`
+	Limit         int    `form:"limit" extensions:"x-order:7" default:"10" min:"1" max:"100"`
+}
+
+// LedgerPosting is one balanced movement of member credit. The member
+// account and the contra account named by ContraCode, or the bank account
+// when BankAccountId is set, are created on first use.
+type LedgerPosting struct {
+	Code        string
+	UserId      int64
+	StatementId *int64
+	Info        string
+	// Amount is added to the member credit, negative takes credit away
+	Amount        Money
+	ContraCode    string
+	BankAccountId *int64
+}
+
+type LedgerUnbalanced struct {
+	TransactionId int64 `json:"transactionId"`
+	Debit         Money `json:"debit"`
+	Credit        Money `json:"credit"`
+}
+
+type LedgerBalanceMismatch struct {
+	AccountId int64  `json:"accountId"`
+	Code      string `json:"code"`
+	Stored    Money  `json:"stored"`
+	Derived   Money  `json:"derived"`
+}
+
+type LedgerCreditMismatch struct {
+	UserId int64 `json:"userId"`
+	Credit Money `json:"credit"`
+	Ledger Money `json:"ledger"`
+}
+
+// LedgerVerifyResult is empty of mismatches when every transaction balances,
+// every stored balance equals the sum of its entries and every Users.credit
+// equals the member account.
+type LedgerVerifyResult struct {
+	TotalDebit      Money                   `json:"totalDebit"`
+	TotalCredit     Money                   `json:"totalCredit"`
+	IsBalanced      bool                    `json:"isBalanced"`
+	Unbalanced      []LedgerUnbalanced      `json:"unbalanced"`
+	BalanceMismatch []LedgerBalanceMismatch `json:"balanceMismatch"`
+	CreditMismatch  []LedgerCreditMismatch  `json:"creditMismatch"`
+}
+
+// Balanced tells whether nothing was found out of balance.
+func (r LedgerVerifyResult) Balanced() bool {
+	return r.TotalDebit == r.TotalCredit &&
+		len(r.Unbalanced) == 0 &&
+		len(r.BalanceMismatch) == 0 &&
+		len(r.CreditMismatch) == 0
+}
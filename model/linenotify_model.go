@@ -6,7 +6,7 @@ import (
 
 type Linenotify struct {
 	Id          int64      `json:"id"`
-	StartCredit float32    `json:"startcredit" sql:"type:decimal(14,2);"`
+	StartCredit Money      `json:"startcredit" sql:"type:decimal(14,2);"`
 	Token       string     `json:"token" validate:"required"`
 	NotifyId    int64      `json:"notifyId" validate:"required"`
 	Status      string     `json:"status"`
@@ -14,11 +14,11 @@ type Linenotify struct {
 	UpdatedAt   *time.Time `json:"updatedAt"`
 }
 type LinenotifyResponse struct {
-	Id          int64   `json:"id"`
-	StartCredit float32 `json:"startcredit" sql:"type:decimal(14,2);"`
-	Token       string  `json:"token" validate:"required"`
-	NotifyId    int64   `json:"notifyId" validate:"required"`
-	Status      string  `json:"status"`
+	Id          int64  `json:"id"`
+	StartCredit Money  `json:"startcredit" sql:"type:decimal(14,2);"`
+	Token       string `json:"token" validate:"required"`
+	NotifyId    int64  `json:"notifyId" validate:"required"`
+	Status      string `json:"status"`
 }
 type LinenotifyListResponse struct {
 	Id    int `json:"id"`
@@ -38,23 +38,23 @@ type LinenotifyListRequest struct {
 }
 
 type LinenotifyCreateBody struct {
-	StartCredit float32 `json:"startcredit" sql:"type:decimal(14,2);"`
-	Token       string  `json:"token" validate:"required"`
-	NotifyId    int64   `json:"notifyId" validate:"required"`
-	Status      string  `json:"status"`
+	StartCredit Money  `json:"startcredit" sql:"type:decimal(14,2);"`
+	Token       string `json:"token" validate:"required"`
+	NotifyId    int64  `json:"notifyId" validate:"required"`
+	Status      string `json:"status"`
 }
 type LinenotifyUpdateBody struct {
-	StartCredit float32 `json:"startcredit" sql:"type:decimal(14,2);"`
-	Token       string  `json:"token" validate:"required"`
-	NotifyId    int64   `json:"notifyId" validate:"required"`
-	Status      string  `json:"status"`
+	StartCredit Money  `json:"startcredit" sql:"type:decimal(14,2);"`
+	Token       string `json:"token" validate:"required"`
+	NotifyId    int64  `json:"notifyId" validate:"required"`
+	Status      string `json:"status"`
 }
 
 type LinenotifyUpdateRequest struct {
-	StartCredit float32 `json:"startcredit" sql:"type:decimal(14,2);"`
-	Token       string  `json:"token" validate:"required"`
-	NotifyId    int64   `json:"notifyId" validate:"required"`
-	Status      string  `json:"status"`
+	StartCredit Money  `json:"startcredit" sql:"type:decimal(14,2);"`
+	Token       string `json:"token" validate:"required"`
+	NotifyId    int64  `json:"notifyId" validate:"required"`
+	Status      string `json:"status"`
 }
 
 type LinenotifyGame struct {
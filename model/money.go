@@ -0,0 +1,205 @@
+package model
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount in satang, the DECIMAL(14,2) columns hold it exactly.
+// Adding, subtracting and comparing Money values is exact. Amounts with more
+// than 2 decimals, from JSON, the database or a float64, are rounded half
+// away from zero to the satang.
+type Money int64
+
+// MaxMoney is the largest amount a DECIMAL(14,2) column holds. The validate
+// tags of request amounts compare satang, max=99999999999999 is MaxMoney.
+const MaxMoney Money = 99999999999999
+
+var ErrInvalidMoney = errors.New("invalid money amount")
+
+// NewMoney rounds a float64 amount, from an API that sends floats, to the
+// satang.
+func NewMoney(amount float64) Money {
+	return Money(math.Round(amount * 100))
+}
+
+// moneyFromFloat is NewMoney for a float64 read from outside, NaN, Inf and
+// amounts a DECIMAL(14,2) column cannot hold are refused.
+func moneyFromFloat(amount float64) (Money, error) {
+
+	if math.IsNaN(amount) || math.IsInf(amount, 0) || math.Abs(amount) > MaxMoney.Float64() {
+		return 0, ErrInvalidMoney
+	}
+	money := NewMoney(amount)
+	if money.Abs() > MaxMoney {
+		return 0, ErrInvalidMoney
+	}
+	return money, nil
+}
+
+// ParseMoney reads a decimal like "1234.56", "-0.5" or "1,234.565" without
+// going through float64. Amounts over MaxMoney are refused.
+func ParseMoney(text string) (Money, error) {
+
+	text = strings.ReplaceAll(strings.TrimSpace(text), ",", "")
+	if text == "" {
+		return 0, ErrInvalidMoney
+	}
+
+	negative := false
+	switch text[0] {
+	case '-':
+		negative = true
+		text = text[1:]
+	case '+':
+		text = text[1:]
+	}
+
+	whole, fraction, _ := strings.Cut(text, ".")
+	if whole == "" && fraction == "" {
+		return 0, ErrInvalidMoney
+	}
+	for _, part := range []string{whole, fraction} {
+		for _, c := range part {
+			if c < '0' || c > '9' {
+				return 0, ErrInvalidMoney
+			}
+		}
+	}
+	if len(whole) > 12 {
+		return 0, ErrInvalidMoney
+	}
+
+	var satang int64
+	if whole != "" {
+		value, err := strconv.ParseInt(whole, 10, 64)
+		if err != nil {
+			return 0, ErrInvalidMoney
+		}
+		satang = value * 100
+	}
+	fraction += "00"
+	satang += int64(fraction[0]-'0')*10 + int64(fraction[1]-'0')
+	if len(fraction) > 2 && fraction[2] >= '5' {
+		satang++
+	}
+
+	if Money(satang) > MaxMoney {
+		return 0, ErrInvalidMoney
+	}
+	if negative {
+		satang = -satang
+	}
+	return Money(satang), nil
+}
+
+func (m Money) Float64() float64 {
+	return float64(m) / 100
+}
+
+func (m Money) Abs() Money {
+	if m < 0 {
+		return -m
+	}
+	return m
+}
+
+// String is the amount with 2 decimals, "-1234.50".
+func (m Money) String() string {
+
+	sign := ""
+	satang := int64(m)
+	if satang < 0 {
+		sign = "-"
+		satang = -satang
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, satang/100, satang%100)
+}
+
+// MarshalJSON writes a JSON number with 2 decimals.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON takes a number or a numeric string, the bank account limits
+// were sent as strings.
+func (m *Money) UnmarshalJSON(data []byte) error {
+
+	text := strings.TrimSpace(string(data))
+	if text == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(text); err == nil {
+		if strings.TrimSpace(unquoted) == "" {
+			*m = 0
+			return nil
+		}
+		text = unquoted
+	}
+
+	value, err := ParseMoney(text)
+	if err != nil {
+		// exponent forms like 1e3
+		float, floatErr := strconv.ParseFloat(text, 64)
+		if floatErr != nil {
+			return ErrInvalidMoney
+		}
+		if value, err = moneyFromFloat(float); err != nil {
+			return err
+		}
+	}
+	*m = value
+	return nil
+}
+
+// Scan reads a DECIMAL column, the mysql driver gives it as text.
+func (m *Money) Scan(src interface{}) error {
+
+	switch value := src.(type) {
+	case nil:
+		*m = 0
+	case []byte:
+		return m.scanText(string(value))
+	case string:
+		return m.scanText(value)
+	case int64:
+		if value > int64(MaxMoney/100) || value < -int64(MaxMoney/100) {
+			return fmt.Errorf("cannot scan %d into Money", value)
+		}
+		*m = Money(value * 100)
+	case float64:
+		money, err := moneyFromFloat(value)
+		if err != nil {
+			return fmt.Errorf("cannot scan %v into Money", value)
+		}
+		*m = money
+	default:
+		return fmt.Errorf("cannot scan %T into Money", src)
+	}
+	return nil
+}
+
+func (m *Money) scanText(text string) error {
+
+	if text == "" {
+		*m = 0
+		return nil
+	}
+	value, err := ParseMoney(text)
+	if err != nil {
+		return fmt.Errorf("cannot scan %q into Money", text)
+	}
+	*m = value
+	return nil
+}
+
+// Value sends the exact decimal text, the column keeps it without rounding
+// through a double. In arithmetic wrap the placeholder in
+// CAST(? AS DECIMAL(14,2)).
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
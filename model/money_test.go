@@ -0,0 +1,281 @@
+package model
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+)
+
+func TestParseMoney(t *testing.T) {
+
+	tests := []struct {
+		text    string
+		want    Money
+		wantErr bool
+	}{
+		{text: "0", want: 0},
+		{text: "1234.56", want: 123456},
+		{text: "1,234.56", want: 123456},
+		{text: " 12.5 ", want: 1250},
+		{text: "+7", want: 700},
+		{text: ".5", want: 50},
+		{text: "3.", want: 300},
+		{text: "-0.5", want: -50},
+		{text: "-1234.50", want: -123450},
+		// half away from zero on the third decimal
+		{text: "0.004", want: 0},
+		{text: "0.005", want: 1},
+		{text: "1.994", want: 199},
+		{text: "1.995", want: 200},
+		{text: "-0.005", want: -1},
+		{text: "-1.995", want: -200},
+		{text: "1.23456789", want: 123},
+		{text: "999999999999.99", want: MaxMoney},
+		{text: "-999999999999.99", want: -MaxMoney},
+		{text: "999999999999.994", want: MaxMoney},
+		{text: "999999999999.995", wantErr: true},
+		{text: "1000000000000", wantErr: true},
+		{text: "9999999999999999.99", wantErr: true},
+		{text: "", wantErr: true},
+		{text: "-", wantErr: true},
+		{text: ".", wantErr: true},
+		{text: "abc", wantErr: true},
+		{text: "1.2.3", wantErr: true},
+		{text: "1e3", wantErr: true},
+		{text: "--1", wantErr: true},
+		{text: "12345678901234567", wantErr: true},
+	}
+
+	for _, test := range tests {
+		got, err := ParseMoney(test.text)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("ParseMoney(%q) = %d, want an error", test.text, got)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("ParseMoney(%q) = %d, %v, want %d", test.text, got, err, test.want)
+		}
+	}
+}
+
+func TestNewMoney(t *testing.T) {
+
+	tests := []struct {
+		amount float64
+		want   Money
+	}{
+		{0, 0},
+		{100, 10000},
+		{0.1 + 0.2, 30},
+		{12.344, 1234},
+		{12.346, 1235},
+		{-12.346, -1235},
+		{-0.5, -50},
+	}
+
+	for _, test := range tests {
+		if got := NewMoney(test.amount); got != test.want {
+			t.Errorf("NewMoney(%v) = %d, want %d", test.amount, got, test.want)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+
+	tests := []struct {
+		money Money
+		want  string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{-5, "-0.05"},
+		{123450, "1234.50"},
+		{-123450, "-1234.50"},
+	}
+
+	for _, test := range tests {
+		if got := test.money.String(); got != test.want {
+			t.Errorf("Money(%d).String() = %q, want %q", int64(test.money), got, test.want)
+		}
+	}
+}
+
+func TestMoneyUnmarshalJSON(t *testing.T) {
+
+	tests := []struct {
+		data    string
+		want    Money
+		wantErr bool
+	}{
+		{data: `100`, want: 10000},
+		{data: `100.5`, want: 10050},
+		{data: `-100.555`, want: -10056},
+		{data: `0.015`, want: 2},
+		{data: `1e3`, want: 100000},
+		{data: `"100.50"`, want: 10050},
+		{data: `"1,000"`, want: 100000},
+		{data: `"-0.5"`, want: -50},
+		{data: `" 12 "`, want: 1200},
+		{data: `""`, want: 0},
+		{data: `"abc"`, wantErr: true},
+		{data: `true`, wantErr: true},
+		{data: `{}`, wantErr: true},
+		{data: `"NaN"`, wantErr: true},
+		{data: `"Inf"`, wantErr: true},
+		{data: `"-Infinity"`, wantErr: true},
+		{data: `1e300`, wantErr: true},
+		{data: `-1e13`, wantErr: true},
+		{data: `999999999999.99`, want: MaxMoney},
+		{data: `1000000000000`, wantErr: true},
+		{data: `9.9999999999999e11`, want: MaxMoney},
+	}
+
+	for _, test := range tests {
+		money := Money(99)
+		err := json.Unmarshal([]byte(test.data), &money)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("unmarshal %s = %d, want an error", test.data, money)
+			}
+			continue
+		}
+		if err != nil || money != test.want {
+			t.Errorf("unmarshal %s = %d, %v, want %d", test.data, money, err, test.want)
+		}
+	}
+
+	// null leaves the value as it was
+	money := Money(99)
+	if err := json.Unmarshal([]byte(`null`), &money); err != nil || money != 99 {
+		t.Errorf("unmarshal null = %d, %v, want 99 untouched", money, err)
+	}
+}
+
+func TestMoneyJSONRoundTrip(t *testing.T) {
+
+	type body struct {
+		Amount Money  `json:"amount"`
+		Limit  *Money `json:"limit"`
+	}
+
+	limit := Money(-50)
+	data, err := json.Marshal(body{Amount: 123450, Limit: &limit})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"amount":1234.50,"limit":-0.50}` {
+		t.Fatalf("marshal = %s", data)
+	}
+
+	var got body
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Amount != 123450 || got.Limit == nil || *got.Limit != -50 {
+		t.Fatalf("round trip = %d %v", got.Amount, got.Limit)
+	}
+}
+
+func TestMoneyScan(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		src     interface{}
+		want    Money
+		wantErr bool
+	}{
+		{name: "null", src: nil, want: 0},
+		{name: "decimal bytes", src: []byte("1234.56"), want: 123456},
+		{name: "negative decimal", src: []byte("-0.50"), want: -50},
+		{name: "decimal string", src: "0.00", want: 0},
+		{name: "empty", src: []byte(""), want: 0},
+		{name: "int64", src: int64(15), want: 1500},
+		{name: "float64", src: float64(1.005), want: 100},
+		{name: "float64 rounded", src: float64(12.345678), want: 1235},
+		{name: "text", src: []byte("abc"), wantErr: true},
+		{name: "out of range", src: []byte("1000000000000.00"), wantErr: true},
+		{name: "NaN", src: math.NaN(), wantErr: true},
+		{name: "Inf", src: math.Inf(-1), wantErr: true},
+		{name: "float64 out of range", src: float64(1e13), wantErr: true},
+		{name: "int64 out of range", src: int64(1e13), wantErr: true},
+		{name: "bool", src: true, wantErr: true},
+	}
+
+	for _, test := range tests {
+		money := Money(99)
+		err := money.Scan(test.src)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("%s: Scan = %d, want an error", test.name, money)
+			}
+			continue
+		}
+		if err != nil || money != test.want {
+			t.Errorf("%s: Scan = %d, %v, want %d", test.name, money, err, test.want)
+		}
+	}
+}
+
+// TestMoneyDecimalRoundTrip writes Money the way the driver sends it and reads
+// it back the way a DECIMAL(14,2) column answers.
+func TestMoneyDecimalRoundTrip(t *testing.T) {
+
+	for _, money := range []Money{0, 1, -1, 99, -99, 100, 123456, -123456, 99999999999999, -99999999999999} {
+
+		value, err := money.Value()
+		if err != nil {
+			t.Fatalf("Value(%d): %s", money, err.Error())
+		}
+		text, ok := value.(string)
+		if !ok {
+			t.Fatalf("Value(%d) is %T, want the decimal text", money, value)
+		}
+
+		var got Money
+		if err := got.Scan([]byte(text)); err != nil || got != money {
+			t.Errorf("Scan(Value(%d)) = %d, %v", money, got, err)
+		}
+	}
+}
+
+// TestMoneyRequestAmounts checks the validate tags of the request amounts
+// take a positive amount up to MaxMoney.
+func TestMoneyRequestAmounts(t *testing.T) {
+
+	tests := []struct {
+		amount  Money
+		wantErr bool
+	}{
+		{amount: 1},
+		{amount: NewMoney(1500.25)},
+		{amount: MaxMoney},
+		{amount: 0, wantErr: true},
+		{amount: -1, wantErr: true},
+		{amount: MaxMoney + 1, wantErr: true},
+	}
+
+	validate := validator.New()
+	for _, test := range tests {
+		bodies := []interface{}{
+			BankTransactionCreateBody{MemberCode: "m1", TransferType: "withdraw", CreditAmount: test.amount},
+			BonusTransactionCreateBody{MemberCode: "m1", BonusAmount: test.amount, TransferAt: time.Now()},
+			BankAccountTransactionBody{AccountId: 1, TransferType: "deposit", Amount: test.amount, TransferAt: time.Now()},
+			BankAccountTransferBody{FromAccountId: 1, ToAccountId: 2, Amount: test.amount, TransferAt: time.Now()},
+			BankConfirmCreditWithdrawRequest{CreditAmount: &test.amount},
+		}
+		for _, body := range bodies {
+			err := validate.Struct(body)
+			if test.wantErr && err == nil {
+				t.Errorf("%T with %s: got no error", body, test.amount)
+			}
+			if !test.wantErr && err != nil {
+				t.Errorf("%T with %s: %s", body, test.amount, err.Error())
+			}
+		}
+	}
+}
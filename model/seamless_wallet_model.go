@@ -0,0 +1,73 @@
+package model
+
+import "time"
+
+// SeamlessRequest is the body every provider callback sends. Sign is
+// sha256(lower(Provider+Username+TransactionId) + Timestamp + lower(key)),
+// balance calls sign Provider+Username.
+type SeamlessRequest struct {
+	Provider         string `json:"provider" validate:"required"`
+	Username         string `json:"username" validate:"required"`
+	TransactionId    string `json:"transactionId"`
+	RoundId          string `json:"roundId"`
+	RefTransactionId string `json:"refTransactionId"`
+	GameCode         string `json:"gameCode"`
+	Amount           Money  `json:"amount" validate:"gte=0"`
+	Timestamp        int64  `json:"timestamp" validate:"required"`
+	Sign             string `json:"sign" validate:"required"`
+}
+
+type SeamlessResponse struct {
+	Code          int    `json:"code"`
+	Message       string `json:"message"`
+	Username      string `json:"username,omitempty"`
+	Balance       Money  `json:"balance"`
+	TransactionId string `json:"transactionId,omitempty"`
+}
+
+type SeamlessTransaction struct {
+	Id               int64      `json:"id" gorm:"primaryKey"`
+	Provider         string     `json:"provider"`
+	TransactionId    string     `json:"transactionId"`
+	RoundId          string     `json:"roundId"`
+	RefTransactionId *string    `json:"refTransactionId"`
+	UserId           int64      `json:"userId"`
+	GameCode         string     `json:"gameCode"`
+	Action           string     `json:"action"`
+	Amount           Money      `json:"amount"`
+	BeforeBalance    Money      `json:"beforeBalance"`
+	AfterBalance     Money      `json:"afterBalance"`
+	Status           string     `json:"status"`
+	StatementId      *int64     `json:"statementId"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	UpdatedAt        *time.Time `json:"updatedAt"`
+}
+
+// SeamlessTransactionBody is one wallet move. Amount is signed, a bet is
+// negative. RefTransactionId names the transaction a refund or rollback undoes.
+type SeamlessTransactionBody struct {
+	Provider         string
+	TransactionId    string
+	RoundId          string
+	RefTransactionId string
+	Username         string
+	GameCode         string
+	Action           string
+	Amount           Money
+	StatementTypeId  int64
+	Info             string
+}
+
+type SeamlessTransactionResult struct {
+	Username string
+	Balance  Money
+	// Replayed is true when the TransactionId was applied before
+	Replayed bool
+}
+
+type SeamlessBalance struct {
+	Id             int64  `json:"id"`
+	Username       string `json:"username"`
+	Credit         Money  `json:"credit"`
+	CreditReserved Money  `json:"creditReserved"`
+}
@@ -27,7 +27,7 @@ type User struct {
 	Contact         string         `json:"contact" gorm:"default:NULL"`
 	Note            string         `json:"note" gorm:"default:NULL"`
 	Course          string         `json:"course" gorm:"default:NULL"`
-	Credit          float64        `json:"credit"`
+	Credit          Money          `json:"credit"`
 	TurnoverLimit   int            `json:"turnoverLimit"`
 	Ip              string         `json:"ip" gorm:"default:NULL"`
 	IsResetPassword bool           `json:"is_reset_password"`
@@ -114,7 +114,7 @@ type UserList struct {
 	Bankname     string     `json:"bankname"`
 	BankAccount  string     `json:"bankAccount"`
 	Channel      string     `json:"channel"`
-	Credit       float64    `json:"credit"`
+	Credit       Money      `json:"credit"`
 	Ip           string     `json:"ip"`
 	IpRegistered string     `json:"ipRegistered"`
 	CreatedAt    *time.Time `json:"createdAt"`
@@ -123,19 +123,22 @@ type UserList struct {
 }
 
 type UserDetail struct {
-	Id          int64  `json:"id"`
-	Partner     string `json:"partner"`
-	MemberCode  string `json:"memberCode"`
-	Phone       string `json:"phone"`
-	Promotion   string `json:"promotion"`
-	Fullname    string `json:"fullname"`
-	Bankname    string `json:"bankname"`
-	BankAccount string `json:"bankAccount"`
-	Channel     string `json:"channel"`
-	TrueWallet  string `json:"trueWallet"`
-	Contact     string `json:"contact"`
-	Note        string `json:"note"`
-	Course      string `json:"course"`
+	Id            int64   `json:"id"`
+	Partner       string  `json:"partner"`
+	MemberCode    string  `json:"memberCode"`
+	Phone         string  `json:"phone"`
+	Promotion     string  `json:"promotion"`
+	Fullname      string  `json:"fullname"`
+	Bankname      string  `json:"bankname"`
+	BankAccount   string  `json:"bankAccount"`
+	Channel       string  `json:"channel"`
+	TrueWallet    string  `json:"trueWallet"`
+	Contact       string  `json:"contact"`
+	Note          string  `json:"note"`
+	Course        string  `json:"course"`
+	Credit        Money   `json:"credit"`
+	TurnoverLimit int     `json:"turnoverLimit"`
+	Turnover      float64 `json:"turnover"`
 }
 
 type UserUpdatePassword struct {
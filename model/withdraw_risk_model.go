@@ -0,0 +1,52 @@
+package model
+
+import "time"
+
+// Withdraw risk rule keys. Amount is the limit of the max rules, Hours the
+// window of recent_bonus and new_bank_account.
+const (
+	WithdrawRiskMaxPerTransaction = "max_per_transaction"
+	WithdrawRiskMaxPerDay         = "max_per_day"
+	WithdrawRiskFirstWithdraw     = "first_withdraw"
+	WithdrawRiskRecentBonus       = "recent_bonus"
+	WithdrawRiskScammer           = "scammer"
+	WithdrawRiskNewBankAccount    = "new_bank_account"
+)
+
+type WithdrawRiskRule struct {
+	Id                int64      `json:"id" gorm:"primaryKey"`
+	RuleKey           string     `json:"ruleKey"`
+	Name              string     `json:"name"`
+	IsEnabled         bool       `json:"isEnabled"`
+	Amount            Money      `json:"amount"`
+	Hours             int        `json:"hours"`
+	UpdatedByUserId   *int64     `json:"updatedByUserId"`
+	UpdatedByUsername *string    `json:"updatedByUsername"`
+	CreatedAt         time.Time  `json:"createdAt"`
+	UpdatedAt         *time.Time `json:"updatedAt"`
+}
+
+type WithdrawRiskRuleUpdateRequest struct {
+	IsEnabled         *bool  `json:"isEnabled"`
+	Amount            *Money `json:"amount"`
+	Hours             *int   `json:"hours" validate:"omitempty,min=0"`
+	UpdatedByUserId   int64  `json:"-"`
+	UpdatedByUsername string `json:"-"`
+}
+
+type WithdrawRiskRuleUpdateBody struct {
+	IsEnabled         *bool
+	Amount            *Money
+	Hours             *int
+	UpdatedByUserId   int64
+	UpdatedByUsername string
+}
+
+// WithdrawRiskCheck is a withdraw about to go out automatically,
+// TransactionId is 0 before the transaction is created.
+type WithdrawRiskCheck struct {
+	TransactionId int64
+	UserId        int64
+	CreditAmount  Money
+	FromAccount   BankAccount
+}
@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"cybergame-api/model"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+// ErrAgentCallExists is returned when another request journaled the same
+// TransactionId first.
+var ErrAgentCallExists = errors.New("Agent call already exists")
+
+func NewAgentCallRepository(db *gorm.DB) AgentCallRepository {
+	return &repo{db}
+}
+
+type AgentCallRepository interface {
+	GetAgentCallJournals(req model.AgentCallJournalListRequest) (*model.SuccessWithPagination, error)
+	GetAgentCallJournalById(id int64) (*model.AgentCallJournal, error)
+	GetAgentCallJournalByTransactionId(transactionId string) (*model.AgentCallJournal, error)
+	GetDueAgentCallJournals(now time.Time, limit int) ([]model.AgentCallJournal, error)
+	CreateAgentCallJournal(body model.AgentCallJournal) (*int64, error)
+	ClaimAgentCallJournal(id int64, now time.Time, leaseUntil time.Time) (bool, error)
+	UpdateAgentCallJournal(id int64, body model.AgentCallResultBody) error
+	GetAgentCallBankTransaction(id int64) (*model.BankTransaction, error)
+}
+
+func (r repo) GetAgentCallJournals(req model.AgentCallJournalListRequest) (*model.SuccessWithPagination, error) {
+
+	var list []model.AgentCallJournal
+	var total int64
+
+	query := r.db.Table("Agent_call_journals")
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+	if req.Action != "" {
+		query = query.Where("action = ?", req.Action)
+	}
+	if req.Search != "" {
+		search_like := fmt.Sprintf("%%%s%%", req.Search)
+		query = query.Where(r.db.Where("transaction_id LIKE ?", search_like).Or("player_name LIKE ?", search_like))
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	if total > 0 {
+		if err := query.
+			Order("id DESC").
+			Limit(req.Limit).
+			Offset(req.Limit * req.Page).
+			Find(&list).
+			Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var result model.SuccessWithPagination
+	result.List = list
+	result.Total = total
+	return &result, nil
+}
+
+func (r repo) GetAgentCallJournalById(id int64) (*model.AgentCallJournal, error) {
+
+	var journal model.AgentCallJournal
+
+	if err := r.db.Table("Agent_call_journals").
+		Where("id = ?", id).
+		First(&journal).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return &journal, nil
+}
+
+func (r repo) GetAgentCallJournalByTransactionId(transactionId string) (*model.AgentCallJournal, error) {
+
+	var journal model.AgentCallJournal
+
+	if err := r.db.Table("Agent_call_journals").
+		Where("transaction_id = ?", transactionId).
+		First(&journal).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return &journal, nil
+}
+
+// GetDueAgentCallJournals lists the calls waiting for a retry and the running
+// ones whose lease ran out, a process stopped while calling the agent.
+func (r repo) GetDueAgentCallJournals(now time.Time, limit int) ([]model.AgentCallJournal, error) {
+
+	var list []model.AgentCallJournal
+
+	if err := r.db.Table("Agent_call_journals").
+		Where("status IN ?", []string{"retry", "running"}).
+		Where("next_retry_at <= ?", now).
+		Order("next_retry_at ASC").
+		Limit(limit).
+		Find(&list).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (r repo) CreateAgentCallJournal(body model.AgentCallJournal) (*int64, error) {
+
+	if err := r.db.Table("Agent_call_journals").
+		Create(&body).
+		Error; err != nil {
+
+		var dup *mysql.MySQLError
+		if errors.As(err, &dup) && dup.Number == 1062 {
+			return nil, ErrAgentCallExists
+		}
+
+		return nil, err
+	}
+
+	return &body.Id, nil
+}
+
+// ClaimAgentCallJournal marks the call running for one more attempt. Only one
+// caller wins, the others get false. A withdraw is only claimed while its bank
+// transaction can still be debited at the agent, the subquery locks that row
+// so a cancel committed before the claim wins and one after it waits.
+func (r repo) ClaimAgentCallJournal(id int64, now time.Time, leaseUntil time.Time) (bool, error) {
+
+	var transferTypes []string
+	for transferType := range model.BankTransactionTransitions {
+		transferTypes = append(transferTypes, transferType)
+	}
+	sort.Strings(transferTypes)
+	refOpen := r.db.Where("1 = 0")
+	for _, transferType := range transferTypes {
+		if statuses := model.AgentDebitStatuses(transferType); len(statuses) > 0 {
+			refOpen = refOpen.Or("Bank_transactions.transfer_type = ? AND Bank_transactions.status IN ?", transferType, statuses)
+		}
+	}
+	refs := r.db.Table("Bank_transactions").
+		Select("1").
+		Where("Bank_transactions.id = Agent_call_journals.ref_id").
+		Where(refOpen)
+
+	query := r.db.Table("Agent_call_journals").
+		Where("id = ?", id).
+		Where(r.db.Where("status IN ?", []string{"retry", "failed"}).Or("status = ? AND next_retry_at <= ?", "running", now)).
+		Where(r.db.Where("action <> ?", "withdraw").Or("EXISTS (?)", refs)).
+		Updates(map[string]interface{}{
+			"status":        "running",
+			"attempt_count": gorm.Expr("attempt_count + ?", 1),
+			"next_retry_at": leaseUntil,
+		})
+	if err := query.Error; err != nil {
+		return false, err
+	}
+
+	return query.RowsAffected == 1, nil
+}
+
+func (r repo) UpdateAgentCallJournal(id int64, body model.AgentCallResultBody) error {
+
+	if err := r.db.Table("Agent_call_journals").
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":        body.Status,
+			"sign":          body.Sign,
+			"request_body":  body.RequestBody,
+			"response_body": body.ResponseBody,
+			"last_error":    body.LastError,
+			"next_retry_at": body.NextRetryAt,
+			"completed_at":  body.CompletedAt,
+		}).
+		Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetAgentCallBankTransaction reads the type and status of the bank
+// transaction a call was made for.
+func (r repo) GetAgentCallBankTransaction(id int64) (*model.BankTransaction, error) {
+
+	var transaction model.BankTransaction
+
+	if err := r.db.Table("Bank_transactions").
+		Select("id, user_id, transfer_type, status").
+		Where("id = ?", id).
+		First(&transaction).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return &transaction, nil
+}
@@ -1,12 +1,9 @@
 package repository
 
 import (
-	"cybergame-api/helper"
+	"context"
+	"cybergame-api/agent"
 	"cybergame-api/model"
-	"errors"
-	"fmt"
-	"log"
-	"os"
 
 	"gorm.io/gorm"
 )
@@ -15,77 +12,47 @@ func NewAgentConnectRepository(db *gorm.DB) AgentConnectRepository {
 	return &repo{db}
 }
 
+// AgentConnectRepository calls the site agent wallet, a refused call comes
+// back as an *agent.Error.
 type AgentConnectRepository interface {
-	Register(data model.AGCRegister) error
-	Login(data model.AGCLogin) error
-	ChangePassword(data model.AGCChangePassword) error
-	Deposit(data model.AGCDeposit) error
-	Withdraw(data model.AGCWithdraw) error
+	AgentWallet() agent.WalletProvider
+	Register(ctx context.Context, data model.AGCRegister) error
+	Login(ctx context.Context, data model.AGCLogin) (*model.AGCLoginResponse, error)
+	ChangePassword(ctx context.Context, data model.AGCChangePassword) error
+	Deposit(ctx context.Context, data model.AGCDeposit) (*model.AGCTransferResponse, error)
+	Withdraw(ctx context.Context, data model.AGCWithdraw) (*model.AGCTransferResponse, error)
+	Balance(ctx context.Context, data model.AGCBalance) (*model.AGCBalanceResponse, error)
+	BetHistory(ctx context.Context, data model.AGCBetHistory) (*model.AGCBetHistoryResponse, error)
 }
 
-func (r repo) Register(data model.AGCRegister) error {
-
-	url := fmt.Sprintf("%s/credit-auth/xregister", os.Getenv("AGENT_API"))
-	result, err := helper.Post(url, data)
-	if err != nil {
-		return err
-	}
-
-	if result.(map[string]interface{})["Success"] == true {
-		return nil
-	} else {
-		return errors.New(result.(map[string]interface{})["Error"].(map[string]interface{})["Message"].(string))
-	}
+func (r repo) AgentWallet() agent.WalletProvider {
+	return agent.Default()
 }
 
-func (r repo) Login(data model.AGCLogin) error {
-
-	url := fmt.Sprintf("%s/credit-auth/login", os.Getenv("AGENT_API"))
-	result, err := helper.Post(url, data)
-	if err != nil {
-		return err
-	}
-
-	log.Println(result)
-
-	return nil
+func (r repo) Register(ctx context.Context, data model.AGCRegister) error {
+	return agent.Default().Register(ctx, data)
 }
 
-func (r repo) ChangePassword(data model.AGCChangePassword) error {
-
-	url := fmt.Sprintf("%s/credit-auth/changepassword", os.Getenv("AGENT_API"))
-	result, err := helper.Post(url, data)
-	if err != nil {
-		return err
-	}
-
-	log.Println(result, result.(map[string]interface{})["Success"])
-
-	return nil
+func (r repo) Login(ctx context.Context, data model.AGCLogin) (*model.AGCLoginResponse, error) {
+	return agent.Default().Login(ctx, data)
 }
 
-func (r repo) Deposit(data model.AGCDeposit) error {
-
-	url := fmt.Sprintf("%s/credit-transfer/deposit", os.Getenv("AGENT_API"))
-	result, err := helper.Post(url, data)
-	if err != nil {
-		return err
-	}
-
-	log.Println(result, result.(map[string]interface{})["Success"])
-
-	return nil
+func (r repo) ChangePassword(ctx context.Context, data model.AGCChangePassword) error {
+	return agent.Default().ChangePassword(ctx, data)
 }
 
-func (r repo) Withdraw(data model.AGCWithdraw) error {
+func (r repo) Deposit(ctx context.Context, data model.AGCDeposit) (*model.AGCTransferResponse, error) {
+	return agent.Default().Deposit(ctx, data)
+}
 
-	url := fmt.Sprintf("%s/credit-transfer/withdraw", os.Getenv("AGENT_API"))
-	result, err := helper.Post(url, data)
-	if err != nil {
-		return err
-	}
+func (r repo) Withdraw(ctx context.Context, data model.AGCWithdraw) (*model.AGCTransferResponse, error) {
+	return agent.Default().Withdraw(ctx, data)
+}
 
-	log.Println(result, result.(map[string]interface{})["Success"])
+func (r repo) Balance(ctx context.Context, data model.AGCBalance) (*model.AGCBalanceResponse, error) {
+	return agent.Default().Balance(ctx, data)
+}
 
-	return nil
+func (r repo) BetHistory(ctx context.Context, data model.AGCBetHistory) (*model.AGCBetHistoryResponse, error) {
+	return agent.Default().BetHistory(ctx, data)
 }
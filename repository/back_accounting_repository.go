@@ -31,6 +31,7 @@ type AccountingRepository interface {
 	GetActiveExternalAccount() (*model.BankAccount, error)
 	GetDepositAccountById(id int64) (*model.BankAccount, error)
 	GetWithdrawAccountById(id int64) (*model.BankAccount, error)
+	GetAutoWithdrawAccounts() ([]model.BankAccount, error)
 	GetBankAccounts(data model.BankAccountListRequest) (*model.SuccessWithPagination, error)
 	GetBankAccountPriorities() (*model.SuccessWithPagination, error)
 	GetBotBankAccounts(data model.BankAccountListRequest) (*model.SuccessWithPagination, error)
@@ -77,9 +78,9 @@ type AccountingRepository interface {
 	GetBankTransactionById(id int64) (*model.BankTransaction, error)
 	CreateTransactionAction(data model.CreateBankTransactionActionBody) (*int64, error)
 	RollbackTransactionAction(id int64) error
-	ConfirmPendingDepositTransaction(id int64, data model.BankDepositTransactionConfirmBody) error
-	ConfirmPendingCreditDepositTransaction(id int64, data model.BankDepositTransactionConfirmBody) error
-	ConfirmPendingWithdrawTransaction(id int64, data model.BankWithdrawTransactionConfirmBody) error
+	ConfirmPendingDepositTransaction(id int64, fromStatus []string, data model.BankDepositTransactionConfirmBody) error
+	ConfirmPendingCreditDepositTransaction(id int64, fromStatus []string, data model.BankDepositTransactionConfirmBody) error
+	ConfirmPendingWithdrawTransaction(id int64, fromStatus []string, data model.BankWithdrawTransactionConfirmBody) error
 	CreateStatementAction(data model.CreateBankStatementActionBody) error
 	UpdateBankStatement(id int64, data model.BankStatementUpdateBody) error
 	// MatchStatementOwner(id int64, data model.BankStatementUpdateBody) error
@@ -357,6 +358,33 @@ func (r repo) GetWithdrawAccountById(id int64) (*model.BankAccount, error) {
 	return &accounting, nil
 }
 
+// GetAutoWithdrawAccounts lists the withdraw accounts that are active and
+// connected to the bank bot, the main withdraw account first.
+func (r repo) GetAutoWithdrawAccounts() ([]model.BankAccount, error) {
+
+	var list []model.BankAccount
+	selectedFields := "accounts.id, accounts.bank_id, accounts.account_type_id, accounts.account_name, accounts.account_number, accounts.account_balance, accounts.account_priority_id, accounts.account_status, accounts.device_uid, accounts.pin_code, accounts.connection_status"
+	selectedFields += ", accounts.auto_credit_flag, accounts.is_main_withdraw, accounts.auto_withdraw_flag, accounts.auto_withdraw_credit_flag, accounts.auto_withdraw_confirm_flag, accounts.auto_withdraw_max_amount, accounts.auto_transfer_max_amount, accounts.qr_wallet_status"
+	selectedFields += ", accounts.last_conn_update_at, accounts.created_at, accounts.updated_at"
+	selectedFields += ", banks.name as bank_name, banks.code as bank_code, banks.icon_url as bank_icon_url, banks.type_flag"
+	selectedFields += ", account_types.name as account_type_name, account_types.limit_flag"
+	if err := r.db.Table("Bank_accounts as accounts").
+		Select(selectedFields).
+		Joins("LEFT JOIN Banks AS banks ON banks.id = accounts.bank_id").
+		Joins("LEFT JOIN Bank_account_types AS account_types ON account_types.id = accounts.account_type_id").
+		Where("account_types.allow_withdraw = 1").
+		Where("accounts.account_status = ?", "active").
+		Where("accounts.connection_status = ?", "active").
+		Where("accounts.device_uid != '' AND accounts.pin_code != ''").
+		Where("accounts.deleted_at IS NULL").
+		Order("accounts.is_main_withdraw DESC, accounts.id ASC").
+		Scan(&list).
+		Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
 func (r repo) GetBankAccountByAccountNumber(accountNumber string) (*model.BankAccount, error) {
 
 	var accounting model.BankAccount
@@ -13,9 +13,27 @@ import (
 	"strings"
 	"time"
 
+	"github.com/go-sql-driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrBankTransactionStatusChanged is a status update that found the
+// transaction already moved on by another request.
+var ErrBankTransactionStatusChanged = errors.New("Bank transaction status changed")
+
+// ErrMemberCreditSeamless is a credit sync of a member who plays on the
+// seamless wallet, whose local credit is the real balance.
+var ErrMemberCreditSeamless = errors.New("Member credit is held by the seamless wallet")
+
+// ErrMemberCreditInProgress is a credit sync of a member with a deposit or
+// withdraw that reached only one side of the agent wallet and the credit.
+var ErrMemberCreditInProgress = errors.New("Member credit transfer in progress")
+
+// ErrMemberCreditUnderReserved is an agent balance under the credit held for
+// pending withdraws.
+var ErrMemberCreditUnderReserved = errors.New("Agent balance under reserved credit")
+
 func NewBankingRepository(db *gorm.DB) BankingRepository {
 	return &repo{db}
 }
@@ -46,15 +64,30 @@ type BankingRepository interface {
 	CreateTransactionAction(data model.CreateBankTransactionActionBody) (*int64, error)
 	RollbackTransactionAction(actionId int64) error
 	CreateStatementAction(data model.CreateBankStatementActionBody) error
-	ConfirmPendingDepositTransaction(id int64, data model.BankDepositTransactionConfirmBody) error
-	ConfirmPendingCreditDepositTransaction(id int64, data model.BankDepositTransactionConfirmBody) error
-	CheckMemeberHasEnoughtCredit(memberId int64, creditAmount float64) error
-	ConfirmPendingWithdrawTransaction(id int64, data model.BankWithdrawTransactionConfirmBody) error
-	ConfirmPendingWithdrawTransfer(id int64, data model.BankWithdrawTransactionConfirmBody) error
-	CancelPendingTransaction(id int64, data model.BankTransactionCancelBody) error
+	ConfirmPendingDepositTransaction(id int64, fromStatus []string, data model.BankDepositTransactionConfirmBody) error
+	ConfirmPendingCreditDepositTransaction(id int64, fromStatus []string, data model.BankDepositTransactionConfirmBody) error
+	CheckMemeberHasEnoughtCredit(memberId int64, transactionId int64, creditAmount model.Money) error
+	ConfirmPendingWithdrawTransaction(id int64, fromStatus []string, data model.BankWithdrawTransactionConfirmBody) error
+	ConfirmPendingWithdrawTransfer(id int64, fromStatus []string, data model.BankWithdrawTransactionConfirmBody) error
+	CancelPendingTransaction(id int64, fromStatus []string, data model.BankTransactionCancelBody) error
+	UpdateBankTransactionStatus(id int64, fromStatus []string, status string) error
+	GetWithdrawAgentDebit(agentTransactionId string) (*model.AgentCallJournal, error)
 	GetFinishedTransactions(req model.FinishedTransactionListRequest) (*model.SuccessWithPagination, error)
-	RemoveFinishedTransaction(id int64, data model.BankTransactionRemoveBody) error
+	RemoveFinishedTransaction(id int64, fromStatus []string, data model.BankTransactionRemoveBody) error
 	GetRemovedTransactions(req model.RemovedTransactionListRequest) (*model.SuccessWithPagination, error)
+	SetBankTransactionStatusDetail(id int64, statusDetail string) error
+
+	GetWithdrawRiskRules() ([]model.WithdrawRiskRule, error)
+	GetWithdrawRiskRuleById(id int64) (*model.WithdrawRiskRule, error)
+	UpdateWithdrawRiskRule(id int64, body model.WithdrawRiskRuleUpdateBody) error
+	GetMemberWithdrawTotalSince(userId int64, since time.Time, exceptId int64) (model.Money, error)
+	CountMemberFinishedWithdraws(userId int64) (int64, error)
+	GetMemberLastBonusAt(userId int64) (*time.Time, error)
+	IsMemberScammer(bankAccount string, phone string) (bool, error)
+	GetMemberBankAccountSince(userId int64) (*time.Time, error)
+	ReserveMemberCredit(userId int64, transactionId int64, amount model.Money) error
+	ReleaseMemberCredit(transactionId int64) error
+	CaptureMemberCredit(transactionId int64) error
 
 	GetMemberById(id int64) (*model.Member, error)
 	GetMemberByCode(code string) (*model.Member, error)
@@ -64,6 +97,10 @@ type BankingRepository interface {
 	GetMemberTransactionSummary(req model.MemberTransactionListRequest) (*model.MemberTransactionSummary, error)
 	IncreaseMemberCredit(body model.MemberStatementCreateBody) error
 	DecreaseMemberCredit(body model.MemberStatementCreateBody) error
+	GetMembersForCreditSync(afterId int64, limit int) ([]model.Member, error)
+	CheckMemberCreditSync(userId int64) error
+	GetMemberTurnover(userId int64) (*model.MemberTurnover, error)
+	AdjustMemberCredit(userId int64, statementTypeId int64, credit model.Money, agentBalance model.Money, info string) (bool, error)
 
 	TransferExternalAccount(body model.ExternalAccountTransferBody) error
 
@@ -588,30 +625,31 @@ func (r repo) CreateBankDepositTransaction(data model.BankTransactionCreateBody)
 
 func (r repo) CreateBankWithdrawTransactionWithCut(data model.BankTransactionCreateBody) (*int64, error) {
 
-	member, err := r.GetMemberById(data.UserId)
+	if data.CreditAmount <= 0 {
+		return nil, fmt.Errorf("INSUFFICIENT_CREDIT")
+	}
+	statementType, err := r.GetMemberStatementTypeByCode("withdraw")
 	if err != nil {
 		return nil, err
 	}
-
-	if data.CreditAmount > 0 && member.Credit >= data.CreditAmount {
-		if err := r.db.Transaction(func(tx *gorm.DB) error {
-			if err := tx.Table("Users").Where("id = ?", data.UserId).UpdateColumn("credit", gorm.Expr("credit - ?", data.CreditAmount)).Error; err != nil {
-				return err
-			}
-			if err := tx.Table("Bank_transactions").Create(&data).Error; err != nil {
-				return err
+	if err := r.db.Transaction(func(tx *gorm.DB) error {
+		body := model.MemberStatementCreateBody{
+			UserId:        data.UserId,
+			Info:          "ถอนเครดิต",
+			BankAccountId: data.FromAccountId,
+		}
+		if err := moveMemberCredit(tx, body, *statementType, -data.CreditAmount); err != nil {
+			if err.Error() == "NOT_ENOUGH_CREDIT" {
+				return fmt.Errorf("INSUFFICIENT_CREDIT")
 			}
-			// if creditBalance, err := r.GetMemberCredit(data.UserId); err != nil {
-			// 	return err
-			// } else if creditBalance <= 0 {
-			// 	return fmt.Errorf("ZERO_CREDIT")
-			// }
-			return nil
-		}); err != nil {
-			return nil, err
+			return err
 		}
-	} else {
-		return nil, fmt.Errorf("INSUFFICIENT_CREDIT")
+		if err := tx.Table("Bank_transactions").Create(&data).Error; err != nil {
+			return err
+		}
+		return nil
+	}); err != nil {
+		return nil, err
 	}
 	return &data.Id, nil
 }
@@ -833,16 +871,58 @@ func (r repo) GetPendingWithdrawTransactions(req model.PendingWithdrawTransactio
 	return &result, nil
 }
 
-func (r repo) CancelPendingTransaction(id int64, data model.BankTransactionCancelBody) error {
-	//.Where("status = ?", "pending")
-	if err := r.db.Table("Bank_transactions").Where("id = ?", id).Updates(&data).Error; err != nil {
-		return err
+// updateBankTransactionStatus only writes when the transaction is still in
+// one of fromStatus, the confirm that comes second gets
+// ErrBankTransactionStatusChanged instead of writing over the first.
+func (r repo) updateBankTransactionStatus(id int64, fromStatus []string, data interface{}) error {
+
+	result := r.db.Table("Bank_transactions").Where("id = ?", id).Where("status IN ?", fromStatus).Updates(data)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrBankTransactionStatusChanged
 	}
 	return nil
 }
 
+func (r repo) CancelPendingTransaction(id int64, fromStatus []string, data model.BankTransactionCancelBody) error {
+	return r.updateBankTransactionStatus(id, fromStatus, &data)
+}
+
+func (r repo) UpdateBankTransactionStatus(id int64, fromStatus []string, status string) error {
+	return r.updateBankTransactionStatus(id, fromStatus, map[string]interface{}{"status": status})
+}
+
+// GetWithdrawAgentDebit reads the journaled agent debit of a withdraw, nil
+// when it was never sent. The row is locked until the commit, taken before
+// the bank transaction like ClaimAgentCallJournal does, so a cancel and a
+// claim of the same debit run one after the other.
+func (r repo) GetWithdrawAgentDebit(agentTransactionId string) (*model.AgentCallJournal, error) {
+
+	var list []model.AgentCallJournal
+	if err := r.db.Table("Agent_call_journals").
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("transaction_id = ?", agentTransactionId).
+		Where("action = ?", "withdraw").
+		Limit(1).
+		Find(&list).
+		Error; err != nil {
+		return nil, err
+	}
+	if len(list) == 0 {
+		return nil, nil
+	}
+	return &list[0], nil
+}
+
 func (r repo) CreateTransactionAction(data model.CreateBankTransactionActionBody) (*int64, error) {
 	if err := r.db.Table("Bank_confirm_transactions").Create(&data).Error; err != nil {
+		// the action key is taken by the request that moved the status first
+		var dup *mysql.MySQLError
+		if errors.As(err, &dup) && dup.Number == 1062 {
+			return nil, ErrBankTransactionStatusChanged
+		}
 		return nil, err
 	}
 	return &data.Id, nil
@@ -866,7 +946,7 @@ func (r repo) CreateStatementAction(data model.CreateBankStatementActionBody) er
 	return nil
 }
 
-func (r repo) ConfirmPendingDepositTransaction(id int64, body model.BankDepositTransactionConfirmBody) error {
+func (r repo) ConfirmPendingDepositTransaction(id int64, fromStatus []string, body model.BankDepositTransactionConfirmBody) error {
 	// todo :
 	// data := map[string]interface{}{
 	// 	"transfer_at":           body.TransferAt,
@@ -876,14 +956,10 @@ func (r repo) ConfirmPendingDepositTransaction(id int64, body model.BankDepositT
 	// 	"confirmed_by_user_id":  body.ConfirmedByUserId,
 	// 	"confirmed_by_username": body.ConfirmedByUsername,
 	// }
-	// .Where("status = ?", "pending")
-	if err := r.db.Table("Bank_transactions").Where("id = ?", id).Updates(body).Error; err != nil {
-		return err
-	}
-	return nil
+	return r.updateBankTransactionStatus(id, fromStatus, body)
 }
 
-func (r repo) ConfirmPendingCreditDepositTransaction(id int64, body model.BankDepositTransactionConfirmBody) error {
+func (r repo) ConfirmPendingCreditDepositTransaction(id int64, fromStatus []string, body model.BankDepositTransactionConfirmBody) error {
 	// todo :
 	// data := map[string]interface{}{
 	// 	"transfer_at":           body.TransferAt,
@@ -893,13 +969,12 @@ func (r repo) ConfirmPendingCreditDepositTransaction(id int64, body model.BankDe
 	// 	"confirmed_by_user_id":  body.ConfirmedByUserId,
 	// 	"confirmed_by_username": body.ConfirmedByUsername,
 	// }
-	if err := r.db.Table("Bank_transactions").Where("id = ?", id).Where("status = ?", "pending_credit").Updates(body).Error; err != nil {
-		return err
-	}
-	return nil
+	return r.updateBankTransactionStatus(id, fromStatus, body)
 }
 
-func (r repo) CheckMemeberHasEnoughtCredit(memberId int64, creditAmount float64) error {
+// CheckMemeberHasEnoughtCredit checks the credit not held by other withdraws
+// covers creditAmount, what transactionId holds itself is free for it.
+func (r repo) CheckMemeberHasEnoughtCredit(memberId int64, transactionId int64, creditAmount model.Money) error {
 
 	member, err := r.GetMemberById(memberId)
 	if err != nil {
@@ -908,13 +983,22 @@ func (r repo) CheckMemeberHasEnoughtCredit(memberId int64, creditAmount float64)
 	if creditAmount <= 0 {
 		return fmt.Errorf("INVALID_CREDIT_AMOUNT")
 	}
-	if member.Credit < creditAmount {
+	var held model.Money
+	if err := r.db.Table("Credit_reservations").
+		Select("COALESCE(SUM(amount), 0)").
+		Where("transaction_id = ?", transactionId).
+		Where("status = ?", model.CreditReservationReserved).
+		Scan(&held).
+		Error; err != nil {
+		return err
+	}
+	if member.Credit-member.CreditReserved+held < creditAmount {
 		return fmt.Errorf("INSUFFICIENT_CREDIT")
 	}
 	return nil
 }
 
-func (r repo) ConfirmPendingWithdrawTransaction(id int64, body model.BankWithdrawTransactionConfirmBody) error {
+func (r repo) ConfirmPendingWithdrawTransaction(id int64, fromStatus []string, body model.BankWithdrawTransactionConfirmBody) error {
 	// todo :
 	//  data := map[string]interface{}{
 	// 	"transfer_at":           body.TransferAt,
@@ -925,14 +1009,10 @@ func (r repo) ConfirmPendingWithdrawTransaction(id int64, body model.BankWithdra
 	// 	"confirmed_by_user_id":  body.ConfirmedByUserId,
 	// 	"confirmed_by_username": body.ConfirmedByUsername,
 	// }
-	//.Where("status = ?", "pending")
-	if err := r.db.Table("Bank_transactions").Where("id = ?", id).Updates(&body).Error; err != nil {
-		return err
-	}
-	return nil
+	return r.updateBankTransactionStatus(id, fromStatus, &body)
 }
 
-func (r repo) ConfirmPendingWithdrawTransfer(id int64, body model.BankWithdrawTransactionConfirmBody) error {
+func (r repo) ConfirmPendingWithdrawTransfer(id int64, fromStatus []string, body model.BankWithdrawTransactionConfirmBody) error {
 	// todo :
 	// data := map[string]interface{}{
 	// 	"transfer_at":           body.TransferAt,
@@ -942,10 +1022,7 @@ func (r repo) ConfirmPendingWithdrawTransfer(id int64, body model.BankWithdrawTr
 	// 	"confirmed_by_user_id":  body.ConfirmedByUserId,
 	// 	"confirmed_by_username": body.ConfirmedByUsername,
 	// }
-	if err := r.db.Table("Bank_transactions").Where("id = ?", id).Where("status = ?", "pending_transfer").Updates(&body).Error; err != nil {
-		return err
-	}
-	return nil
+	return r.updateBankTransactionStatus(id, fromStatus, &body)
 }
 
 func (r repo) GetFinishedTransactions(req model.FinishedTransactionListRequest) (*model.SuccessWithPagination, error) {
@@ -1051,11 +1128,8 @@ func (r repo) GetFinishedTransactions(req model.FinishedTransactionListRequest)
 	return &result, nil
 }
 
-func (r repo) RemoveFinishedTransaction(id int64, data model.BankTransactionRemoveBody) error {
-	if err := r.db.Table("Bank_transactions").Where("id = ?", id).Updates(&data).Error; err != nil {
-		return err
-	}
-	return nil
+func (r repo) RemoveFinishedTransaction(id int64, fromStatus []string, data model.BankTransactionRemoveBody) error {
+	return r.updateBankTransactionStatus(id, fromStatus, &data)
 }
 
 func (r repo) GetRemovedTransactions(req model.RemovedTransactionListRequest) (*model.SuccessWithPagination, error) {
@@ -1156,7 +1230,7 @@ func (r repo) GetRemovedTransactions(req model.RemovedTransactionListRequest) (*
 func (r repo) GetMemberById(id int64) (*model.Member, error) {
 	var record model.Member
 
-	selectedFields := "users.id, users.member_code, users.username, users.phone, users.firstname, users.lastname, users.fullname, users.credit, users.bankname, users.bank_account, users.promotion, users.status, users.channel, users.true_wallet, users.note, users.turnover_limit, users.created_at"
+	selectedFields := "users.id, users.member_code, users.username, users.phone, users.firstname, users.lastname, users.fullname, users.credit, users.credit_reserved, users.bankname, users.bank_account, users.promotion, users.status, users.channel, users.true_wallet, users.note, users.turnover_limit, users.turnover, users.created_at"
 	if err := r.db.Table("Users as users").
 		Select(selectedFields).
 		Where("users.id = ?", id).
@@ -1168,7 +1242,7 @@ func (r repo) GetMemberById(id int64) (*model.Member, error) {
 	return &record, nil
 }
 
-func (r repo) GetMemberCredit(id int64) (float64, error) {
+func (r repo) GetMemberCredit(id int64) (model.Money, error) {
 	var record model.Member
 
 	selectedFields := "users.id, users.credit"
@@ -1186,7 +1260,7 @@ func (r repo) GetMemberCredit(id int64) (float64, error) {
 func (r repo) GetMemberByCode(memberCode string) (*model.Member, error) {
 	var record model.Member
 
-	selectedFields := "users.id, users.member_code, users.username, users.phone, users.firstname, users.lastname, users.fullname, users.credit, users.bankname, users.bank_account, users.promotion, users.status, users.channel, users.true_wallet, users.note, users.turnover_limit, users.created_at"
+	selectedFields := "users.id, users.member_code, users.username, users.phone, users.firstname, users.lastname, users.fullname, users.credit, users.bankname, users.bank_account, users.promotion, users.status, users.channel, users.true_wallet, users.note, users.turnover_limit, users.turnover, users.created_at"
 	if err := r.db.Table("Users as users").
 		Select(selectedFields).
 		Where("users.member_code = ?", memberCode).
@@ -1220,7 +1294,7 @@ func (r repo) GetMembers(req model.MemberListRequest) (*model.SuccessWithPaginat
 	}
 	if total > 0 {
 		// SELECT //
-		selectedFields := "users.id, users.member_code, users.username, users.phone, users.firstname, users.lastname, users.fullname, users.credit, users.bankname, users.bank_account, users.promotion, users.status, users.channel, users.true_wallet, users.note, users.turnover_limit, users.created_at"
+		selectedFields := "users.id, users.member_code, users.username, users.phone, users.firstname, users.lastname, users.fullname, users.credit, users.bankname, users.bank_account, users.promotion, users.status, users.channel, users.true_wallet, users.note, users.turnover_limit, users.turnover, users.created_at"
 		query := r.db.Table("Users as users")
 		query = query.Select(selectedFields)
 		if req.Search != "" {
@@ -1283,7 +1357,7 @@ func (r repo) GetPossibleStatementOwners(req model.MemberPossibleListRequest) (*
 	}
 	if total > 0 {
 		// SELECT //
-		selectedFields := "users.id, users.member_code, users.username, users.phone, users.firstname, users.lastname, users.fullname, users.credit, users.bankname, users.bank_account, users.promotion, users.status, users.channel, users.true_wallet, users.note, users.turnover_limit, users.created_at"
+		selectedFields := "users.id, users.member_code, users.username, users.phone, users.firstname, users.lastname, users.fullname, users.credit, users.bankname, users.bank_account, users.promotion, users.status, users.channel, users.true_wallet, users.note, users.turnover_limit, users.turnover, users.created_at"
 		query := r.db.Table("Users as users")
 		query = query.Select(selectedFields)
 		if req.UserBankCode != nil {
@@ -1457,78 +1531,242 @@ func (r repo) GetMemberTransactionSummary(req model.MemberTransactionListRequest
 
 func (r repo) IncreaseMemberCredit(body model.MemberStatementCreateBody) error {
 
-	member, err := r.GetMemberById(body.UserId)
-	if err != nil {
-		return err
-	}
-
 	// todo : use agent credit
-	if err := r.db.Transaction(func(tx *gorm.DB) error {
-		data := map[string]interface{}{
-			"user_id":           member.Id,
-			"statement_type_id": body.StatementTypeId,
-			"transfer_at":       time.Now(),
-			"info":              body.Info,
-			"before_balance":    member.Credit,
-			"amount":            body.Amount,
-			"after_balance":     member.Credit + body.Amount,
-		}
-		if err := r.db.Table("User_statements").Create(&data).Error; err != nil {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		statementType, err := memberStatementTypeById(tx, body.StatementTypeId)
+		if err != nil {
 			return err
 		}
-		if err := tx.Table("Users").Where("id = ?", member.Id).UpdateColumn("credit", gorm.Expr("credit + ?", body.Amount)).Error; err != nil {
+		// the agent balance is synced back by AdjustMemberCredit
+		return moveMemberCredit(tx, body, *statementType, body.Amount)
+	})
+}
+
+func (r repo) DecreaseMemberCredit(body model.MemberStatementCreateBody) error {
+
+	// todo : check with agent credit
+	if body.Amount <= 0 {
+		return fmt.Errorf("NOT_ENOUGH_CREDIT")
+	}
+	// todo : use agent credit
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		statementType, err := memberStatementTypeById(tx, body.StatementTypeId)
+		if err != nil {
 			return err
 		}
-		// todo : sync with agent credit
-		// var agentCreditBalance = from agent
-		// if err := tx.Table("Users").Where("id = ?", member.Id).UpdateColumn("credit", agentCreditBalance).Error; err != nil {
-		// 	return err
-		// }
-		return nil // COMMIT
-	}); err != nil {
+		// the agent balance is synced back by AdjustMemberCredit
+		return moveMemberCredit(tx, body, *statementType, -body.Amount)
+	})
+}
+
+// moveMemberCredit adds amount to the member credit, writes the statement and
+// posts it to the ledger. The member row stays locked from reading the
+// balance until the commit, a debit larger than the credit not reserved is
+// refused.
+func moveMemberCredit(tx *gorm.DB, body model.MemberStatementCreateBody, statementType model.MemberStatementType, amount model.Money) error {
+
+	var member struct {
+		Id             int64
+		Credit         model.Money
+		CreditReserved model.Money
+	}
+	if err := tx.Table("Users").
+		Select("id, COALESCE(credit, 0) AS credit, credit_reserved").
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", body.UserId).
+		Take(&member).
+		Error; err != nil {
 		return err
 	}
-	return nil
+	// the credit held for pending withdraws cannot be taken by anything else
+	if amount < 0 && member.Credit+amount < member.CreditReserved {
+		return fmt.Errorf("NOT_ENOUGH_CREDIT")
+	}
+
+	afterBalance := member.Credit + amount
+	statement := model.MemberStatement{
+		UserId:          member.Id,
+		StatementTypeId: statementType.Id,
+		TransferAt:      time.Now(),
+		Info:            body.Info,
+		BeforeBalance:   member.Credit,
+		Amount:          amount,
+		AfterBalance:    afterBalance,
+	}
+	if err := tx.Table("User_statements").Create(&statement).Error; err != nil {
+		return err
+	}
+	if err := tx.Table("Users").Where("id = ?", member.Id).UpdateColumn("credit", afterBalance).Error; err != nil {
+		return err
+	}
+
+	return postLedger(tx, model.LedgerPosting{
+		Code:          statementType.Code,
+		UserId:        member.Id,
+		StatementId:   &statement.Id,
+		Info:          body.Info,
+		Amount:        amount,
+		ContraCode:    ledgerContraCode(statementType.Code),
+		BankAccountId: body.BankAccountId,
+	})
 }
 
-func (r repo) DecreaseMemberCredit(body model.MemberStatementCreateBody) error {
+func memberStatementTypeById(tx *gorm.DB, id int64) (*model.MemberStatementType, error) {
 
-	member, err := r.GetMemberById(body.UserId)
-	if err != nil {
+	var record model.MemberStatementType
+	if err := tx.Table("User_statement_types").
+		Where("id = ?", id).
+		Where("deleted_at IS NULL").
+		First(&record).
+		Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// GetMembersForCreditSync pages by id through the members registered at the
+// agent, seamless wallet members are left out.
+func (r repo) GetMembersForCreditSync(afterId int64, limit int) ([]model.Member, error) {
+	var list []model.Member
+
+	selectedFields := "users.id, users.member_code, users.username, users.credit, users.credit_reserved"
+	if err := r.db.Table("Users as users").
+		Select(selectedFields).
+		Where("users.id > ?", afterId).
+		Where("users.username IS NOT NULL AND users.username != ''").
+		Where("users.deleted_at IS NULL").
+		Where("NOT EXISTS (SELECT 1 FROM Seamless_transactions AS seamless WHERE seamless.user_id = users.id)").
+		Order("users.id ASC").
+		Limit(limit).
+		Find(&list).
+		Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// CheckMemberCreditSync tells whether the credit of the member may be set to
+// the agent balance, see checkMemberCreditSync.
+func (r repo) CheckMemberCreditSync(userId int64) error {
+	return checkMemberCreditSync(r.db, userId)
+}
+
+// checkMemberCreditSync refuses a seamless member, and a member with a
+// deposit or withdraw not finished yet or an agent call still to be sent:
+// those amounts are at the agent and not here, or the other way round, until
+// they finish.
+func checkMemberCreditSync(tx *gorm.DB, userId int64) error {
+
+	var seamless int64
+	if err := tx.Table("Seamless_transactions").
+		Where("user_id = ?", userId).
+		Limit(1).
+		Count(&seamless).
+		Error; err != nil {
 		return err
 	}
-	// todo : check with agent credit
-	if body.Amount > 0 && member.Credit >= body.Amount {
-		// todo : use agent credit
-		if err := r.db.Transaction(func(tx *gorm.DB) error {
-			data := map[string]interface{}{
-				"user_id":           member.Id,
-				"statement_type_id": body.StatementTypeId,
-				"transfer_at":       time.Now(),
-				"info":              body.Info,
-				"before_balance":    member.Credit,
-				"amount":            body.Amount * -1,
-				"after_balance":     member.Credit + body.Amount,
-			}
-			if err := r.db.Table("User_statements").Create(&data).Error; err != nil {
-				return err
-			}
-			if err := tx.Table("Users").Where("id = ?", member.Id).UpdateColumn("credit", gorm.Expr("credit - ?", body.Amount)).Error; err != nil {
-				return err
-			}
-			// todo : sync with agent credit
-			// var agentCreditBalance = from agent
-			// if err := tx.Table("Users").Where("id = ?", member.Id).UpdateColumn("credit", agentCreditBalance).Error; err != nil {
-			// 	return err
-			// }
-			return nil // COMMIT
+	if seamless > 0 {
+		return ErrMemberCreditSeamless
+	}
+
+	var pending int64
+	if err := tx.Table("Bank_transactions").
+		Where("user_id = ?", userId).
+		Where("status IN ?", []string{model.BankTransactionPending, model.BankTransactionPendingCredit, model.BankTransactionPendingAgent}).
+		Where("deleted_at IS NULL").
+		Limit(1).
+		Count(&pending).
+		Error; err != nil {
+		return err
+	}
+	if pending > 0 {
+		return ErrMemberCreditInProgress
+	}
+
+	var calls int64
+	if err := tx.Table("Agent_call_journals").
+		Where("user_id = ?", userId).
+		Where("status IN ?", []string{"running", "retry"}).
+		Limit(1).
+		Count(&calls).
+		Error; err != nil {
+		return err
+	}
+	if calls > 0 {
+		return ErrMemberCreditInProgress
+	}
+
+	return nil
+}
+
+// AdjustMemberCredit sets the member credit to the agent balance with a
+// statement of the difference. Nothing is written when the credit moved since
+// it was read, false is returned, or when checkMemberCreditSync refuses the
+// member or the balance is under the reserved credit, with the error.
+func (r repo) AdjustMemberCredit(userId int64, statementTypeId int64, credit model.Money, agentBalance model.Money, info string) (bool, error) {
+
+	adjusted := false
+	if err := r.db.Transaction(func(tx *gorm.DB) error {
+
+		// locked like moveMemberCredit, no credit moves until the commit
+		var member struct {
+			Id             int64
+			Credit         model.Money
+			CreditReserved model.Money
+		}
+		if err := tx.Table("Users").
+			Select("id, COALESCE(credit, 0) AS credit, credit_reserved").
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", userId).
+			Take(&member).
+			Error; err != nil {
+			return err
+		}
+		if member.Credit != credit {
+			return nil
+		}
+		if err := checkMemberCreditSync(tx, userId); err != nil {
+			return err
+		}
+		if agentBalance < member.CreditReserved {
+			return ErrMemberCreditUnderReserved
+		}
+
+		if err := tx.Table("Users").
+			Where("id = ?", userId).
+			UpdateColumn("credit", agentBalance).
+			Error; err != nil {
+			return err
+		}
+		statement := model.MemberStatement{
+			UserId:          userId,
+			StatementTypeId: statementTypeId,
+			TransferAt:      time.Now(),
+			Info:            info,
+			BeforeBalance:   credit,
+			Amount:          agentBalance - credit,
+			AfterBalance:    agentBalance,
+		}
+		if err := tx.Table("User_statements").Create(&statement).Error; err != nil {
+			return err
+		}
+		// the member won or lost this at the agent
+		if err := postLedger(tx, model.LedgerPosting{
+			Code:        "adjustment",
+			UserId:      userId,
+			StatementId: &statement.Id,
+			Info:        info,
+			Amount:      agentBalance - credit,
+			ContraCode:  ledgerContraCode("adjustment"),
 		}); err != nil {
 			return err
 		}
-	} else {
-		return fmt.Errorf("NOT_ENOUGH_CREDIT")
+		adjusted = true
+		return nil // COMMIT
+	}); err != nil {
+		return false, err
 	}
-	return nil
+	return adjusted, nil
 }
 
 func (r repo) TransferExternalAccount(body model.ExternalAccountTransferBody) error {
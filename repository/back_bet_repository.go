@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"cybergame-api/model"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func NewBetRepository(db *gorm.DB) BetRepository {
+	return &repo{db}
+}
+
+type BetRepository interface {
+	GetBets(req model.BetListRequest) (*model.SuccessWithPagination, error)
+	GetUserIdsByUsernames(usernames []string) (map[string]int64, error)
+	GetLastAgentBetAt() (*time.Time, error)
+	SaveBets(bets []model.Bet) (*model.BetSaveResult, error)
+	GetMemberTurnover(userId int64) (*model.MemberTurnover, error)
+}
+
+func (r repo) GetBets(req model.BetListRequest) (*model.SuccessWithPagination, error) {
+
+	var list []model.Bet
+	var total int64
+
+	query := r.db.Table("Bets")
+	if req.UserId != 0 {
+		query = query.Where("user_id = ?", req.UserId)
+	}
+	if req.Provider != "" {
+		query = query.Where("provider = ?", req.Provider)
+	}
+	if req.Status != "" {
+		query = query.Where("status = ?", req.Status)
+	}
+	if req.FromBetDate != "" {
+		query = query.Where("bet_at >= ?", req.FromBetDate)
+	}
+	if req.ToBetDate != "" {
+		query = query.Where("bet_at <= ?", req.ToBetDate)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	if total > 0 {
+		if err := query.
+			Order("bet_at DESC, id DESC").
+			Limit(req.Limit).
+			Offset(req.Limit * req.Page).
+			Find(&list).
+			Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var result model.SuccessWithPagination
+	result.List = list
+	result.Total = total
+	return &result, nil
+}
+
+func (r repo) GetUserIdsByUsernames(usernames []string) (map[string]int64, error) {
+
+	var list []struct {
+		Id       int64
+		Username string
+	}
+
+	result := make(map[string]int64, len(usernames))
+	if len(usernames) == 0 {
+		return result, nil
+	}
+
+	if err := r.db.Table("Users").
+		Select("id, username").
+		Where("username IN ?", usernames).
+		Where("deleted_at IS NULL").
+		Find(&list).
+		Error; err != nil {
+		return nil, err
+	}
+
+	for _, user := range list {
+		result[user.Username] = user.Id
+	}
+	return result, nil
+}
+
+// GetLastAgentBetAt is the latest time an imported agent bet was placed or
+// settled, the next pull starts from there.
+func (r repo) GetLastAgentBetAt() (*time.Time, error) {
+
+	var last struct {
+		LastAt *time.Time
+	}
+
+	if err := r.db.Table("Bets").
+		Select("MAX(COALESCE(settled_at, bet_at)) AS last_at").
+		Where("source = ?", "agent").
+		Scan(&last).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return last.LastAt, nil
+}
+
+// SaveBets inserts new bets and updates the known ones by provider and
+// BetRef. Users.turnover moves by the valid amount of the bets that became
+// settled, or back when a settled bet is canceled, so a bet imported twice
+// counts once.
+func (r repo) SaveBets(bets []model.Bet) (*model.BetSaveResult, error) {
+
+	var result model.BetSaveResult
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+
+		turnovers := make(map[int64]float64)
+		for _, bet := range bets {
+
+			var existing []model.Bet
+			if err := tx.Table("Bets").
+				Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("provider = ? AND bet_ref = ?", bet.Provider, bet.BetRef).
+				Limit(1).
+				Find(&existing).
+				Error; err != nil {
+				return err
+			}
+
+			if len(existing) == 0 {
+				if err := tx.Table("Bets").Create(&bet).Error; err != nil {
+					return err
+				}
+				turnovers[bet.UserId] += betTurnover(bet)
+				result.Created++
+				continue
+			}
+
+			old := existing[0]
+			if old.Status == bet.Status && old.ValidAmount == bet.ValidAmount && old.PayoutAmount == bet.PayoutAmount && old.WinLoss == bet.WinLoss {
+				result.Unchanged++
+				continue
+			}
+
+			if err := tx.Table("Bets").
+				Where("id = ?", old.Id).
+				Updates(map[string]interface{}{
+					"bet_amount":    bet.BetAmount,
+					"valid_amount":  bet.ValidAmount,
+					"payout_amount": bet.PayoutAmount,
+					"win_loss":      bet.WinLoss,
+					"status":        bet.Status,
+					"settled_at":    bet.SettledAt,
+				}).
+				Error; err != nil {
+				return err
+			}
+			turnovers[old.UserId] += betTurnover(bet) - betTurnover(old)
+			result.Updated++
+		}
+
+		for userId, amount := range turnovers {
+			amount = math.Round(amount*100) / 100
+			if amount == 0 {
+				continue
+			}
+			if err := tx.Table("Users").
+				Where("id = ?", userId).
+				UpdateColumn("turnover", gorm.Expr("turnover + ?", amount)).
+				Error; err != nil {
+				return err
+			}
+			result.TurnoverAdded += amount
+		}
+		return nil // COMMIT
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result.TurnoverAdded = math.Round(result.TurnoverAdded*100) / 100
+	return &result, nil
+}
+
+// only settled bets count toward the turnover
+func betTurnover(bet model.Bet) float64 {
+	if bet.Status != "settled" {
+		return 0
+	}
+	return bet.ValidAmount
+}
+
+func (r repo) GetMemberTurnover(userId int64) (*model.MemberTurnover, error) {
+
+	var record model.MemberTurnover
+
+	if err := r.db.Table("Users").
+		Select("id AS user_id, COALESCE(turnover_limit, 0) AS turnover_limit, turnover").
+		Where("id = ?", userId).
+		Where("deleted_at IS NULL").
+		First(&record).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
@@ -0,0 +1,426 @@
+package repository
+
+import (
+	"cybergame-api/model"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func NewGameRepository(db *gorm.DB) GameRepository {
+	return &repo{db}
+}
+
+type GameRepository interface {
+	CountGames() (int64, error)
+	GetGames(req model.GameAdminListRequest) (*model.SuccessWithPagination, error)
+	GetGameById(id int64) (*model.Game, error)
+	GetProviderGames(provider string) ([]model.Game, error)
+	UpdateGame(id int64, body model.GameUpdateBody) error
+	UpsertGames(games []model.Game) error
+	SyncProviderGames(games []model.Game, removedIds []int64) error
+	GetGameProviderStates() ([]model.GameProvider, error)
+	GetGameProviderTotals() ([]model.GameProviderResponse, error)
+	SetGameProviderEnabled(code string, body model.GameProviderUpdateBody) error
+	GetActiveGameMaintenances(provider string) ([]model.GameMaintenance, error)
+	GetGameMaintenances(req model.GameMaintenanceListRequest) (*model.SuccessWithPagination, error)
+	GetGameMaintenanceById(id int64) (*model.GameMaintenance, error)
+	CreateGameMaintenance(body model.GameMaintenanceBody) error
+	DeleteGameMaintenance(id int64) error
+	GetGameIconsToMirror(req model.GameIconMirrorRequest) ([]model.GameIconMirror, error)
+	UpdateGameIconMirror(ids []int64, source string, pathname *string, status string) error
+	GetGamesWithoutSearchKeys() ([]model.GameSearchKeys, error)
+	UpdateGameSearchKeys(id int64, searchNames string, searchPhonetic string) error
+}
+
+// catalogColumns are the columns owned by the provider files. Everything else
+// (sort_order, is_hidden, ...) is kept as is when a game is imported again.
+var catalogColumns = []string{
+	"device_codes",
+	"name",
+	"names",
+	"game_type",
+	"provider_game_type",
+	"image_icon",
+	"currencies",
+	"is_desktop",
+	"is_mobile",
+	"is_hot",
+	"is_new",
+	"is_recommend",
+	"is_available",
+	"released_at",
+	"search_names",
+	"search_phonetic",
+	"deleted_at",
+}
+
+func (r repo) CountGames() (int64, error) {
+
+	var total int64
+
+	if err := r.db.Table("Games").
+		Where("deleted_at IS NULL").
+		Count(&total).
+		Error; err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
+
+func (r repo) GetGames(req model.GameAdminListRequest) (*model.SuccessWithPagination, error) {
+
+	var list []model.Game
+	var total int64
+
+	query := r.db.Table("Games").Where("deleted_at IS NULL")
+	if req.Provider != "" {
+		query = query.Where("provider = ?", req.Provider)
+	}
+	if req.GameType != "" {
+		query = query.Where("game_type = ?", req.GameType)
+	}
+	if req.Search != "" {
+		search_like := fmt.Sprintf("%%%s%%", req.Search)
+		query = query.Where(r.db.Where("name LIKE ?", search_like).Or("game_code LIKE ?", search_like))
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	if total > 0 {
+		if err := query.
+			Order("provider ASC, sort_order ASC, id ASC").
+			Limit(req.Limit).
+			Offset(req.Limit * req.Page).
+			Find(&list).
+			Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var result model.SuccessWithPagination
+	result.List = list
+	result.Total = total
+	return &result, nil
+}
+
+func (r repo) GetGameById(id int64) (*model.Game, error) {
+
+	var game model.Game
+
+	if err := r.db.Table("Games").
+		Where("id = ? AND deleted_at IS NULL", id).
+		Take(&game).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return &game, nil
+}
+
+func (r repo) GetProviderGames(provider string) ([]model.Game, error) {
+
+	var list []model.Game
+
+	if err := r.db.Table("Games").
+		Where("provider = ? AND deleted_at IS NULL", provider).
+		Order("id ASC").
+		Find(&list).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (r repo) UpdateGame(id int64, body model.GameUpdateBody) error {
+
+	data := map[string]interface{}{}
+	if body.SortOrder != nil {
+		data["sort_order"] = *body.SortOrder
+	}
+	if body.IsHidden != nil {
+		data["is_hidden"] = *body.IsHidden
+	}
+	if body.IsEnabled != nil {
+		data["is_enabled"] = *body.IsEnabled
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	if err := r.db.Table("Games").
+		Where("id = ?", id).
+		Updates(data).
+		Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r repo) UpsertGames(games []model.Game) error {
+
+	return upsertGames(r.db, games)
+}
+
+// SyncProviderGames upserts the games of a provider and soft deletes the ones
+// that left its list, all or nothing.
+func (r repo) SyncProviderGames(games []model.Game, removedIds []int64) error {
+
+	tx := r.db.Begin()
+
+	if err := upsertGames(tx, games); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if len(removedIds) > 0 {
+		if err := tx.Table("Games").
+			Where("id IN ?", removedIds).
+			Update("deleted_at", time.Now()).
+			Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return nil
+}
+
+func (r repo) GetGameProviderStates() ([]model.GameProvider, error) {
+
+	var list []model.GameProvider
+
+	if err := r.db.Table("Game_providers").
+		Find(&list).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (r repo) GetGameProviderTotals() ([]model.GameProviderResponse, error) {
+
+	var list []model.GameProviderResponse
+
+	if err := r.db.Table("Games").
+		Select("provider, COUNT(id) AS total").
+		Where("deleted_at IS NULL").
+		Group("provider").
+		Scan(&list).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (r repo) SetGameProviderEnabled(code string, body model.GameProviderUpdateBody) error {
+
+	data := model.GameProvider{
+		Code:      code,
+		IsEnabled: *body.IsEnabled,
+		UpdatedBy: &body.UpdatedBy,
+	}
+
+	if err := r.db.Table("Game_providers").
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "code"}},
+			DoUpdates: clause.AssignmentColumns([]string{"is_enabled", "updated_by"}),
+		}).
+		Create(&data).
+		Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r repo) GetGameMaintenances(req model.GameMaintenanceListRequest) (*model.SuccessWithPagination, error) {
+
+	var list []model.GameMaintenance
+	var total int64
+
+	query := r.db.Table("Game_maintenances").Where("deleted_at IS NULL")
+	if req.Provider != "" {
+		query = query.Where("provider = ?", req.Provider)
+	}
+	if req.IsActive {
+		now := time.Now()
+		query = query.Where("start_at <= ? AND end_at > ?", now, now)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	if total > 0 {
+		if err := query.
+			Order("start_at DESC").
+			Limit(req.Limit).
+			Offset(req.Limit * req.Page).
+			Find(&list).
+			Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var result model.SuccessWithPagination
+	result.List = list
+	result.Total = total
+	return &result, nil
+}
+
+func (r repo) GetGameMaintenanceById(id int64) (*model.GameMaintenance, error) {
+
+	var maintenance model.GameMaintenance
+
+	if err := r.db.Table("Game_maintenances").
+		Where("id = ?", id).
+		Take(&maintenance).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return &maintenance, nil
+}
+
+func (r repo) CreateGameMaintenance(body model.GameMaintenanceBody) error {
+
+	data := model.GameMaintenance{
+		Provider:  body.Provider,
+		GameId:    body.GameId,
+		StartAt:   body.StartAt,
+		EndAt:     body.EndAt,
+		MessageTh: body.MessageTh,
+		MessageEn: body.MessageEn,
+		CreatedBy: &body.CreatedBy,
+	}
+
+	if err := r.db.Table("Game_maintenances").
+		Create(&data).
+		Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r repo) DeleteGameMaintenance(id int64) error {
+
+	if err := r.db.Table("Game_maintenances").
+		Where("id = ?", id).
+		Delete(&model.GameMaintenance{}).
+		Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetGameIconsToMirror lists games whose current icon has not been mirrored
+// yet, a sync that changes image_icon puts the game back in this list.
+func (r repo) GetGameIconsToMirror(req model.GameIconMirrorRequest) ([]model.GameIconMirror, error) {
+
+	var list []model.GameIconMirror
+
+	query := r.db.Table("Games").
+		Select("id, image_icon, provider").
+		Where("deleted_at IS NULL").
+		Where("image_icon <> ''")
+	if req.Provider != "" {
+		query = query.Where("provider = ?", req.Provider)
+	}
+	if req.RetryFailed {
+		query = query.Where("(image_mirror_source IS NULL OR image_mirror_source <> image_icon OR image_mirror_status = ?)", "failed")
+	} else {
+		query = query.Where("(image_mirror_source IS NULL OR image_mirror_source <> image_icon)")
+	}
+
+	if err := query.
+		Order("id ASC").
+		Scan(&list).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (r repo) UpdateGameIconMirror(ids []int64, source string, pathname *string, status string) error {
+
+	if err := r.db.Table("Games").
+		Where("id IN ?", ids).
+		Updates(map[string]interface{}{
+			"image_mirror":        pathname,
+			"image_mirror_source": source,
+			"image_mirror_status": status,
+			"image_mirrored_at":   time.Now(),
+		}).
+		Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r repo) GetGamesWithoutSearchKeys() ([]model.GameSearchKeys, error) {
+
+	var list []model.GameSearchKeys
+
+	if err := r.db.Table("Games").
+		Select("id, name, names").
+		Where("search_names IS NULL").
+		Find(&list).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (r repo) UpdateGameSearchKeys(id int64, searchNames string, searchPhonetic string) error {
+
+	if err := r.db.Table("Games").
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"search_names":    searchNames,
+			"search_phonetic": searchPhonetic,
+		}).
+		Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func upsertGames(db *gorm.DB, games []model.Game) error {
+
+	if len(games) == 0 {
+		return nil
+	}
+
+	if err := db.Table("Games").
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "provider"}, {Name: "game_code"}},
+			DoUpdates: clause.AssignmentColumns(catalogColumns),
+		}).
+		CreateInBatches(&games, 200).
+		Error; err != nil {
+		return err
+	}
+
+	return nil
+}
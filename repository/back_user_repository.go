@@ -129,7 +129,7 @@ func (r repo) GetUser(id int64) (*model.UserDetail, error) {
 	var admin *model.UserDetail
 
 	if err := r.db.Model(model.User{}).Table("Users").
-		Select("id, partner, member_code, phone, promotion, bankname, bank_account, fullname, channel, true_wallet, contact, note, course").
+		Select("id, partner, member_code, phone, promotion, bankname, bank_account, fullname, channel, true_wallet, contact, note, course, credit, turnover_limit, turnover").
 		Where("id = ?", id).
 		First(&admin).
 		Error; err != nil {
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"cybergame-api/model"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrMemberCreditNotEnough is a reservation larger than the credit not held
+// by the other withdraws of the member.
+var ErrMemberCreditNotEnough = errors.New("NOT_ENOUGH_CREDIT")
+
+// ReserveMemberCredit holds amount of the member credit for the withdraw
+// transaction. The member row stays locked until the commit so two requests
+// cannot hold the same credit. The agent wallet of a transfer wallet member
+// is debited by the service right after the commit.
+func (r repo) ReserveMemberCredit(userId int64, transactionId int64, amount model.Money) error {
+
+	if amount <= 0 {
+		return ErrMemberCreditNotEnough
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+
+		var member struct {
+			Id             int64
+			Credit         model.Money
+			CreditReserved model.Money
+		}
+		if err := tx.Table("Users").
+			Select("id, COALESCE(credit, 0) AS credit, credit_reserved").
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", userId).
+			Take(&member).
+			Error; err != nil {
+			return err
+		}
+		if member.Credit-member.CreditReserved < amount {
+			return ErrMemberCreditNotEnough
+		}
+
+		reservation := model.CreditReservation{
+			UserId:        member.Id,
+			TransactionId: transactionId,
+			Amount:        amount,
+			Status:        model.CreditReservationReserved,
+		}
+		if err := tx.Table("Credit_reservations").Create(&reservation).Error; err != nil {
+			return err
+		}
+		return tx.Table("Users").
+			Where("id = ?", member.Id).
+			UpdateColumn("credit_reserved", gorm.Expr("credit_reserved + CAST(? AS DECIMAL(14,2))", amount)).
+			Error
+	})
+}
+
+// ReleaseMemberCredit gives the held credit of a canceled withdraw back to
+// the member.
+func (r repo) ReleaseMemberCredit(transactionId int64) error {
+	return r.settleCreditReservation(transactionId, model.CreditReservationReleased)
+}
+
+// CaptureMemberCredit ends the hold of a confirmed withdraw, the caller
+// decreases the credit in the same database transaction.
+func (r repo) CaptureMemberCredit(transactionId int64) error {
+	return r.settleCreditReservation(transactionId, model.CreditReservationCaptured)
+}
+
+// settleCreditReservation moves a held reservation to status and takes its
+// amount off Users.credit_reserved. A transaction without a held reservation,
+// created before the holds or settled already, changes nothing.
+func (r repo) settleCreditReservation(transactionId int64, status string) error {
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+
+		var list []model.CreditReservation
+		if err := tx.Table("Credit_reservations").
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("transaction_id = ?", transactionId).
+			Where("status = ?", model.CreditReservationReserved).
+			Limit(1).
+			Find(&list).
+			Error; err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			return nil
+		}
+		reservation := list[0]
+
+		if err := tx.Table("Credit_reservations").
+			Where("id = ?", reservation.Id).
+			Update("status", status).
+			Error; err != nil {
+			return err
+		}
+		return tx.Table("Users").
+			Where("id = ?", reservation.UserId).
+			UpdateColumn("credit_reserved", gorm.Expr("GREATEST(credit_reserved - CAST(? AS DECIMAL(14,2)), 0)", reservation.Amount)).
+			Error
+	})
+}
@@ -0,0 +1,344 @@
+package repository
+
+import (
+	"cybergame-api/model"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func NewFrontGameRepository(db *gorm.DB) FrontGameRepository {
+	return &repo{db}
+}
+
+type FrontGameRepository interface {
+	GetFrontGames(req model.GameListRequest) ([]model.GameResponse, int64, error)
+	GetFrontGame(provider string, code string) (*model.GameResponse, error)
+	GetFrontGameProviders() ([]model.GameProviderResponse, error)
+	SearchFrontGames(req model.GameSearchRequest, query string, queryKey string) ([]model.GameSearchCandidate, error)
+	GetFrontLaunchGame(provider string, code string) (*model.Game, error)
+	IsGameProviderEnabled(code string) (bool, error)
+	GetActiveGameMaintenances(provider string) ([]model.GameMaintenance, error)
+	CreateFrontGameLaunchLog(data model.GameLaunchLog) error
+	GetFrontGameFavorites(userId int64, req model.GameFavoriteListRequest) ([]model.GameResponse, int64, error)
+	AddFrontGameFavorite(userId int64, gameId int64) error
+	RemoveFrontGameFavorite(userId int64, gameId int64) error
+	GetFrontGameRecentPlays(userId int64, limit int) ([]model.GameRecentResponse, error)
+	SaveFrontGameRecentPlay(userId int64, gameId int64) error
+}
+
+const frontGameFields = "id, provider, game_code, device_codes, name, names, game_type, image_icon, image_mirror, image_mirror_source, image_mirror_status, currencies, is_desktop, is_mobile, is_hot, is_new, is_recommend"
+
+// frontGameColumns prefixes frontGameFields with the Games table for joins.
+func frontGameColumns() string {
+	fields := strings.Split(frontGameFields, ", ")
+	for i, field := range fields {
+		fields[i] = "Games." + field
+	}
+	return strings.Join(fields, ", ")
+}
+
+// frontListedGames limits a query on Games to what the catalog offers members.
+func frontListedGames(db *gorm.DB) *gorm.DB {
+	return db.Table("Games").
+		Where("deleted_at IS NULL").
+		Where("is_hidden = ?", false).
+		Where("is_available = ?", true)
+}
+
+// frontVisibleGames also drops games and providers switched off by an admin.
+func frontVisibleGames(db *gorm.DB) *gorm.DB {
+	return frontListedGames(db).
+		Where("is_enabled = ?", true).
+		Where("provider NOT IN (SELECT code FROM Game_providers WHERE is_enabled = 0)")
+}
+
+func frontGameFilters(db *gorm.DB, req model.GameListRequest) *gorm.DB {
+
+	if req.Provider != "" {
+		db = db.Where("provider = ?", req.Provider)
+	}
+	if req.GameType != "" {
+		db = db.Where("game_type = ?", req.GameType)
+	}
+	if req.Device == "desktop" {
+		db = db.Where("is_desktop = ?", true)
+	}
+	if req.Device == "mobile" {
+		db = db.Where("is_mobile = ?", true)
+	}
+	if req.Currency != "" {
+		// games without a currency list are available in every currency
+		db = db.Where("(currencies IS NULL OR JSON_CONTAINS(currencies, JSON_QUOTE(?)))", req.Currency)
+	}
+	if req.IsHot {
+		db = db.Where("is_hot = ?", true)
+	}
+	if req.IsNew {
+		db = db.Where("is_new = ?", true)
+	}
+	if req.IsRecommend {
+		db = db.Where("is_recommend = ?", true)
+	}
+	return db
+}
+
+func (r repo) GetFrontGames(req model.GameListRequest) ([]model.GameResponse, int64, error) {
+
+	var list []model.GameResponse
+	var total int64
+
+	if err := frontGameFilters(frontVisibleGames(r.db), req).
+		Count(&total).
+		Error; err != nil {
+		return nil, 0, err
+	}
+
+	if total > 0 {
+		if err := frontGameFilters(frontVisibleGames(r.db), req).
+			Select(frontGameFields).
+			Order("sort_order ASC, id ASC").
+			Limit(req.Limit).
+			Offset(req.Limit * req.Page).
+			Find(&list).
+			Error; err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return list, total, nil
+}
+
+// SearchFrontGames returns every visible game whose names, in any language, or
+// their phonetic keys contain the query. Ranking is left to the caller.
+func (r repo) SearchFrontGames(req model.GameSearchRequest, query string, queryKey string) ([]model.GameSearchCandidate, error) {
+
+	var list []model.GameSearchCandidate
+
+	filters := model.GameListRequest{
+		Provider: req.Provider,
+		GameType: req.GameType,
+		Device:   req.Device,
+	}
+
+	db := frontGameFilters(frontVisibleGames(r.db), filters).
+		Select(frontGameFields + ", search_names, search_phonetic, sort_order")
+	if queryKey != "" {
+		db = db.Where("(search_names LIKE ? OR search_phonetic LIKE ?)", "%"+escapeLike(query)+"%", "%"+escapeLike(queryKey)+"%")
+	} else {
+		db = db.Where("search_names LIKE ?", "%"+escapeLike(query)+"%")
+	}
+
+	if err := db.
+		Find(&list).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// likeEscaper makes the LIKE wildcards of a user query match themselves,
+// backslash is the default LIKE escape of MySQL.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(query string) string {
+	return likeEscaper.Replace(query)
+}
+
+func (r repo) GetFrontGame(provider string, code string) (*model.GameResponse, error) {
+
+	var game model.GameResponse
+
+	if err := frontVisibleGames(r.db).
+		Select(frontGameFields).
+		Where("provider = ? AND game_code = ?", provider, code).
+		Take(&game).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return &game, nil
+}
+
+func (r repo) GetFrontGameProviders() ([]model.GameProviderResponse, error) {
+
+	var list []model.GameProviderResponse
+
+	if err := frontVisibleGames(r.db).
+		Select("provider, COUNT(id) AS total").
+		Group("provider").
+		Order("provider ASC").
+		Scan(&list).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (r repo) GetFrontLaunchGame(provider string, code string) (*model.Game, error) {
+
+	var game model.Game
+
+	if err := frontListedGames(r.db).
+		Where("provider = ? AND game_code = ?", provider, code).
+		Take(&game).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return &game, nil
+}
+
+func (r repo) IsGameProviderEnabled(code string) (bool, error) {
+
+	var total int64
+
+	if err := r.db.Table("Game_providers").
+		Where("code = ? AND is_enabled = ?", code, false).
+		Count(&total).
+		Error; err != nil {
+		return false, err
+	}
+
+	return total == 0, nil
+}
+
+// GetActiveGameMaintenances returns the windows open right now, of every
+// provider when provider is empty.
+func (r repo) GetActiveGameMaintenances(provider string) ([]model.GameMaintenance, error) {
+
+	var list []model.GameMaintenance
+
+	now := time.Now()
+	query := r.db.Table("Game_maintenances").
+		Where("start_at <= ? AND end_at > ?", now, now)
+	if provider != "" {
+		query = query.Where("provider = ?", provider)
+	}
+
+	if err := query.
+		Order("start_at ASC").
+		Find(&list).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func (r repo) CreateFrontGameLaunchLog(data model.GameLaunchLog) error {
+
+	if err := r.db.Table("Game_launch_logs").
+		Create(&data).
+		Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r repo) GetFrontGameFavorites(userId int64, req model.GameFavoriteListRequest) ([]model.GameResponse, int64, error) {
+
+	var list []model.GameResponse
+	var total int64
+
+	query := frontVisibleGames(r.db).
+		Joins("JOIN Game_favorites AS f ON f.game_id = Games.id AND f.user_id = ?", userId)
+
+	if err := query.
+		Count(&total).
+		Error; err != nil {
+		return nil, 0, err
+	}
+
+	if total > 0 {
+		if err := query.
+			Select(frontGameColumns()).
+			Order("f.created_at DESC, f.id DESC").
+			Limit(req.Limit).
+			Offset(req.Limit * req.Page).
+			Find(&list).
+			Error; err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return list, total, nil
+}
+
+func (r repo) AddFrontGameFavorite(userId int64, gameId int64) error {
+
+	data := model.GameFavorite{
+		UserId: userId,
+		GameId: gameId,
+	}
+
+	if err := r.db.Table("Game_favorites").
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&data).
+		Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r repo) RemoveFrontGameFavorite(userId int64, gameId int64) error {
+
+	if err := r.db.Table("Game_favorites").
+		Where("user_id = ? AND game_id = ?", userId, gameId).
+		Delete(&model.GameFavorite{}).
+		Error; err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r repo) GetFrontGameRecentPlays(userId int64, limit int) ([]model.GameRecentResponse, error) {
+
+	var list []model.GameRecentResponse
+
+	if err := frontVisibleGames(r.db).
+		Select(frontGameColumns()+", p.play_count, p.last_played_at").
+		Joins("JOIN Game_recent_plays AS p ON p.game_id = Games.id AND p.user_id = ?", userId).
+		Order("p.last_played_at DESC, p.id DESC").
+		Limit(limit).
+		Find(&list).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// SaveFrontGameRecentPlay moves the game to the top of the member history.
+func (r repo) SaveFrontGameRecentPlay(userId int64, gameId int64) error {
+
+	now := time.Now()
+	data := model.GameRecentPlay{
+		UserId:       userId,
+		GameId:       gameId,
+		PlayCount:    1,
+		LastPlayedAt: now,
+	}
+
+	if err := r.db.Table("Game_recent_plays").
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "user_id"}, {Name: "game_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"play_count":     gorm.Expr("play_count + 1"),
+				"last_played_at": now,
+			}),
+		}).
+		Create(&data).
+		Error; err != nil {
+		return err
+	}
+
+	return nil
+}
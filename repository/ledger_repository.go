@@ -0,0 +1,402 @@
+package repository
+
+import (
+	"cybergame-api/model"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrLedgerUnbalanced = errors.New("ledger transaction is not balanced")
+
+// how many rows of each mismatch VerifyLedger reports
+const ledgerVerifyLimit = 100
+
+func NewLedgerRepository(db *gorm.DB) LedgerRepository {
+	return &repo{db}
+}
+
+type LedgerRepository interface {
+	GetLedgerAccounts(req model.LedgerAccountListRequest) (*model.SuccessWithPagination, error)
+	GetLedgerAccountById(id int64) (*model.LedgerAccount, error)
+	GetLedgerEntries(req model.LedgerEntryListRequest) (*model.SuccessWithPagination, error)
+	VerifyLedger() (*model.LedgerVerifyResult, error)
+}
+
+func (r repo) GetLedgerAccounts(req model.LedgerAccountListRequest) (*model.SuccessWithPagination, error) {
+
+	var list []model.LedgerAccount
+	var total int64
+
+	query := r.db.Table("Ledger_accounts")
+	if req.Type != "" {
+		query = query.Where("type = ?", req.Type)
+	}
+	if req.UserId != 0 {
+		query = query.Where("user_id = ?", req.UserId)
+	}
+	if req.Search != "" {
+		search_like := fmt.Sprintf("%%%s%%", req.Search)
+		query = query.Where(r.db.Where("code LIKE ?", search_like).Or("name LIKE ?", search_like))
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	if total > 0 {
+		if err := query.
+			Order("id ASC").
+			Limit(req.Limit).
+			Offset(req.Limit * req.Page).
+			Find(&list).
+			Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var result model.SuccessWithPagination
+	result.List = list
+	result.Total = total
+	return &result, nil
+}
+
+func (r repo) GetLedgerAccountById(id int64) (*model.LedgerAccount, error) {
+
+	var record model.LedgerAccount
+	if err := r.db.Table("Ledger_accounts").
+		Where("id = ?", id).
+		First(&record).
+		Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r repo) GetLedgerEntries(req model.LedgerEntryListRequest) (*model.SuccessWithPagination, error) {
+
+	var list []model.LedgerEntryResponse
+	var total int64
+
+	selectedFields := "entries.id, entries.transaction_id, transactions.code AS transaction_code, transactions.info"
+	selectedFields += ", entries.account_id, accounts.code AS account_code, accounts.name AS account_name"
+	selectedFields += ", entries.debit, entries.credit, entries.created_at"
+	query := r.db.Table("Ledger_entries AS entries").
+		Joins("JOIN Ledger_transactions AS transactions ON transactions.id = entries.transaction_id").
+		Joins("JOIN Ledger_accounts AS accounts ON accounts.id = entries.account_id")
+	if req.AccountId != 0 {
+		query = query.Where("entries.account_id = ?", req.AccountId)
+	}
+	if req.TransactionId != 0 {
+		query = query.Where("entries.transaction_id = ?", req.TransactionId)
+	}
+	if req.UserId != 0 {
+		query = query.Where("transactions.user_id = ?", req.UserId)
+	}
+	if req.FromDate != "" {
+		query = query.Where("entries.created_at >= ?", req.FromDate)
+	}
+	if req.ToDate != "" {
+		query = query.Where("entries.created_at <= ?", req.ToDate)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, err
+	}
+
+	if total > 0 {
+		if err := query.
+			Select(selectedFields).
+			Order("entries.id DESC").
+			Limit(req.Limit).
+			Offset(req.Limit * req.Page).
+			Scan(&list).
+			Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var result model.SuccessWithPagination
+	result.List = list
+	result.Total = total
+	return &result, nil
+}
+
+// VerifyLedger recomputes the balances from the entries and compares them
+// with the stored ones and with Users.credit.
+func (r repo) VerifyLedger() (*model.LedgerVerifyResult, error) {
+
+	result := model.LedgerVerifyResult{
+		Unbalanced:      []model.LedgerUnbalanced{},
+		BalanceMismatch: []model.LedgerBalanceMismatch{},
+		CreditMismatch:  []model.LedgerCreditMismatch{},
+	}
+
+	var totals struct {
+		Debit  model.Money
+		Credit model.Money
+	}
+	if err := r.db.Table("Ledger_entries").
+		Select("COALESCE(SUM(debit), 0) AS debit, COALESCE(SUM(credit), 0) AS credit").
+		Scan(&totals).
+		Error; err != nil {
+		return nil, err
+	}
+	result.TotalDebit = totals.Debit
+	result.TotalCredit = totals.Credit
+
+	if err := r.db.Table("Ledger_entries").
+		Select("transaction_id, SUM(debit) AS debit, SUM(credit) AS credit").
+		Group("transaction_id").
+		Having("SUM(debit) != SUM(credit)").
+		Order("transaction_id ASC").
+		Limit(ledgerVerifyLimit).
+		Scan(&result.Unbalanced).
+		Error; err != nil {
+		return nil, err
+	}
+
+	derived := fmt.Sprintf("CASE WHEN accounts.type IN ('%s', '%s') THEN COALESCE(sums.debit - sums.credit, 0) ELSE COALESCE(sums.credit - sums.debit, 0) END", model.LedgerAccountAsset, model.LedgerAccountExpense)
+	if err := r.db.Table("Ledger_accounts AS accounts").
+		Select("accounts.id AS account_id, accounts.code, accounts.balance AS stored, " + derived + " AS derived").
+		Joins("LEFT JOIN (SELECT account_id, SUM(debit) AS debit, SUM(credit) AS credit FROM Ledger_entries GROUP BY account_id) AS sums ON sums.account_id = accounts.id").
+		Where("accounts.balance != " + derived).
+		Order("accounts.id ASC").
+		Limit(ledgerVerifyLimit).
+		Scan(&result.BalanceMismatch).
+		Error; err != nil {
+		return nil, err
+	}
+
+	if err := r.db.Table("Users AS users").
+		Select("users.id AS user_id, COALESCE(users.credit, 0) AS credit, COALESCE(accounts.balance, 0) AS ledger").
+		Joins("LEFT JOIN Ledger_accounts AS accounts ON accounts.user_id = users.id AND accounts.type = ?", model.LedgerAccountMember).
+		Where("COALESCE(users.credit, 0) != COALESCE(accounts.balance, 0)").
+		Order("users.id ASC").
+		Limit(ledgerVerifyLimit).
+		Scan(&result.CreditMismatch).
+		Error; err != nil {
+		return nil, err
+	}
+
+	result.IsBalanced = result.Balanced()
+	return &result, nil
+}
+
+// ledgerContraCode is the account on the other side of a member statement,
+// deposits and withdrawals use the bank account when it is known.
+func ledgerContraCode(statementCode string) string {
+
+	switch {
+	case statementCode == "deposit", statementCode == "withdraw":
+		return "bank_clearing"
+	case statementCode == "bonus":
+		return "bonus"
+	case statementCode == "adjustment", strings.HasPrefix(statementCode, "seamless_"):
+		return "game"
+	}
+	return "house"
+}
+
+// postLedger writes posting as one balanced ledger transaction and moves the
+// stored balances, inside the caller's database transaction. The member row
+// must already be locked by the caller, the member account is locked before
+// the contra account so concurrent postings wait in the same order.
+func postLedger(tx *gorm.DB, posting model.LedgerPosting) error {
+
+	amount := posting.Amount
+	if amount == 0 {
+		return nil
+	}
+
+	member, err := ledgerMemberAccount(tx, posting.UserId)
+	if err != nil {
+		return err
+	}
+
+	var contra *model.LedgerAccount
+	if posting.BankAccountId != nil {
+		contra, err = ledgerBankAccount(tx, *posting.BankAccountId)
+	} else {
+		contra, err = ledgerAccountByCode(tx, posting.ContraCode)
+	}
+	if err != nil {
+		return err
+	}
+
+	transaction := model.LedgerTransaction{
+		Code:        posting.Code,
+		UserId:      &posting.UserId,
+		StatementId: posting.StatementId,
+		Info:        posting.Info,
+	}
+	if err := tx.Table("Ledger_transactions").Create(&transaction).Error; err != nil {
+		return err
+	}
+
+	entries, err := LedgerEntries(posting, transaction.Id, member.Id, contra.Id)
+	if err != nil {
+		return err
+	}
+	if err := tx.Table("Ledger_entries").Create(&entries).Error; err != nil {
+		return err
+	}
+
+	if err := moveLedgerBalance(tx, *member, entries[0]); err != nil {
+		return err
+	}
+	return moveLedgerBalance(tx, *contra, entries[1])
+}
+
+// LedgerEntries are the member entry and the contra entry of posting in the
+// ledger transaction transactionId, the member is credited with what it
+// receives and the contra account debited.
+func LedgerEntries(posting model.LedgerPosting, transactionId int64, memberAccountId int64, contraAccountId int64) ([]model.LedgerEntry, error) {
+
+	memberEntry := model.LedgerEntry{TransactionId: transactionId, AccountId: memberAccountId}
+	contraEntry := model.LedgerEntry{TransactionId: transactionId, AccountId: contraAccountId}
+	if posting.Amount > 0 {
+		memberEntry.Credit = posting.Amount
+		contraEntry.Debit = posting.Amount
+	} else {
+		memberEntry.Debit = -posting.Amount
+		contraEntry.Credit = -posting.Amount
+	}
+	entries := []model.LedgerEntry{memberEntry, contraEntry}
+	if err := CheckLedgerBalanced(entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// CheckLedgerBalanced refuses entries whose debits and credits differ.
+func CheckLedgerBalanced(entries []model.LedgerEntry) error {
+
+	var debit, credit model.Money
+	for _, entry := range entries {
+		debit += entry.Debit
+		credit += entry.Credit
+	}
+	if debit != credit {
+		return ErrLedgerUnbalanced
+	}
+	return nil
+}
+
+func moveLedgerBalance(tx *gorm.DB, account model.LedgerAccount, entry model.LedgerEntry) error {
+
+	change := entry.Credit - entry.Debit
+	if account.Type == model.LedgerAccountAsset || account.Type == model.LedgerAccountExpense {
+		change = -change
+	}
+	return tx.Table("Ledger_accounts").
+		Where("id = ?", account.Id).
+		UpdateColumn("balance", gorm.Expr("balance + CAST(? AS DECIMAL(14,2))", change)).
+		Error
+}
+
+func ledgerAccountByCode(tx *gorm.DB, code string) (*model.LedgerAccount, error) {
+
+	var record model.LedgerAccount
+	if err := tx.Table("Ledger_accounts").
+		Where("code = ?", code).
+		First(&record).
+		Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("ledger account %s not found", code)
+		}
+		return nil, err
+	}
+	return &record, nil
+}
+
+func ledgerMemberAccount(tx *gorm.DB, userId int64) (*model.LedgerAccount, error) {
+
+	return ledgerAccountOnce(tx, fmt.Sprintf("member:%d", userId), func() (*model.LedgerAccount, error) {
+		var user struct {
+			MemberCode *string
+			Username   *string
+		}
+		if err := tx.Table("Users").
+			Select("member_code, username").
+			Where("id = ?", userId).
+			Take(&user).
+			Error; err != nil {
+			return nil, err
+		}
+		name := ""
+		if user.MemberCode != nil && *user.MemberCode != "" {
+			name = *user.MemberCode
+		} else if user.Username != nil {
+			name = *user.Username
+		}
+		return &model.LedgerAccount{Name: name, Type: model.LedgerAccountMember, UserId: &userId}, nil
+	})
+}
+
+func ledgerBankAccount(tx *gorm.DB, bankAccountId int64) (*model.LedgerAccount, error) {
+
+	return ledgerAccountOnce(tx, fmt.Sprintf("bank:%d", bankAccountId), func() (*model.LedgerAccount, error) {
+		var bankAccount struct {
+			AccountName   string
+			AccountNumber string
+		}
+		if err := tx.Table("Bank_accounts").
+			Select("account_name, account_number").
+			Where("id = ?", bankAccountId).
+			Take(&bankAccount).
+			Error; err != nil {
+			return nil, err
+		}
+		name := bankAccount.AccountName + " " + bankAccount.AccountNumber
+		return &model.LedgerAccount{Name: name, Type: model.LedgerAccountAsset, BankAccountId: &bankAccountId}, nil
+	})
+}
+
+// ledgerAccountOnce finds the account of code, locked, or creates it from
+// newAccount. Two postings creating the same account meet on the unique code.
+func ledgerAccountOnce(tx *gorm.DB, code string, newAccount func() (*model.LedgerAccount, error)) (*model.LedgerAccount, error) {
+
+	find := func() ([]model.LedgerAccount, error) {
+		var list []model.LedgerAccount
+		err := tx.Table("Ledger_accounts").
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("code = ?", code).
+			Limit(1).
+			Find(&list).
+			Error
+		return list, err
+	}
+
+	list, err := find()
+	if err != nil {
+		return nil, err
+	}
+	if len(list) > 0 {
+		return &list[0], nil
+	}
+
+	account, err := newAccount()
+	if err != nil {
+		return nil, err
+	}
+	account.Code = code
+	if err := tx.Table("Ledger_accounts").Create(account).Error; err != nil {
+		var dup *mysql.MySQLError
+		if !errors.As(err, &dup) || dup.Number != 1062 {
+			return nil, err
+		}
+		if list, err = find(); err != nil {
+			return nil, err
+		}
+		if len(list) == 0 {
+			return nil, fmt.Errorf("ledger account %s not found", code)
+		}
+		return &list[0], nil
+	}
+	return account, nil
+}
@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"cybergame-api/model"
+	"errors"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrSeamlessPlayerNotFound      = errors.New("Player not found")
+	ErrSeamlessInsufficientCredit  = errors.New("Insufficient credit")
+	ErrSeamlessTransactionNotFound = errors.New("Transaction not found")
+	ErrSeamlessTransactionMismatch = errors.New("Transaction id already used")
+	ErrSeamlessTransactionSettled  = errors.New("Transaction already refunded or rolled back")
+)
+
+func NewSeamlessWalletRepository(db *gorm.DB) SeamlessWalletRepository {
+	return &repo{db}
+}
+
+type SeamlessWalletRepository interface {
+	GetSeamlessBalance(username string) (*model.SeamlessBalance, error)
+	GetMemberStatementTypeByCode(code string) (*model.MemberStatementType, error)
+	ApplySeamlessTransaction(body model.SeamlessTransactionBody) (*model.SeamlessTransactionResult, error)
+}
+
+func (r repo) GetSeamlessBalance(username string) (*model.SeamlessBalance, error) {
+
+	var record model.SeamlessBalance
+
+	if err := r.db.Table("Users").
+		Select("id, username, COALESCE(credit, 0) AS credit, credit_reserved").
+		Where("username = ?", username).
+		Where("deleted_at IS NULL").
+		First(&record).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// ApplySeamlessTransaction moves Users.credit, writes the statement and keeps
+// the provider transaction in one database transaction, the member row stays
+// locked meanwhile. A TransactionId applied before answers the current
+// balance without moving anything.
+func (r repo) ApplySeamlessTransaction(body model.SeamlessTransactionBody) (*model.SeamlessTransactionResult, error) {
+
+	var result model.SeamlessTransactionResult
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+
+		var member model.SeamlessBalance
+		if err := tx.Table("Users").
+			Select("id, username, COALESCE(credit, 0) AS credit, credit_reserved").
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("username = ?", body.Username).
+			Where("deleted_at IS NULL").
+			First(&member).
+			Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrSeamlessPlayerNotFound
+			}
+			return err
+		}
+		result.Username = member.Username
+		result.Balance = member.Credit - member.CreditReserved
+
+		var done []model.SeamlessTransaction
+		if err := tx.Table("Seamless_transactions").
+			Where("provider = ? AND transaction_id = ?", body.Provider, body.TransactionId).
+			Limit(1).
+			Find(&done).
+			Error; err != nil {
+			return err
+		}
+		if len(done) > 0 {
+			if done[0].Action != body.Action || done[0].UserId != member.Id {
+				return ErrSeamlessTransactionMismatch
+			}
+			result.Replayed = true
+			return nil
+		}
+
+		amount := body.Amount
+		var refTransactionId *string
+		if body.RefTransactionId != "" {
+			var refs []model.SeamlessTransaction
+			if err := tx.Table("Seamless_transactions").
+				Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("provider = ? AND transaction_id = ?", body.Provider, body.RefTransactionId).
+				Limit(1).
+				Find(&refs).
+				Error; err != nil {
+				return err
+			}
+			if len(refs) == 0 {
+				return ErrSeamlessTransactionNotFound
+			}
+			ref := refs[0]
+			if ref.UserId != member.Id || (body.Action == "refund" && ref.Action != "bet") {
+				return ErrSeamlessTransactionMismatch
+			}
+			if ref.Status != "applied" {
+				return ErrSeamlessTransactionSettled
+			}
+			// undo exactly what the referenced transaction did
+			amount = -ref.Amount
+			status := "refunded"
+			if body.Action == "rollback" {
+				status = "rolledback"
+			}
+			if err := tx.Table("Seamless_transactions").
+				Where("id = ?", ref.Id).
+				Update("status", status).
+				Error; err != nil {
+				return err
+			}
+			refTransactionId = &ref.TransactionId
+		}
+
+		// a rollback must go through even when the win was already played,
+		// the credit held for pending withdraws cannot be bet
+		if amount < 0 && body.Action != "rollback" && member.Credit-member.CreditReserved+amount < 0 {
+			return ErrSeamlessInsufficientCredit
+		}
+
+		afterBalance := member.Credit + amount
+		if err := tx.Table("Users").
+			Where("id = ?", member.Id).
+			UpdateColumn("credit", afterBalance).
+			Error; err != nil {
+			return err
+		}
+
+		statement := model.MemberStatement{
+			UserId:          member.Id,
+			StatementTypeId: body.StatementTypeId,
+			TransferAt:      time.Now(),
+			Info:            body.Info,
+			BeforeBalance:   member.Credit,
+			Amount:          amount,
+			AfterBalance:    afterBalance,
+		}
+		if err := tx.Table("User_statements").Create(&statement).Error; err != nil {
+			return err
+		}
+		statementCode := "seamless_" + body.Action
+		if err := postLedger(tx, model.LedgerPosting{
+			Code:        statementCode,
+			UserId:      member.Id,
+			StatementId: &statement.Id,
+			Info:        body.Info,
+			Amount:      amount,
+			ContraCode:  ledgerContraCode(statementCode),
+		}); err != nil {
+			return err
+		}
+
+		transaction := model.SeamlessTransaction{
+			Provider:         body.Provider,
+			TransactionId:    body.TransactionId,
+			RoundId:          body.RoundId,
+			RefTransactionId: refTransactionId,
+			UserId:           member.Id,
+			GameCode:         body.GameCode,
+			Action:           body.Action,
+			Amount:           amount,
+			BeforeBalance:    member.Credit,
+			AfterBalance:     afterBalance,
+			Status:           "applied",
+			StatementId:      &statement.Id,
+		}
+		if err := tx.Table("Seamless_transactions").Create(&transaction).Error; err != nil {
+			var dup *mysql.MySQLError
+			if errors.As(err, &dup) && dup.Number == 1062 {
+				return ErrSeamlessTransactionMismatch
+			}
+			return err
+		}
+
+		result.Balance = afterBalance - member.CreditReserved
+		return nil // COMMIT
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,33 @@
+package repository
+
+import "gorm.io/gorm"
+
+func NewUnitOfWork(db *gorm.DB) UnitOfWork {
+	return &repo{db}
+}
+
+// UnitOfWork runs writes that go through several repositories as one database
+// transaction, either all of them are committed or none.
+type UnitOfWork interface {
+	WithinTransaction(fn func(repos UnitOfWorkRepositories) error) error
+}
+
+// UnitOfWorkRepositories are bound to the transaction, they must not be kept
+// after fn returns.
+type UnitOfWorkRepositories struct {
+	Banking    BankingRepository
+	Accounting AccountingRepository
+}
+
+// WithinTransaction commits when fn returns nil, any error from fn rolls the
+// transaction back and is returned as is. Repository methods that open their
+// own transaction run as a savepoint inside this one.
+func (r repo) WithinTransaction(fn func(repos UnitOfWorkRepositories) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		txRepo := &repo{tx}
+		return fn(UnitOfWorkRepositories{
+			Banking:    txRepo,
+			Accounting: txRepo,
+		})
+	})
+}
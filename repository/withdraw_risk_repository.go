@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"cybergame-api/model"
+	"database/sql"
+	"time"
+)
+
+func (r repo) SetBankTransactionStatusDetail(id int64, statusDetail string) error {
+
+	if err := r.db.Table("Bank_transactions").
+		Where("id = ?", id).
+		Update("status_detail", statusDetail).
+		Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r repo) GetWithdrawRiskRules() ([]model.WithdrawRiskRule, error) {
+
+	var list []model.WithdrawRiskRule
+	if err := r.db.Table("Withdraw_risk_rules").
+		Order("id ASC").
+		Find(&list).
+		Error; err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (r repo) GetWithdrawRiskRuleById(id int64) (*model.WithdrawRiskRule, error) {
+
+	var record model.WithdrawRiskRule
+	if err := r.db.Table("Withdraw_risk_rules").
+		Where("id = ?", id).
+		First(&record).
+		Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r repo) UpdateWithdrawRiskRule(id int64, body model.WithdrawRiskRuleUpdateBody) error {
+
+	if err := r.db.Table("Withdraw_risk_rules").
+		Where("id = ?", id).
+		Updates(&body).
+		Error; err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetMemberWithdrawTotalSince sums the withdraws not canceled or removed,
+// exceptId leaves out the withdraw being checked.
+func (r repo) GetMemberWithdrawTotalSince(userId int64, since time.Time, exceptId int64) (model.Money, error) {
+
+	var total model.Money
+	if err := r.db.Table("Bank_transactions").
+		Select("COALESCE(SUM(credit_amount), 0)").
+		Where("user_id = ?", userId).
+		Where("transfer_type = ?", "withdraw").
+		Where("status NOT IN ?", []string{model.BankTransactionCanceled, model.BankTransactionRemoved}).
+		Where("created_at >= ?", since).
+		Where("id != ?", exceptId).
+		Where("deleted_at IS NULL").
+		Row().
+		Scan(&total); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func (r repo) CountMemberFinishedWithdraws(userId int64) (int64, error) {
+
+	var count int64
+	if err := r.db.Table("Bank_transactions").
+		Where("user_id = ?", userId).
+		Where("transfer_type = ?", "withdraw").
+		Where("status = ?", model.BankTransactionFinished).
+		Where("deleted_at IS NULL").
+		Count(&count).
+		Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// GetMemberLastBonusAt is the last bonus credited, a bonus transaction or a
+// deposit with bonus, nil when the member never got one.
+func (r repo) GetMemberLastBonusAt(userId int64) (*time.Time, error) {
+
+	var lastAt sql.NullTime
+	if err := r.db.Table("Bank_transactions").
+		Select("MAX(COALESCE(confirmed_at, created_at))").
+		Where("user_id = ?", userId).
+		Where("transfer_type = ? OR (transfer_type = ? AND bonus_amount > 0)", "bonus", "deposit").
+		Where("status = ?", model.BankTransactionFinished).
+		Where("deleted_at IS NULL").
+		Row().
+		Scan(&lastAt); err != nil {
+		return nil, err
+	}
+	if !lastAt.Valid {
+		return nil, nil
+	}
+	return &lastAt.Time, nil
+}
+
+func (r repo) IsMemberScammer(bankAccount string, phone string) (bool, error) {
+
+	if bankAccount == "" && phone == "" {
+		return false, nil
+	}
+
+	var count int64
+	query := r.db.Table("Scammers")
+	if bankAccount != "" && phone != "" {
+		query = query.Where("bank_account = ? OR phone = ?", bankAccount, phone)
+	} else if bankAccount != "" {
+		query = query.Where("bank_account = ?", bankAccount)
+	} else {
+		query = query.Where("phone = ?", phone)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// GetMemberBankAccountSince is when the member bank account was last changed
+// by an admin, or the register time when it never was.
+func (r repo) GetMemberBankAccountSince(userId int64) (*time.Time, error) {
+
+	var changedAt sql.NullTime
+	if err := r.db.Table("User_update_logs").
+		Select("MAX(created_at)").
+		Where("user_id = ?", userId).
+		Where("description LIKE ?", "BankAccount changed%").
+		Row().
+		Scan(&changedAt); err != nil {
+		return nil, err
+	}
+	if changedAt.Valid {
+		return &changedAt.Time, nil
+	}
+
+	var createdAt sql.NullTime
+	if err := r.db.Table("Users").
+		Select("created_at").
+		Where("id = ?", userId).
+		Row().
+		Scan(&createdAt); err != nil {
+		return nil, err
+	}
+	if !createdAt.Valid {
+		return nil, nil
+	}
+	return &createdAt.Time, nil
+}
@@ -0,0 +1,461 @@
+package service
+
+import (
+	"context"
+	"cybergame-api/agent"
+	"cybergame-api/helper"
+	"cybergame-api/model"
+	"cybergame-api/repository"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+const AgentCallNotFound = "ไม่พบรายการเรียกเอเย่นต์"
+const AgentCallInProgress = "รายการนี้กำลังส่งไปที่เอเย่นต์ กรุณารอสักครู่"
+const AgentCallMismatch = "เลขที่รายการนี้ถูกใช้กับรายการเอเย่นต์อื่นแล้ว"
+const AgentCallCompleted = "รายการนี้ส่งไปที่เอเย่นต์สำเร็จแล้ว"
+const AgentCallRefClosed = "รายการถอนนี้ไม่ได้รอยืนยันเครดิตแล้ว ไม่สามารถส่งไปที่เอเย่นต์ได้"
+
+var errAgentCallRefClosed = badRequest(AgentCallRefClosed)
+
+const (
+	agentCallDeposit  = "deposit"
+	agentCallWithdraw = "withdraw"
+)
+
+const (
+	agentCallRunning = "running"
+	agentCallRetry   = "retry"
+	agentCallSuccess = "success"
+	agentCallFailed  = "failed"
+)
+
+// a running call older than its lease is taken over by the retrier
+const agentCallLease = 2 * time.Minute
+
+const agentCallMaxAttempts = 8
+const agentCallFirstBackoff = 30 * time.Second
+const agentCallMaxBackoff = 30 * time.Minute
+const agentCallBatchSize = 50
+
+type AgentCallService interface {
+	Deposit(ctx context.Context, userId *int64, refId *int64, data model.AGCDeposit) (*model.AGCTransferResponse, error)
+	Withdraw(ctx context.Context, userId *int64, refId *int64, data model.AGCWithdraw) (*model.AGCTransferResponse, error)
+	GetAgentCallJournals(req model.AgentCallJournalListRequest) (*model.SuccessWithPagination, error)
+	GetAgentCallJournalById(id int64) (*model.AgentCallJournal, error)
+	AgentCallExists(transactionId string) (bool, error)
+	RetryAgentCall(ctx context.Context, id int64) error
+	RetryDueAgentCalls(ctx context.Context) (*model.AgentCallRetryResult, error)
+	StartAgentCallRetrier(interval time.Duration)
+}
+
+type agentCallService struct {
+	repo             repository.AgentCallRepository
+	repoAgentConnect repository.AgentConnectRepository
+	retrying         *int32
+}
+
+func NewAgentCallService(
+	repo repository.AgentCallRepository,
+	repoAgentConnect repository.AgentConnectRepository,
+) AgentCallService {
+	return &agentCallService{repo, repoAgentConnect, new(int32)}
+}
+
+// Deposit credits the agent wallet once per TransactionId. A call that ended
+// without a clear answer stays in the journal and the retrier sends it again
+// with the same TransactionId, check isRetryableAgentError on the error.
+func (s *agentCallService) Deposit(ctx context.Context, userId *int64, refId *int64, data model.AGCDeposit) (*model.AGCTransferResponse, error) {
+
+	journal := model.AgentCallJournal{
+		TransactionId: data.TransactionId,
+		Action:        agentCallDeposit,
+		Path:          "/credit-transfer/deposit",
+		UserId:        userId,
+		RefId:         refId,
+		PlayerName:    data.PlayerName,
+		Amount:        model.NewMoney(data.Amount),
+	}
+	return s.call(ctx, journal, data)
+}
+
+// Withdraw debits the agent wallet once per TransactionId, see Deposit.
+func (s *agentCallService) Withdraw(ctx context.Context, userId *int64, refId *int64, data model.AGCWithdraw) (*model.AGCTransferResponse, error) {
+
+	journal := model.AgentCallJournal{
+		TransactionId: data.TransactionId,
+		Action:        agentCallWithdraw,
+		Path:          "/credit-transfer/withdraw",
+		UserId:        userId,
+		RefId:         refId,
+		PlayerName:    data.PlayerName,
+		Amount:        model.NewMoney(data.Amount),
+	}
+	return s.call(ctx, journal, data)
+}
+
+func (s *agentCallService) GetAgentCallJournals(req model.AgentCallJournalListRequest) (*model.SuccessWithPagination, error) {
+
+	if err := helper.Pagination(&req.Page, &req.Limit); err != nil {
+		return nil, badRequest(err.Error())
+	}
+
+	list, err := s.repo.GetAgentCallJournals(req)
+	if err != nil {
+		return nil, internalServerError(err.Error())
+	}
+
+	return list, nil
+}
+
+func (s *agentCallService) GetAgentCallJournalById(id int64) (*model.AgentCallJournal, error) {
+
+	journal, err := s.repo.GetAgentCallJournalById(id)
+	if err != nil {
+		if err.Error() == recordNotFound {
+			return nil, notFound(AgentCallNotFound)
+		}
+		return nil, internalServerError(err.Error())
+	}
+
+	return journal, nil
+}
+
+// AgentCallExists tells whether a call with this TransactionId was journaled,
+// whatever its status.
+func (s *agentCallService) AgentCallExists(transactionId string) (bool, error) {
+
+	if _, err := s.repo.GetAgentCallJournalByTransactionId(transactionId); err != nil {
+		if err.Error() == recordNotFound {
+			return false, nil
+		}
+		return false, internalServerError(err.Error())
+	}
+	return true, nil
+}
+
+// RetryAgentCall sends a waiting or failed call now, for an admin who fixed
+// what the agent refused.
+func (s *agentCallService) RetryAgentCall(ctx context.Context, id int64) error {
+
+	journal, err := s.GetAgentCallJournalById(id)
+	if err != nil {
+		return err
+	}
+	if journal.Status == agentCallSuccess {
+		return badRequest(AgentCallCompleted)
+	}
+	if err := s.checkAgentCallRef(*journal); err != nil {
+		return err
+	}
+
+	if _, err := s.resend(ctx, *journal); err != nil {
+		return agentError(err)
+	}
+
+	return nil
+}
+
+// RetryDueAgentCalls sends again every call whose backoff is over.
+func (s *agentCallService) RetryDueAgentCalls(ctx context.Context) (*model.AgentCallRetryResult, error) {
+
+	list, err := s.repo.GetDueAgentCallJournals(time.Now(), agentCallBatchSize)
+	if err != nil {
+		return nil, internalServerError(err.Error())
+	}
+
+	var result model.AgentCallRetryResult
+	for _, journal := range list {
+		result.Total++
+		if err := s.checkAgentCallRef(journal); err != nil {
+			if err == errAgentCallRefClosed {
+				s.closeAgentCall(journal, err)
+				result.Failed++
+			} else {
+				// the transaction could not be read, try again on the next run
+				log.Printf("agent call %s retry: %s", journal.TransactionId, err.Error())
+				result.Retrying++
+			}
+			continue
+		}
+		_, err := s.resend(ctx, journal)
+		switch {
+		case err == nil:
+			result.Succeeded++
+		case isRetryableAgentError(err):
+			result.Retrying++
+		case err == errAgentCallRefClosed:
+			// canceled between the check and the claim
+			s.closeAgentCall(journal, err)
+			result.Failed++
+		default:
+			result.Failed++
+		}
+		if err != nil {
+			log.Printf("agent call %s retry: %s", journal.TransactionId, err.Error())
+		}
+	}
+
+	return &result, nil
+}
+
+// StartAgentCallRetrier runs RetryDueAgentCalls every interval in the
+// background, one run at a time.
+func (s *agentCallService) StartAgentCallRetrier(interval time.Duration) {
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if !atomic.CompareAndSwapInt32(s.retrying, 0, 1) {
+				continue
+			}
+			if _, err := s.RetryDueAgentCalls(context.Background()); err != nil {
+				log.Println("agent call retrier:", err.Error())
+			}
+			atomic.StoreInt32(s.retrying, 0)
+		}
+	}()
+}
+
+// checkAgentCallRef stops a withdraw from being sent again once its bank
+// transaction left pending_agent, the agent must not take money for a
+// withdraw an admin canceled meanwhile. Deposits only ever give the member
+// money and are always sent again.
+func (s *agentCallService) checkAgentCallRef(journal model.AgentCallJournal) error {
+
+	if journal.Action != agentCallWithdraw {
+		return nil
+	}
+	if journal.RefId == nil {
+		return errAgentCallRefClosed
+	}
+
+	transaction, err := s.repo.GetAgentCallBankTransaction(*journal.RefId)
+	if err != nil {
+		if err.Error() == recordNotFound {
+			return errAgentCallRefClosed
+		}
+		return internalServerError(err.Error())
+	}
+	for _, status := range model.AgentDebitStatuses(transaction.TransferType) {
+		if transaction.Status == status {
+			return nil
+		}
+	}
+	return errAgentCallRefClosed
+}
+
+// closeAgentCall fails a call the retrier will not send again.
+func (s *agentCallService) closeAgentCall(journal model.AgentCallJournal, reason error) {
+
+	completedAt := time.Now()
+	lastError := reason.Error()
+	result := model.AgentCallResultBody{
+		Status:       agentCallFailed,
+		Sign:         journal.Sign,
+		RequestBody:  journal.RequestBody,
+		ResponseBody: journal.ResponseBody,
+		LastError:    &lastError,
+		CompletedAt:  &completedAt,
+	}
+	if err := s.repo.UpdateAgentCallJournal(journal.Id, result); err != nil {
+		log.Printf("agent call %s journal: %s", journal.TransactionId, err.Error())
+	}
+	log.Printf("agent call %s retry stopped: %s", journal.TransactionId, lastError)
+}
+
+func (s *agentCallService) call(ctx context.Context, journal model.AgentCallJournal, data interface{}) (*model.AGCTransferResponse, error) {
+
+	requestBody, err := json.Marshal(data)
+	if err != nil {
+		return nil, internalServerError(err.Error())
+	}
+
+	now := time.Now()
+	leaseUntil := now.Add(agentCallLease)
+	journal.RequestBody = string(requestBody)
+	journal.NextRetryAt = &leaseUntil
+	// a withdraw is only ever sent through the claim, which checks in the same
+	// statement that its bank transaction still waits for the debit
+	if journal.Action == agentCallWithdraw {
+		journal.Status = agentCallRetry
+	} else {
+		journal.Status = agentCallRunning
+		journal.AttemptCount = 1
+	}
+
+	id, err := s.repo.CreateAgentCallJournal(journal)
+	if err == nil {
+		journal.Id = *id
+		if journal.Action == agentCallWithdraw {
+			return s.resend(ctx, journal)
+		}
+		return s.send(ctx, journal)
+	}
+	if !errors.Is(err, repository.ErrAgentCallExists) {
+		return nil, internalServerError(err.Error())
+	}
+
+	// the TransactionId was sent before, answer from the journal
+	existing, err := s.repo.GetAgentCallJournalByTransactionId(journal.TransactionId)
+	if err != nil {
+		return nil, internalServerError(err.Error())
+	}
+	if existing.Action != journal.Action || existing.PlayerName != journal.PlayerName || existing.Amount != journal.Amount {
+		return nil, badRequest(AgentCallMismatch)
+	}
+	if existing.Status == agentCallSuccess {
+		return replayAgentCall(*existing)
+	}
+	return s.resend(ctx, *existing)
+}
+
+// resend claims the journal row then calls the agent again with the stored
+// request, signed with a fresh timestamp.
+func (s *agentCallService) resend(ctx context.Context, journal model.AgentCallJournal) (*model.AGCTransferResponse, error) {
+
+	now := time.Now()
+	claimed, err := s.repo.ClaimAgentCallJournal(journal.Id, now, now.Add(agentCallLease))
+	if err != nil {
+		return nil, internalServerError(err.Error())
+	}
+	if !claimed {
+		// someone else is calling, or just finished, or the withdraw was closed
+		current, err := s.repo.GetAgentCallJournalById(journal.Id)
+		if err != nil {
+			return nil, internalServerError(err.Error())
+		}
+		if current.Status == agentCallSuccess {
+			return replayAgentCall(*current)
+		}
+		if err := s.checkAgentCallRef(*current); err != nil {
+			return nil, err
+		}
+		return nil, ResponseError{Code: http.StatusConflict, Message: AgentCallInProgress}
+	}
+
+	journal.AttemptCount++
+	return s.send(ctx, journal)
+}
+
+func (s *agentCallService) send(ctx context.Context, journal model.AgentCallJournal) (*model.AGCTransferResponse, error) {
+
+	var response *model.AGCTransferResponse
+	var sign string
+	var requestBody []byte
+	var err error
+
+	// signed here so the journal keeps the sign the transfer wallet sent
+	wallet := s.repoAgentConnect.AgentWallet()
+	timeNow := time.Now()
+	switch journal.Action {
+	case agentCallDeposit:
+		var data model.AGCDeposit
+		if err := json.Unmarshal([]byte(journal.RequestBody), &data); err != nil {
+			return nil, internalServerError(err.Error())
+		}
+		data.Agentname = wallet.AgentName()
+		data.Timestamp = timeNow.Unix()
+		data.Sign = wallet.Sign(data.Agentname+data.PlayerName, timeNow)
+		sign = data.Sign
+		requestBody, _ = json.Marshal(data)
+		response, err = s.repoAgentConnect.Deposit(ctx, data)
+	case agentCallWithdraw:
+		var data model.AGCWithdraw
+		if err := json.Unmarshal([]byte(journal.RequestBody), &data); err != nil {
+			return nil, internalServerError(err.Error())
+		}
+		data.Agentname = wallet.AgentName()
+		data.TimeStamp = timeNow.Unix()
+		data.Sign = wallet.Sign(data.Agentname+data.PlayerName, timeNow)
+		sign = data.Sign
+		requestBody, _ = json.Marshal(data)
+		response, err = s.repoAgentConnect.Withdraw(ctx, data)
+	default:
+		return nil, internalServerError("Unknown agent call " + journal.Action)
+	}
+
+	result := model.AgentCallResultBody{
+		Sign:        sign,
+		RequestBody: string(requestBody),
+	}
+	completedAt := time.Now()
+	if err == nil {
+		responseBody, _ := json.Marshal(response)
+		responseText := string(responseBody)
+		result.Status = agentCallSuccess
+		result.ResponseBody = &responseText
+		result.CompletedAt = &completedAt
+	} else {
+		lastError := err.Error()
+		if len(lastError) > 255 {
+			lastError = lastError[:255]
+		}
+		result.LastError = &lastError
+		if isRetryableAgentError(err) && journal.AttemptCount < agentCallMaxAttempts {
+			nextRetryAt := completedAt.Add(agentCallBackoff(journal.AttemptCount))
+			result.Status = agentCallRetry
+			result.NextRetryAt = &nextRetryAt
+		} else {
+			result.Status = agentCallFailed
+			result.CompletedAt = &completedAt
+		}
+	}
+
+	if updateErr := s.repo.UpdateAgentCallJournal(journal.Id, result); updateErr != nil {
+		// the row stays running and the retrier replays it after the lease
+		log.Printf("agent call %s journal: %s", journal.TransactionId, updateErr.Error())
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func replayAgentCall(journal model.AgentCallJournal) (*model.AGCTransferResponse, error) {
+
+	var response model.AGCTransferResponse
+	if journal.ResponseBody != nil {
+		if err := json.Unmarshal([]byte(*journal.ResponseBody), &response); err != nil {
+			return nil, internalServerError(err.Error())
+		}
+	}
+	return &response, nil
+}
+
+// agentCallBackoff doubles the wait after each failed attempt.
+func agentCallBackoff(attempt int) time.Duration {
+
+	wait := agentCallFirstBackoff
+	for i := 1; i < attempt && wait < agentCallMaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > agentCallMaxBackoff {
+		wait = agentCallMaxBackoff
+	}
+	return wait
+}
+
+// isRetryableAgentError tells whether the agent may or may not have applied
+// the call, sending the same TransactionId again settles it.
+func isRetryableAgentError(err error) bool {
+
+	agentErr, ok := agent.AsError(err)
+	if !ok {
+		return false
+	}
+
+	switch agentErr.Kind {
+	case agent.KindTimeout, agent.KindCanceled, agent.KindUnavailable:
+		return true
+	case agent.KindHttp:
+		return agentErr.Status >= 500 || agentErr.Status == http.StatusTooManyRequests || agentErr.Status == http.StatusRequestTimeout
+	}
+	return false
+}
@@ -0,0 +1,458 @@
+package service
+
+import (
+	"context"
+	"cybergame-api/agent"
+	"cybergame-api/agent/fake"
+	"cybergame-api/model"
+	"cybergame-api/repository"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const testAgentName = "testagent"
+const testAgentKey = "testkey"
+
+// startFakeAgent points the site wallet at an in memory agent for the test.
+func startFakeAgent(t *testing.T) (*fake.Server, repository.AgentConnectRepository) {
+
+	server := fake.NewServer(testAgentName, testAgentKey)
+	httpServer := server.Start()
+	t.Cleanup(httpServer.Close)
+
+	agent.Register(agent.NewClient(agent.MainWallet, httpServer.URL, testAgentName, agent.SHA256Signer{Key: testAgentKey}, time.Second))
+	return server, repository.NewAgentConnectRepository(nil)
+}
+
+// memoryAgentCallRepository keeps the journal the way the Agent_call_journals
+// table does, one row per TransactionId.
+type memoryAgentCallRepository struct {
+	mutex    sync.Mutex
+	journals []model.AgentCallJournal
+	// bankTransactions answers GetAgentCallBankTransaction, unless banking is set
+	bankTransactions map[int64]model.BankTransaction
+	banking          repository.BankingRepository
+}
+
+func (r *memoryAgentCallRepository) GetAgentCallJournals(req model.AgentCallJournalListRequest) (*model.SuccessWithPagination, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	list := append([]model.AgentCallJournal{}, r.journals...)
+	return &model.SuccessWithPagination{List: list, Total: int64(len(list))}, nil
+}
+
+func (r *memoryAgentCallRepository) GetAgentCallJournalById(id int64) (*model.AgentCallJournal, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, journal := range r.journals {
+		if journal.Id == id {
+			return &journal, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryAgentCallRepository) GetAgentCallJournalByTransactionId(transactionId string) (*model.AgentCallJournal, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, journal := range r.journals {
+		if journal.TransactionId == transactionId {
+			return &journal, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryAgentCallRepository) GetDueAgentCallJournals(now time.Time, limit int) ([]model.AgentCallJournal, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	var list []model.AgentCallJournal
+	for _, journal := range r.journals {
+		if (journal.Status == agentCallRetry || journal.Status == agentCallRunning) && journal.NextRetryAt != nil && !journal.NextRetryAt.After(now) && len(list) < limit {
+			list = append(list, journal)
+		}
+	}
+	return list, nil
+}
+
+func (r *memoryAgentCallRepository) CreateAgentCallJournal(body model.AgentCallJournal) (*int64, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for _, journal := range r.journals {
+		if journal.TransactionId == body.TransactionId {
+			return nil, repository.ErrAgentCallExists
+		}
+	}
+	body.Id = int64(len(r.journals) + 1)
+	body.CreatedAt = time.Now()
+	r.journals = append(r.journals, body)
+	return &body.Id, nil
+}
+
+func (r *memoryAgentCallRepository) ClaimAgentCallJournal(id int64, now time.Time, leaseUntil time.Time) (bool, error) {
+	journal, err := r.GetAgentCallJournalById(id)
+	if err != nil || journal.Action == agentCallWithdraw && !r.refOpen(journal.RefId) {
+		return false, nil
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for i := range r.journals {
+		journal := &r.journals[i]
+		if journal.Id != id {
+			continue
+		}
+		expired := journal.Status == agentCallRunning && journal.NextRetryAt != nil && !journal.NextRetryAt.After(now)
+		if journal.Status != agentCallRetry && journal.Status != agentCallFailed && !expired {
+			return false, nil
+		}
+		journal.Status = agentCallRunning
+		journal.AttemptCount++
+		journal.NextRetryAt = &leaseUntil
+		return true, nil
+	}
+	return false, nil
+}
+
+func (r *memoryAgentCallRepository) UpdateAgentCallJournal(id int64, body model.AgentCallResultBody) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for i := range r.journals {
+		journal := &r.journals[i]
+		if journal.Id == id {
+			journal.Status = body.Status
+			journal.Sign = body.Sign
+			journal.RequestBody = body.RequestBody
+			journal.ResponseBody = body.ResponseBody
+			journal.LastError = body.LastError
+			journal.NextRetryAt = body.NextRetryAt
+			journal.CompletedAt = body.CompletedAt
+		}
+	}
+	return nil
+}
+
+func (r *memoryAgentCallRepository) GetAgentCallBankTransaction(id int64) (*model.BankTransaction, error) {
+	if r.banking != nil {
+		return r.banking.GetBankTransactionById(id)
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	transaction, ok := r.bankTransactions[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &transaction, nil
+}
+
+// refOpen is the bank transaction check ClaimAgentCallJournal makes in SQL.
+func (r *memoryAgentCallRepository) refOpen(refId *int64) bool {
+	if refId == nil {
+		return false
+	}
+	transaction, err := r.GetAgentCallBankTransaction(*refId)
+	if err != nil {
+		return false
+	}
+	for _, status := range model.AgentDebitStatuses(transaction.TransferType) {
+		if transaction.Status == status {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memoryAgentCallRepository) setBankTransactionStatus(id int64, status string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.bankTransactions == nil {
+		r.bankTransactions = make(map[int64]model.BankTransaction)
+	}
+	r.bankTransactions[id] = model.BankTransaction{Id: id, TransferType: "withdraw", Status: status}
+}
+
+// makeDue ends the backoff of every waiting call.
+func (r *memoryAgentCallRepository) makeDue() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	past := time.Now().Add(-time.Second)
+	for i := range r.journals {
+		if r.journals[i].NextRetryAt != nil {
+			r.journals[i].NextRetryAt = &past
+		}
+	}
+}
+
+func (r *memoryAgentCallRepository) journal(t *testing.T, transactionId string) model.AgentCallJournal {
+	journal, err := r.GetAgentCallJournalByTransactionId(transactionId)
+	if err != nil {
+		t.Fatalf("journal %s: %s", transactionId, err.Error())
+	}
+	return *journal
+}
+
+func playerBalance(t *testing.T, server *fake.Server, username string) float64 {
+	player, ok := server.Player(username)
+	if !ok {
+		t.Fatalf("player %s not found", username)
+	}
+	return player.Balance
+}
+
+func responseCode(err error) int {
+	if responseErr, ok := err.(ResponseError); ok {
+		return responseErr.Code
+	}
+	return 0
+}
+
+func TestAgentCallReplay(t *testing.T) {
+
+	server, connect := startFakeAgent(t)
+	server.AddPlayer("member1", "secret", 0)
+	journals := &memoryAgentCallRepository{}
+	service := NewAgentCallService(journals, connect)
+
+	data := model.AGCDeposit{PlayerName: "member1", Amount: 100, TransactionId: "D1"}
+	first, err := service.Deposit(context.Background(), nil, nil, data)
+	if err != nil {
+		t.Fatalf("deposit: %s", err.Error())
+	}
+	second, err := service.Deposit(context.Background(), nil, nil, data)
+	if err != nil {
+		t.Fatalf("deposit again: %s", err.Error())
+	}
+
+	if calls := server.Calls(fake.PathDeposit); calls != 1 {
+		t.Fatalf("agent got %d deposits, want 1", calls)
+	}
+	if second.Data.Balance != first.Data.Balance || second.Data.TransactionId != "D1" {
+		t.Fatalf("replay answered %+v, want %+v", second.Data, first.Data)
+	}
+	if balance := playerBalance(t, server, "member1"); balance != 100 {
+		t.Fatalf("balance %v, want 100", balance)
+	}
+	if journal := journals.journal(t, "D1"); journal.Status != agentCallSuccess || journal.AttemptCount != 1 {
+		t.Fatalf("journal %s after %d attempts, want success after 1", journal.Status, journal.AttemptCount)
+	}
+}
+
+func TestAgentCallMismatch(t *testing.T) {
+
+	server, connect := startFakeAgent(t)
+	server.AddPlayer("member1", "secret", 0)
+	service := NewAgentCallService(&memoryAgentCallRepository{}, connect)
+
+	if _, err := service.Deposit(context.Background(), nil, nil, model.AGCDeposit{PlayerName: "member1", Amount: 100, TransactionId: "T1"}); err != nil {
+		t.Fatalf("deposit: %s", err.Error())
+	}
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"amount", func() error {
+			_, err := service.Deposit(context.Background(), nil, nil, model.AGCDeposit{PlayerName: "member1", Amount: 50, TransactionId: "T1"})
+			return err
+		}},
+		{"player", func() error {
+			_, err := service.Deposit(context.Background(), nil, nil, model.AGCDeposit{PlayerName: "member2", Amount: 100, TransactionId: "T1"})
+			return err
+		}},
+		{"action", func() error {
+			_, err := service.Withdraw(context.Background(), nil, nil, model.AGCWithdraw{PlayerName: "member1", Amount: 100, TransactionId: "T1"})
+			return err
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.call()
+			if responseCode(err) != http.StatusBadRequest || err.Error() != AgentCallMismatch {
+				t.Fatalf("got %v, want 400 %s", err, AgentCallMismatch)
+			}
+		})
+	}
+
+	if calls := server.Calls(fake.PathDeposit) + server.Calls(fake.PathWithdraw); calls != 1 {
+		t.Fatalf("agent got %d transfers, want 1", calls)
+	}
+	if balance := playerBalance(t, server, "member1"); balance != 100 {
+		t.Fatalf("balance %v, want 100", balance)
+	}
+}
+
+func TestAgentCallRetry(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		failure fake.Failure
+	}{
+		{"bad gateway", fake.Failure{Status: http.StatusBadGateway}},
+		{"applied then bad gateway", fake.Failure{Status: http.StatusBadGateway, Apply: true}},
+		{"timeout", fake.Failure{Delay: 2 * time.Second}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			server, connect := startFakeAgent(t)
+			server.AddPlayer("member1", "secret", 300)
+			server.InjectFailure(fake.PathWithdraw, test.failure)
+			journals := &memoryAgentCallRepository{}
+			journals.setBankTransactionStatus(7, model.BankTransactionPendingAgent)
+			service := NewAgentCallService(journals, connect)
+
+			refId := int64(7)
+			_, err := service.Withdraw(context.Background(), nil, &refId, model.AGCWithdraw{PlayerName: "member1", Amount: 100, TransactionId: "W1"})
+			if err == nil || !isRetryableAgentError(err) {
+				t.Fatalf("got %v, want a retryable agent error", err)
+			}
+			journal := journals.journal(t, "W1")
+			if journal.Status != agentCallRetry || journal.NextRetryAt == nil || !journal.NextRetryAt.After(time.Now()) {
+				t.Fatalf("journal %s next at %v, want retry later", journal.Status, journal.NextRetryAt)
+			}
+
+			// nothing is due before the backoff ends
+			result, err := service.RetryDueAgentCalls(context.Background())
+			if err != nil || result.Total != 0 {
+				t.Fatalf("retry before backoff: %+v %v, want nothing due", result, err)
+			}
+
+			journals.makeDue()
+			result, err = service.RetryDueAgentCalls(context.Background())
+			if err != nil {
+				t.Fatalf("retry: %s", err.Error())
+			}
+			if result.Total != 1 || result.Succeeded != 1 {
+				t.Fatalf("retry result %+v, want 1 succeeded", result)
+			}
+
+			if balance := playerBalance(t, server, "member1"); balance != 200 {
+				t.Fatalf("balance %v, want 200 debited once", balance)
+			}
+			journal = journals.journal(t, "W1")
+			if journal.Status != agentCallSuccess || journal.AttemptCount != 2 {
+				t.Fatalf("journal %s after %d attempts, want success after 2", journal.Status, journal.AttemptCount)
+			}
+		})
+	}
+}
+
+func TestAgentCallRefusedIsNotRetried(t *testing.T) {
+
+	server, connect := startFakeAgent(t)
+	server.AddPlayer("member1", "secret", 50)
+	journals := &memoryAgentCallRepository{}
+	journals.setBankTransactionStatus(7, model.BankTransactionPendingAgent)
+	service := NewAgentCallService(journals, connect)
+
+	refId := int64(7)
+	_, err := service.Withdraw(context.Background(), nil, &refId, model.AGCWithdraw{PlayerName: "member1", Amount: 100, TransactionId: "W1"})
+	if agentErr, ok := agent.AsError(err); !ok || agentErr.Kind != agent.KindRefused {
+		t.Fatalf("got %v, want a refusal", err)
+	}
+	if journal := journals.journal(t, "W1"); journal.Status != agentCallFailed {
+		t.Fatalf("journal %s, want failed", journal.Status)
+	}
+
+	journals.makeDue()
+	if result, _ := service.RetryDueAgentCalls(context.Background()); result.Total != 0 {
+		t.Fatalf("retrier picked %d failed calls, want 0", result.Total)
+	}
+}
+
+func TestAgentCallRetryStopsForClosedWithdraw(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		status string
+	}{
+		{"canceled", model.BankTransactionCanceled},
+		{"removed", model.BankTransactionRemoved},
+		{"missing", ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			server, connect := startFakeAgent(t)
+			server.AddPlayer("member1", "secret", 300)
+			server.InjectFailure(fake.PathWithdraw, fake.Failure{Status: http.StatusGatewayTimeout})
+			journals := &memoryAgentCallRepository{}
+			journals.setBankTransactionStatus(7, model.BankTransactionPendingAgent)
+			service := NewAgentCallService(journals, connect)
+
+			refId := int64(7)
+			if _, err := service.Withdraw(context.Background(), nil, &refId, model.AGCWithdraw{PlayerName: "member1", Amount: 100, TransactionId: "7"}); !isRetryableAgentError(err) {
+				t.Fatalf("got %v, want a retryable agent error", err)
+			}
+
+			// an admin cancels the withdraw before the retrier runs
+			if test.status == "" {
+				journals.bankTransactions = nil
+			} else {
+				journals.setBankTransactionStatus(7, test.status)
+			}
+			journals.makeDue()
+
+			result, err := service.RetryDueAgentCalls(context.Background())
+			if err != nil {
+				t.Fatalf("retry: %s", err.Error())
+			}
+			if result.Total != 1 || result.Failed != 1 {
+				t.Fatalf("retry result %+v, want 1 failed", result)
+			}
+			if calls := server.Calls(fake.PathWithdraw); calls != 1 {
+				t.Fatalf("agent got %d withdraws, want 1", calls)
+			}
+			if balance := playerBalance(t, server, "member1"); balance != 300 {
+				t.Fatalf("balance %v, want 300", balance)
+			}
+			journal := journals.journal(t, "7")
+			if journal.Status != agentCallFailed {
+				t.Fatalf("journal %s, want failed", journal.Status)
+			}
+
+			// nor can an admin send it
+			if err := service.RetryAgentCall(context.Background(), journal.Id); responseCode(err) != http.StatusBadRequest {
+				t.Fatalf("admin retry: got %v, want 400", err)
+			}
+		})
+	}
+}
+
+// TestAgentCallWithdrawForClosedTransaction is a withdraw whose transaction
+// was canceled before the call was claimed, nothing reaches the agent.
+func TestAgentCallWithdrawForClosedTransaction(t *testing.T) {
+
+	server, connect := startFakeAgent(t)
+	server.AddPlayer("member1", "secret", 300)
+	journals := &memoryAgentCallRepository{}
+	journals.setBankTransactionStatus(7, model.BankTransactionCanceled)
+	service := NewAgentCallService(journals, connect)
+
+	refId := int64(7)
+	_, err := service.Withdraw(context.Background(), nil, &refId, model.AGCWithdraw{PlayerName: "member1", Amount: 100, TransactionId: "7"})
+	if err != errAgentCallRefClosed {
+		t.Fatalf("got %v, want %s", err, AgentCallRefClosed)
+	}
+	if calls := server.Calls(fake.PathWithdraw); calls != 0 {
+		t.Fatalf("agent got %d withdraws, want 0", calls)
+	}
+
+	// the retrier closes the journal left behind
+	journals.makeDue()
+	if result, _ := service.RetryDueAgentCalls(context.Background()); result.Failed != 1 {
+		t.Fatalf("retry result %+v, want 1 failed", result)
+	}
+	if journal := journals.journal(t, "7"); journal.Status != agentCallFailed {
+		t.Fatalf("journal %s, want failed", journal.Status)
+	}
+	if calls := server.Calls(fake.PathWithdraw); calls != 0 {
+		t.Fatalf("agent got %d withdraws, want 0", calls)
+	}
+}
@@ -12,7 +12,6 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"strconv"
 	"strings"
 	"time"
 )
@@ -71,6 +70,7 @@ type AccountingService interface {
 
 type accountingService struct {
 	repo repository.AccountingRepository
+	uow  repository.UnitOfWork
 }
 
 var invalidConfirmation = "Invalid confirmation password"
@@ -84,8 +84,19 @@ var transferNotFound = "Transfer not found"
 
 func NewAccountingService(
 	repo repository.AccountingRepository,
+	uow repository.UnitOfWork,
 ) AccountingService {
-	return &accountingService{repo}
+	return &accountingService{repo, uow}
+}
+
+// inTransaction runs fn on a copy of the service whose repository is bound to
+// one database transaction.
+func (s *accountingService) inTransaction(fn func(tx *accountingService) error) error {
+	return s.uow.WithinTransaction(func(repos repository.UnitOfWorkRepositories) error {
+		tx := *s
+		tx.repo = repos.Accounting
+		return fn(&tx)
+	})
 }
 
 func (s *accountingService) CheckCurrentAdminId(input any) (*int64, error) {
@@ -529,7 +540,7 @@ func (s *accountingService) UpdateBankAccountBalanceById(id int64) error {
 			return internalServerError(err.Error())
 		}
 		if balaceResp.AccountNo == account.AccountNumber {
-			balance, _ := strconv.ParseFloat(strings.TrimSpace(balaceResp.AccountBalance), 64)
+			balance, _ := model.ParseMoney(balaceResp.AccountBalance)
 			updateData.AccountBalance = &balance
 		} else {
 			fmt.Println("ERROR, balaceResp: ", balaceResp)
@@ -590,7 +601,7 @@ func (s *accountingService) UpdateAllBankAccountBotStatus() error {
 			// fmt.Println("ERROR", err.Error())
 		} else {
 			if balaceResp.AccountNo == account.AccountNumber {
-				balance, _ := strconv.ParseFloat(strings.TrimSpace(balaceResp.AccountBalance), 64)
+				balance, _ := model.ParseMoney(balaceResp.AccountBalance)
 				data.AccountBalance = &balance
 			} else {
 				data.LastConnUpdateAt = &errorMinDelay
@@ -1065,11 +1076,12 @@ func (s *accountingService) GetExternalAccountBalance(query model.ExternalAccoun
 	reqExternal, _ := http.NewRequest("GET", os.Getenv("ACCOUNTING_API_ENDPOINT")+"/api/v2/statement/balance?accountNo="+query.AccountNumber, nil)
 	reqExternal.Header.Set("apiKey", os.Getenv("ACCOUNTING_API_KEY"))
 	response, err := client.Do(reqExternal)
-
+	// the auto withdraw asks the balance too, a bank API down must not stop the server
 	if err != nil {
-		fmt.Print(err.Error())
-		os.Exit(1)
+		log.Println(err.Error())
+		return nil, internalServerError("Error from external API")
 	}
+	defer response.Body.Close()
 
 	if response.StatusCode != 200 {
 		fmt.Println(response)
@@ -1077,7 +1089,8 @@ func (s *accountingService) GetExternalAccountBalance(query model.ExternalAccoun
 	}
 	responseData, err := io.ReadAll(response.Body)
 	if err != nil {
-		log.Fatal(err)
+		log.Println(err.Error())
+		return nil, internalServerError("Error from external API")
 	}
 	var result model.ExternalAccountBalance
 	errJson := json.Unmarshal(responseData, &result)
@@ -1085,8 +1098,9 @@ func (s *accountingService) GetExternalAccountBalance(query model.ExternalAccoun
 		return nil, internalServerError("Error from JSON response")
 	}
 	if result.AccountNo != query.AccountNumber {
-		_, err := s.CreateWebhookLog("GetExternalAccountBalance, ERROR:", string(responseData))
-		log.Panicln(err)
+		if _, err := s.CreateWebhookLog("GetExternalAccountBalance, ERROR:", string(responseData)); err != nil {
+			log.Println(err)
+		}
 		return nil, notFound("Bank account not found")
 	}
 	return &result, nil
@@ -1886,16 +1900,17 @@ func (s *accountingService) ConfirmDepositTransaction(id int64, req model.BankCo
 	if err != nil {
 		return internalServerError(err.Error())
 	}
-	if record.Status != "pending" {
-		return badRequest("Transaction is not pending")
-	}
 	if record.TransferType != "deposit" && record.TransferType != "bonus" {
 		return badRequest("Transaction is not deposit")
 	}
+	transition, err := bankTransactionTransition(*record, model.BankTransactionActionConfirm)
+	if err != nil {
+		return err
+	}
 	jsonBefore, _ := json.Marshal(record)
 
 	var updateData model.BankDepositTransactionConfirmBody
-	updateData.Status = "pending_credit"
+	updateData.Status = transition.To
 	updateData.ConfirmedAt = req.ConfirmedAt
 	updateData.ConfirmedByUserId = req.ConfirmedByUserId
 	updateData.ConfirmedByUsername = req.ConfirmedByUsername
@@ -1928,11 +1943,11 @@ func (s *accountingService) ConfirmDepositTransaction(id int64, req model.BankCo
 	createBody.ConfirmedByUsername = req.ConfirmedByUsername
 	if actionId, err := s.repo.CreateTransactionAction(createBody); err == nil {
 		// do nothing ?
-		if err := s.repo.ConfirmPendingDepositTransaction(id, updateData); err != nil {
-			if err := s.repo.RollbackTransactionAction(*actionId); err == nil {
+		if err := s.repo.ConfirmPendingDepositTransaction(id, transition.From, updateData); err != nil {
+			if err := s.repo.RollbackTransactionAction(*actionId); err != nil {
 				return internalServerError(err.Error())
 			}
-			return internalServerError(err.Error())
+			return bankTransactionError(err)
 		}
 	} else {
 		return internalServerError(err.Error())
@@ -1949,13 +1964,14 @@ func (s *accountingService) ConfirmDepositCredit(id int64, req model.BankConfirm
 	if record.TransferType != "deposit" && record.TransferType != "bonus" {
 		return badRequest("Transaction is not deposit")
 	}
-	if record.Status != "pending_credit" {
-		return badRequest("Transaction is not pending")
+	transition, err := bankTransactionTransition(*record, model.BankTransactionActionConfirmCredit)
+	if err != nil {
+		return err
 	}
 	jsonBefore, _ := json.Marshal(record)
 
 	var updateData model.BankDepositTransactionConfirmBody
-	updateData.Status = "finished"
+	updateData.Status = transition.To
 
 	updateData.ConfirmedAt = req.ConfirmedAt
 	updateData.ConfirmedByUserId = req.ConfirmedByUserId
@@ -1990,27 +2006,29 @@ func (s *accountingService) ConfirmDepositCredit(id int64, req model.BankConfirm
 	createBody.ConfirmedAt = req.ConfirmedAt
 	createBody.ConfirmedByUserId = req.ConfirmedByUserId
 	createBody.ConfirmedByUsername = req.ConfirmedByUsername
-	// todo : transaction
-	if _, err := s.repo.CreateTransactionAction(createBody); err == nil {
-		fmt.Println("ConfirmPendingTransaction updateData:", helper.StructJson(updateData))
-		if err := s.increaseMemberCreditFromDeposit(record.UserId, record.CreditAmount, "ฝากเงิน"); err != nil {
-			return internalServerError(err.Error())
+	fmt.Println("ConfirmPendingTransaction updateData:", helper.StructJson(updateData))
+	// credit is only added together with the transaction leaving pending_credit
+	return s.inTransaction(func(tx *accountingService) error {
+
+		if _, err := tx.repo.CreateTransactionAction(createBody); err != nil {
+			return bankTransactionError(err)
+		}
+		if err := tx.repo.ConfirmPendingCreditDepositTransaction(id, transition.From, updateData); err != nil {
+			return bankTransactionError(err)
+		}
+		if err := tx.increaseMemberCreditFromDeposit(record.UserId, record.CreditAmount, "ฝากเงิน", ledgerBankAccountId(record.ToAccountId)); err != nil {
+			return err
 		}
 		if record.BonusAmount > 0 {
-			if err := s.increaseMemberCreditFromBonus(record.UserId, record.CreditAmount, "ได้รับโบนัสจากการฝากเงิน"); err != nil {
-				return internalServerError(err.Error())
+			if err := tx.increaseMemberCreditFromBonus(record.UserId, record.BonusAmount, "ได้รับโบนัสจากการฝากเงิน"); err != nil {
+				return err
 			}
 		}
-		if err := s.repo.ConfirmPendingCreditDepositTransaction(id, updateData); err != nil {
-			return internalServerError(err.Error())
-		}
-	} else {
-		return internalServerError(err.Error())
-	}
-	return nil
+		return nil // COMMIT
+	})
 }
 
-func (s *accountingService) increaseMemberCreditFromDeposit(userId int64, creditAmount float64, info string) error {
+func (s *accountingService) increaseMemberCreditFromDeposit(userId int64, creditAmount model.Money, info string, bankAccountId *int64) error {
 
 	statementType, err := s.repo.GetMemberStatementTypeByCode("deposit")
 	if err != nil {
@@ -2022,13 +2040,14 @@ func (s *accountingService) increaseMemberCreditFromDeposit(userId int64, credit
 	body.StatementTypeId = statementType.Id
 	body.Info = info
 	body.Amount = creditAmount
+	body.BankAccountId = bankAccountId
 	if err := s.repo.IncreaseMemberCredit(body); err != nil {
 		return internalServerError(err.Error())
 	}
 	return nil
 }
 
-func (s *accountingService) increaseMemberCreditFromBonus(userId int64, creditAmount float64, info string) error {
+func (s *accountingService) increaseMemberCreditFromBonus(userId int64, creditAmount model.Money, info string) error {
 
 	statementType, err := s.repo.GetMemberStatementTypeByCode("bonus")
 	if err != nil {
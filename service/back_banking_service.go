@@ -1,14 +1,18 @@
 package service
 
 import (
+	"context"
+	"cybergame-api/agent"
 	"cybergame-api/helper"
 	"cybergame-api/model"
 	"cybergame-api/repository"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"os"
+	"net/http"
 	"strconv"
+	"sync/atomic"
 	"time"
 )
 
@@ -27,7 +31,7 @@ type BankingService interface {
 	GetBankWithdrawTransactions(req model.BankTransactionListRequest) (*model.SuccessWithPagination, error)
 	GetBankDepositTransStatusCounts(req model.BankTransactionListRequest) (*model.BankDepositTransStatusCounts, error)
 	GetBankWithdrawTransStatusCounts(req model.BankTransactionListRequest) (*model.BankWithdrawTransStatusCounts, error)
-	CreateBankTransaction(data model.BankTransactionCreateBody) error
+	CreateBankTransaction(ctx context.Context, data model.BankTransactionCreateBody) error
 	CreateBonusTransaction(data model.BonusTransactionCreateBody) error
 	UpdateBankTransaction(id int64, req model.BankTransactionUpdateRequest) error
 	DeleteBankTransaction(id int64) error
@@ -36,13 +40,20 @@ type BankingService interface {
 	GetPendingWithdrawTransactions(req model.PendingWithdrawTransactionListRequest) (*model.SuccessWithPagination, error)
 	ConfirmDepositTransaction(id int64, req model.BankConfirmDepositRequest) error
 	ConfirmDepositCredit(id int64, req model.BankConfirmDepositRequest) error
-	ContinueAutoWithdrawTransaction(id int64) error
-	ConfirmWithdrawTransaction(id int64, req model.BankConfirmCreditWithdrawRequest) error
+	ContinueAutoWithdrawTransaction(ctx context.Context, id int64) error
+	ConfirmWithdrawTransaction(ctx context.Context, id int64, req model.BankConfirmCreditWithdrawRequest) error
 	ConfirmWithdrawTransfer(id int64, req model.BankConfirmTransferWithdrawRequest) error
-	CancelPendingTransaction(id int64, data model.BankTransactionCancelBody) error
+	CancelPendingTransaction(ctx context.Context, id int64, data model.BankTransactionCancelBody) error
 	GetFinishedTransactions(req model.FinishedTransactionListRequest) (*model.SuccessWithPagination, error)
 	RemoveFinishedTransaction(id int64, data model.BankTransactionRemoveBody) error
 	GetRemovedTransactions(req model.RemovedTransactionListRequest) (*model.SuccessWithPagination, error)
+	BulkConfirmDepositTransactions(ctx context.Context, ids []int64, req model.BankConfirmDepositRequest) *model.BankTransactionBulkResult
+	BulkConfirmDepositCredits(ctx context.Context, ids []int64, req model.BankConfirmDepositRequest) *model.BankTransactionBulkResult
+	BulkConfirmWithdrawTransactions(ctx context.Context, ids []int64, req model.BankConfirmCreditWithdrawRequest) *model.BankTransactionBulkResult
+	BulkCancelPendingTransactions(ctx context.Context, ids []int64, data model.BankTransactionCancelBody) *model.BankTransactionBulkResult
+
+	GetWithdrawRiskRules() ([]model.WithdrawRiskRule, error)
+	UpdateWithdrawRiskRule(id int64, req model.WithdrawRiskRuleUpdateRequest) error
 
 	GetMemberByCode(code string) (*model.Member, error)
 	GetMembers(req model.MemberListRequest) (*model.SuccessWithPagination, error)
@@ -51,28 +62,62 @@ type BankingService interface {
 	GetMemberTransactionSummary(req model.MemberTransactionListRequest) (*model.MemberTransactionSummary, error)
 	GetMemberStatementById(req model.GetByIdRequest) (*model.MemberStatementResponse, error)
 	GetMemberStatements(req model.MemberStatementListRequest) (*model.SuccessWithPagination, error)
-	ProcessMemberDepositCredit(userId int64, amount float64) error
-	ProcessMemberWithdrawCredit(userId int64, amount float64) error
-	ProcessMemberBonusCredit(userId int64, amount float64) error
-	ProcessMemberGetbackCredit(userId int64, amount float64) error
+	ProcessMemberDepositCredit(userId int64, amount model.Money) error
+	ProcessMemberWithdrawCredit(userId int64, amount model.Money) error
+	ProcessMemberBonusCredit(userId int64, amount model.Money) error
+	ProcessMemberGetbackCredit(userId int64, amount model.Money) error
+	SyncMemberCredits(ctx context.Context, body model.MemberCreditSyncBody) (*model.MemberCreditSyncResult, error)
+	StartMemberCreditSync(interval time.Duration)
 }
 
 var memberNotFound = "Member not found"
 var bankStatementferNotFound = "Statement not found"
 var bankTransactionferNotFound = "Transaction not found"
 
+const AgentUserNotRegistered = "สมาชิกยังไม่ได้ลงทะเบียนกับเอเย่นต์"
+const AgentWithdrawRefused = "เอเย่นต์ไม่อนุมัติการถอนเครดิต"
+const AgentWithdrawBalanceMismatch = "ยอดเครดิตคงเหลือจากเอเย่นต์ไม่ถูกต้อง"
+const WithdrawAgentDebitInProgress = "รายการถอนนี้กำลังตัดเครดิตที่เอเย่นต์ กรุณารอสักครู่"
+const WithdrawAmountDebited = "ยอดถอนต้องเท่ากับยอดที่ตัดเครดิตที่เอเย่นต์แล้ว หากต้องการเปลี่ยนยอดให้ยกเลิกแล้วทำรายการใหม่"
+const MemberCreditMoved = "เครดิตมีการเปลี่ยนแปลงระหว่างตรวจสอบ"
+const MemberCreditSeamless = "สมาชิกเล่นผ่าน seamless wallet ใช้เครดิตในระบบเป็นยอดหลัก"
+const MemberCreditInProgress = "มีรายการฝากหรือถอนที่ยังไม่เสร็จ ตรวจสอบใหม่รอบถัดไป"
+const MemberCreditUnderReserved = "ยอดที่เอเย่นต์ต่ำกว่าเครดิตที่กันไว้สำหรับการถอน"
+const MemberCreditNotEnough = "เครดิตคงเหลือไม่พอสำหรับการถอน"
+
+// members are read from the database this many at a time during a sync
+const memberCreditSyncBatch = 200
+
+var memberCreditSyncing int32
+
 type bankingService struct {
 	repoBanking      repository.BankingRepository
 	repoAccounting   repository.AccountingRepository
 	repoAgentConnect repository.AgentConnectRepository
+	agentCall        AgentCallService
+	accounting       AccountingService
+	uow              repository.UnitOfWork
 }
 
 func NewBankingService(
 	repoBanking repository.BankingRepository,
 	repoAccounting repository.AccountingRepository,
 	repoAgentConnect repository.AgentConnectRepository,
+	repoAgentCall repository.AgentCallRepository,
+	uow repository.UnitOfWork,
 ) BankingService {
-	return &bankingService{repoBanking, repoAccounting, repoAgentConnect}
+	return &bankingService{repoBanking, repoAccounting, repoAgentConnect, NewAgentCallService(repoAgentCall, repoAgentConnect), NewAccountingService(repoAccounting, uow), uow}
+}
+
+// inTransaction runs fn on a copy of the service whose banking and accounting
+// repositories share one database transaction.
+func (s *bankingService) inTransaction(fn func(tx *bankingService) error) error {
+	return s.uow.WithinTransaction(func(repos repository.UnitOfWorkRepositories) error {
+		tx := *s
+		tx.repoBanking = repos.Banking
+		tx.repoAccounting = repos.Accounting
+		return fn(&tx)
+	})
 }
 
 func (s *bankingService) GetBankStatementById(req model.GetByIdRequest) (*model.BankStatement, error) {
@@ -317,6 +362,8 @@ func (s *bankingService) GetBankWithdrawTransStatusCounts(req model.BankTransact
 		result.AllCount += record.Count
 		if record.Status == "pending_credit" {
 			result.PendingCreditCount = record.Count
+		} else if record.Status == "pending_agent" {
+			result.PendingAgentCount = record.Count
 		} else if record.Status == "pending_transfer" {
 			result.PendingTransferCount = record.Count
 		} else if record.Status == "finished" {
@@ -328,7 +375,7 @@ func (s *bankingService) GetBankWithdrawTransStatusCounts(req model.BankTransact
 	return &result, nil
 }
 
-func (s *bankingService) CreateBankTransaction(data model.BankTransactionCreateBody) error {
+func (s *bankingService) CreateBankTransaction(ctx context.Context, data model.BankTransactionCreateBody) error {
 
 	var body model.BankTransactionCreateBody
 	body.TransferAt = data.TransferAt
@@ -379,20 +426,19 @@ func (s *bankingService) CreateBankTransaction(data model.BankTransactionCreateB
 			return internalServerError(err.Error())
 		}
 
-		agentName := os.Getenv("AGENT_NAME")
-		sign := agentName + *member.Username
-		timeNow := time.Now()
 		agentData := model.AGCDeposit{
-			Agentname:     agentName,
 			PlayerName:    *member.Username,
-			Amount:        body.CreditAmount,
-			Timestamp:     timeNow.Unix(),
-			Sign:          helper.CreateSign(sign, timeNow),
+			Amount:        body.CreditAmount.Float64(),
 			TransactionId: strconv.FormatInt(*transactionId, 10),
 		}
 
-		if err := s.repoAgentConnect.Deposit(agentData); err != nil {
-			return internalServerError(err.Error())
+		if _, err := s.agentCall.Deposit(ctx, &member.Id, transactionId, agentData); err != nil {
+			if isRetryableAgentError(err) {
+				// journaled, the retrier sends it again with the same TransactionId
+				log.Printf("deposit %d agent call queued: %s", *transactionId, err.Error())
+				return nil
+			}
+			return agentError(err)
 		}
 	} else if data.TransferType == "withdraw" {
 		var autoWithdrawCondition *model.BankAutoWithdrawCondition
@@ -412,55 +458,81 @@ func (s *bankingService) CreateBankTransaction(data model.BankTransactionCreateB
 		body.CreditAmount = data.CreditAmount
 		body.TransferType = data.TransferType
 
-		// Withdraw SystemAccount is no more requried
+		if err := s.checkMemberTurnover(member.Id); err != nil {
+			return err
+		}
+
+		// Withdraw SystemAccount is no more requried, without one it is picked by balance
+		var fromAccount *model.BankAccount
 		if data.FromAccountId != nil {
-			fromAccount, err := s.repoAccounting.GetWithdrawAccountById(*data.FromAccountId)
+			fromAccount, err = s.repoAccounting.GetWithdrawAccountById(*data.FromAccountId)
 			if err != nil {
 				fmt.Println(err)
 				return badRequest("Invalid Bank Account")
 			}
+		} else {
+			fromAccount = s.selectWithdrawAccount(data.CreditAmount)
+		}
+		if fromAccount != nil {
 			body.FromAccountId = &fromAccount.Id
 			body.FromBankId = &fromAccount.BankId
 			body.FromAccountName = &fromAccount.AccountName
 			body.FromAccountNumber = &fromAccount.AccountNumber
 			if condition, err := s.GetNewAutoWithdrawCondition(body, *fromAccount); err == nil {
 				autoWithdrawCondition = condition
+				body.StatusDetail = condition.StatusDetail
 			}
 		}
 
 		body.ToBankId = &bank.Id
 		body.ToAccountName = &member.Fullname
 		body.ToAccountNumber = &member.BankAccount
-		body.Status = "pending_credit"
+		body.Status = model.BankTransactionPendingAgent
 
-		// later : reserved/check current user credit ?
-		// for now : no need to cut user Credit !
-		if insertId, err := s.repoBanking.CreateBankWithdrawTransaction(body); err == nil {
-			if insertId != nil && autoWithdrawCondition != nil {
-
-				agentName := os.Getenv("AGENT_NAME")
-				sign := agentName + *member.Username
-				timeNow := time.Now()
-				agentData := model.AGCDeposit{
-					Agentname:     agentName,
-					PlayerName:    *member.Username,
-					Amount:        body.CreditAmount,
-					Timestamp:     timeNow.Unix(),
-					Sign:          helper.CreateSign(sign, timeNow),
-					TransactionId: strconv.FormatInt(*insertId, 10),
-				}
+		// the credit is held from the request and the agent wallet debited, the
+		// confirm takes the credit and a cancel gives both back
+		var insertId *int64
+		if err := s.inTransaction(func(tx *bankingService) error {
 
-				if err := s.repoAgentConnect.Deposit(agentData); err != nil {
-					return internalServerError(err.Error())
-				}
-
-				autoWithdrawCondition.TransId = *insertId
-				if err := s.ProcessAutoWithdrawCondition(*autoWithdrawCondition); err != nil {
-					return internalServerError(err.Error())
+			id, err := tx.repoBanking.CreateBankWithdrawTransaction(body)
+			if err != nil {
+				return internalServerError(err.Error())
+			}
+			if err := tx.repoBanking.ReserveMemberCredit(body.UserId, *id, body.CreditAmount); err != nil {
+				if errors.Is(err, repository.ErrMemberCreditNotEnough) {
+					return badRequest(MemberCreditNotEnough)
 				}
+				return internalServerError(err.Error())
+			}
+			insertId = id
+			return nil // COMMIT
+		}); err != nil {
+			return err
+		}
+		record := model.BankTransaction{Id: *insertId, UserId: body.UserId, TransferType: body.TransferType, Status: body.Status, CreditAmount: body.CreditAmount}
+		if _, err := s.debitWithdrawAgent(ctx, record, body.CreditAmount); err != nil {
+			if _, settleErr := s.settledWithdrawAgentDebit(strconv.FormatInt(*insertId, 10)); settleErr != nil {
+				// journaled, the withdraw waits in pending_agent for the retrier or the confirm
+				log.Printf("withdraw %d agent debit queued: %s", *insertId, err.Error())
+				return nil
+			}
+			// the agent took nothing, the withdraw does not stay with its hold
+			cancelBody := model.BankTransactionCancelBody{
+				CancelRemark:       err.Error(),
+				CanceledAt:         time.Now(),
+				CanceledByUserId:   data.CreatedByUserId,
+				CanceledByUsername: data.CreatedByUsername,
+			}
+			if cancelErr := s.CancelPendingTransaction(ctx, *insertId, cancelBody); cancelErr != nil {
+				log.Printf("withdraw %d cancel: %s", *insertId, cancelErr.Error())
+			}
+			return err
+		}
+		if autoWithdrawCondition != nil {
+			autoWithdrawCondition.TransId = *insertId
+			if err := s.ProcessAutoWithdrawCondition(ctx, *autoWithdrawCondition); err != nil {
+				return internalServerError(err.Error())
 			}
-		} else {
-			return internalServerError(err.Error())
 		}
 	} else if data.TransferType == "getcreditback" {
 		// จะดึงยอดสลายไปเลย
@@ -508,7 +580,11 @@ func (s *bankingService) GetNewAutoWithdrawCondition(body model.BankTransactionC
 		autoWithdrawCondition.AutoWithdrawCreditFlag = "auto"
 	}
 
-	// later : More Conditions
+	s.holdAutoWithdraw(&autoWithdrawCondition, model.WithdrawRiskCheck{
+		UserId:       body.UserId,
+		CreditAmount: body.CreditAmount,
+		FromAccount:  fromAccount,
+	})
 	return &autoWithdrawCondition, nil
 }
 
@@ -527,10 +603,52 @@ func (s *bankingService) GetAutoWithdrawCondition(body model.BankTransaction, fr
 		autoWithdrawCondition.AutoWithdrawCreditFlag = "auto"
 	}
 
-	// later : More Conditions
+	s.holdAutoWithdraw(&autoWithdrawCondition, model.WithdrawRiskCheck{
+		TransactionId: body.Id,
+		UserId:        body.UserId,
+		CreditAmount:  body.CreditAmount,
+		FromAccount:   fromAccount,
+	})
 	return &autoWithdrawCondition, nil
 }
 
+// selectWithdrawAccount picks the account to pay a withdraw from when the
+// admin did not choose one. The main withdraw account goes first, then the
+// one with the most money. An account is skipped when the amount is over its
+// AutoTransferMaxAmount or its balance at the bank, nil leaves it manual.
+func (s *bankingService) selectWithdrawAccount(amount model.Money) *model.BankAccount {
+
+	accounts, err := s.repoAccounting.GetAutoWithdrawAccounts()
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+
+	var selected *model.BankAccount
+	var selectedBalance model.Money
+	for i, account := range accounts {
+		if account.AutoTransferMaxAmount > 0 && amount > account.AutoTransferMaxAmount {
+			continue
+		}
+		balanceResp, err := s.accounting.GetExternalAccountBalance(model.ExternalAccountStatusRequest{AccountNumber: account.AccountNumber})
+		if err != nil {
+			log.Printf("withdraw account %d balance: %s", account.Id, err.Error())
+			continue
+		}
+		balance, err := model.ParseMoney(balanceResp.AccountBalance)
+		if err != nil || balance < amount {
+			continue
+		}
+		if selected == nil ||
+			(account.IsMainWithdraw && !selected.IsMainWithdraw) ||
+			(account.IsMainWithdraw == selected.IsMainWithdraw && balance > selectedBalance) {
+			selected = &accounts[i]
+			selectedBalance = balance
+		}
+	}
+	return selected
+}
+
 func (s *bankingService) SetAutoWithdrawCondition(transId int64, curCondition *model.BankAutoWithdrawCondition) error {
 
 	// Fix Same request on auto+auto
@@ -545,7 +663,10 @@ func (s *bankingService) SetAutoWithdrawCondition(transId int64, curCondition *m
 	curCondition.UserId = transaction.UserId
 	curCondition.TransStatus = transaction.Status
 	curCondition.CreditAmount = transaction.CreditAmount
-	curCondition.FromAccountId = transaction.FromAccountId
+	// keep the picked account until the confirm writes it on the transaction
+	if transaction.FromAccountId != 0 {
+		curCondition.FromAccountId = transaction.FromAccountId
+	}
 
 	// if fromAccount.AutoWithdrawCreditFlag == "auto" {
 	// 	autoWithdrawCondition.AutoWithdrawConfirmFlag = "auto"
@@ -554,11 +675,16 @@ func (s *bankingService) SetAutoWithdrawCondition(transId int64, curCondition *m
 	// 	autoWithdrawCondition.AutoWithdrawCreditFlag = "auto"
 	// }
 
-	// later : More Conditions
+	// held for review, keep why on the transaction
+	if curCondition.StatusDetail != "" && curCondition.StatusDetail != transaction.StatusDetail {
+		if err := s.repoBanking.SetBankTransactionStatusDetail(transaction.Id, curCondition.StatusDetail); err != nil {
+			return internalServerError(err.Error())
+		}
+	}
 	return nil
 }
 
-func (s *bankingService) ProcessAutoWithdrawCondition(req model.BankAutoWithdrawCondition) error {
+func (s *bankingService) ProcessAutoWithdrawCondition(ctx context.Context, req model.BankAutoWithdrawCondition) error {
 
 	if req.TransStatus == "pending" && req.AutoWithdrawCreditFlag == "auto" {
 		var confirmReq model.BankConfirmCreditWithdrawRequest
@@ -568,11 +694,11 @@ func (s *bankingService) ProcessAutoWithdrawCondition(req model.BankAutoWithdraw
 		confirmReq.ConfirmedAt = time.Now()
 		confirmReq.ConfirmedByUserId = 0
 		confirmReq.ConfirmedByUsername = "อัตโนมัติ"
-		actionErr := s.ConfirmWithdrawTransaction(req.TransId, confirmReq)
+		actionErr := s.ConfirmWithdrawTransaction(ctx, req.TransId, confirmReq)
 		if actionErr != nil {
 			return internalServerError(actionErr.Error())
 		}
-	} else if req.TransStatus == "pending_credit" && req.AutoWithdrawCreditFlag == "auto" {
+	} else if (req.TransStatus == "pending_credit" || req.TransStatus == "pending_agent") && req.AutoWithdrawCreditFlag == "auto" {
 		var confirmReq model.BankConfirmCreditWithdrawRequest
 		confirmReq.FromAccountId = &req.FromAccountId
 		confirmReq.CreditAmount = &req.CreditAmount
@@ -580,7 +706,7 @@ func (s *bankingService) ProcessAutoWithdrawCondition(req model.BankAutoWithdraw
 		confirmReq.ConfirmedAt = time.Now()
 		confirmReq.ConfirmedByUserId = 0
 		confirmReq.ConfirmedByUsername = "อัตโนมัติ"
-		actionErr := s.ConfirmWithdrawTransaction(req.TransId, confirmReq)
+		actionErr := s.ConfirmWithdrawTransaction(ctx, req.TransId, confirmReq)
 		if actionErr != nil {
 			return internalServerError(actionErr.Error())
 		}
@@ -705,15 +831,17 @@ func (s *bankingService) GetPendingWithdrawTransactions(req model.PendingWithdra
 	return records, nil
 }
 
-func (s *bankingService) CancelPendingTransaction(id int64, data model.BankTransactionCancelBody) error {
+func (s *bankingService) CancelPendingTransaction(ctx context.Context, id int64, data model.BankTransactionCancelBody) error {
 
 	transaction, err := s.repoBanking.GetBankTransactionById(id)
 	if err != nil {
 		return internalServerError(err.Error())
 	}
-	if transaction.Status != "pending" && transaction.Status != "pending_credit" && transaction.Status != "pending_transfer" {
-		return badRequest("Transaction is not pending")
+	transition, err := bankTransactionTransition(*transaction, model.BankTransactionActionCancel)
+	if err != nil {
+		return err
 	}
+	data.Status = transition.To
 	jsonBefore, _ := json.Marshal(transaction)
 
 	var createBody model.CreateBankTransactionActionBody
@@ -735,39 +863,61 @@ func (s *bankingService) CancelPendingTransaction(id int64, data model.BankTrans
 	createBody.ConfirmedAt = data.CanceledAt
 	createBody.ConfirmedByUserId = data.CanceledByUserId
 	createBody.ConfirmedByUsername = data.CanceledByUsername
-	if actionId, err := s.repoBanking.CreateTransactionAction(createBody); err == nil {
+	var agentTransactionId string
+	if transaction.TransferType == "withdraw" {
+		if agentTransactionId, err = s.withdrawAgentTransactionId(transaction.Id); err != nil {
+			return err
+		}
+	}
+	// the status moves first, a cancel that lost to a confirm returns no credit
+	var agentDebit *model.AgentCallJournal
+	if err := s.inTransaction(func(tx *bankingService) error {
+
+		if _, err := tx.repoBanking.CreateTransactionAction(createBody); err != nil {
+			return bankTransactionError(err)
+		}
+		if transaction.TransferType == "withdraw" {
+			debit, err := tx.settledWithdrawAgentDebit(agentTransactionId)
+			if err != nil {
+				return err
+			}
+			agentDebit = debit
+		}
+		if err := tx.repoBanking.CancelPendingTransaction(id, transition.From, data); err != nil {
+			return bankTransactionError(err)
+		}
 
 		if transaction.TransferType == "deposit" {
 			// DO_NOTHING
 		} else if transaction.TransferType == "withdraw" {
-			// RETURN_CREDIT
-			if err := s.increaseMemberCredit(transaction.UserId, transaction.CreditAmount, "withdraw", "คืนเครดิตจากการถอนไม่สำเร็จ"); err != nil {
-				if err := s.repoBanking.RollbackTransactionAction(*actionId); err != nil {
+			if transaction.Status == model.BankTransactionPendingTransfer {
+				// RETURN_CREDIT, taken here on confirm credit
+				if err := tx.increaseMemberCredit(transaction.UserId, transaction.CreditAmount, "withdraw", "คืนเครดิตจากการถอนไม่สำเร็จ", ledgerBankAccountId(transaction.FromAccountId)); err != nil {
+					return err
+				}
+			} else {
+				// RELEASE_CREDIT, only held until now
+				if err := tx.repoBanking.ReleaseMemberCredit(transaction.Id); err != nil {
 					return internalServerError(err.Error())
 				}
-				return internalServerError(err.Error())
 			}
 		} else if transaction.TransferType == "bonus" {
 			// DO_NOTHING
 		} else if transaction.TransferType == "getcreditback" {
 			// RETURN_CREDIT
-			if err := s.increaseMemberCredit(transaction.UserId, transaction.CreditAmount, "getcreditback", "คืนเครดิตจากรายการที่ไม่สำเร็จ"); err != nil {
-				if err := s.repoBanking.RollbackTransactionAction(*actionId); err != nil {
-					return internalServerError(err.Error())
-				}
-				return internalServerError(err.Error())
-			}
-		}
-
-		if err := s.repoBanking.CancelPendingTransaction(id, data); err != nil {
-			if err := s.repoBanking.RollbackTransactionAction(*actionId); err != nil {
-				return internalServerError(err.Error())
+			if err := tx.increaseMemberCredit(transaction.UserId, transaction.CreditAmount, "getcreditback", "คืนเครดิตจากรายการที่ไม่สำเร็จ", nil); err != nil {
+				return err
 			}
-			return internalServerError(err.Error())
 		}
+		return nil // COMMIT
+	}); err != nil {
+		return err
+	}
 
-	} else {
-		return internalServerError(err.Error())
+	// the agent wallet gets back what it was debited, after the cancel is
+	// committed so a lost cancel sends nothing
+	if agentDebit != nil {
+		return s.refundAgentCredit(transaction.UserId, agentDebit.Amount, transaction.Id, agentDebit.TransactionId)
 	}
 	return nil
 }
@@ -778,16 +928,17 @@ func (s *bankingService) ConfirmDepositTransaction(id int64, req model.BankConfi
 	if err != nil {
 		return internalServerError(err.Error())
 	}
-	if record.Status != "pending" {
-		return badRequest("Transaction is not pending")
-	}
 	if record.TransferType != "deposit" && record.TransferType != "bonus" {
 		return badRequest("Transaction is not deposit")
 	}
+	transition, err := bankTransactionTransition(*record, model.BankTransactionActionConfirm)
+	if err != nil {
+		return err
+	}
 	jsonBefore, _ := json.Marshal(record)
 
 	var updateData model.BankDepositTransactionConfirmBody
-	updateData.Status = "pending_credit"
+	updateData.Status = transition.To
 	updateData.ConfirmedAt = req.ConfirmedAt
 	updateData.ConfirmedByUserId = req.ConfirmedByUserId
 	updateData.ConfirmedByUsername = req.ConfirmedByUsername
@@ -820,11 +971,11 @@ func (s *bankingService) ConfirmDepositTransaction(id int64, req model.BankConfi
 	createBody.ConfirmedByUsername = req.ConfirmedByUsername
 	if actionId, err := s.repoBanking.CreateTransactionAction(createBody); err == nil {
 		// do nothing ?
-		if err := s.repoBanking.ConfirmPendingDepositTransaction(id, updateData); err != nil {
+		if err := s.repoBanking.ConfirmPendingDepositTransaction(id, transition.From, updateData); err != nil {
 			if err := s.repoBanking.RollbackTransactionAction(*actionId); err != nil {
 				return internalServerError(err.Error())
 			}
-			return internalServerError(err.Error())
+			return bankTransactionError(err)
 		}
 	} else {
 		return internalServerError(err.Error())
@@ -833,7 +984,7 @@ func (s *bankingService) ConfirmDepositTransaction(id int64, req model.BankConfi
 	if record.IsAutoCredit {
 		// isAUtoCredit with same request
 		if err := s.ConfirmDepositCredit(id, req); err != nil {
-			return internalServerError(err.Error())
+			return err
 		}
 	}
 	return nil
@@ -848,13 +999,14 @@ func (s *bankingService) ConfirmDepositCredit(id int64, req model.BankConfirmDep
 	if record.TransferType != "deposit" && record.TransferType != "bonus" {
 		return badRequest("Transaction is not deposit")
 	}
-	if record.Status != "pending_credit" {
-		return badRequest("Transaction is not pending")
+	transition, err := bankTransactionTransition(*record, model.BankTransactionActionConfirmCredit)
+	if err != nil {
+		return err
 	}
 	jsonBefore, _ := json.Marshal(record)
 
 	var updateData model.BankDepositTransactionConfirmBody
-	updateData.Status = "finished"
+	updateData.Status = transition.To
 	updateData.ConfirmedAt = req.ConfirmedAt
 	updateData.ConfirmedByUserId = req.ConfirmedByUserId
 	updateData.ConfirmedByUsername = req.ConfirmedByUsername
@@ -888,36 +1040,204 @@ func (s *bankingService) ConfirmDepositCredit(id int64, req model.BankConfirmDep
 	createBody.ConfirmedAt = req.ConfirmedAt
 	createBody.ConfirmedByUserId = req.ConfirmedByUserId
 	createBody.ConfirmedByUsername = req.ConfirmedByUsername
-	// todo : transaction
-	if _, err := s.repoBanking.CreateTransactionAction(createBody); err == nil {
-		fmt.Println("ConfirmPendingTransaction updateData:", helper.StructJson(updateData))
-		if err := s.increaseMemberCredit(record.UserId, record.CreditAmount, "deposit", "ฝากเครดิต"); err != nil {
-			// if err := s.repoBanking.RollbackTransactionAction(*actionId); err != nil {
-			// 	return internalServerError(err.Error())
-			// }
-			return internalServerError(err.Error())
+	fmt.Println("ConfirmPendingTransaction updateData:", helper.StructJson(updateData))
+	// credit is only added together with the transaction leaving pending_credit
+	return s.inTransaction(func(tx *bankingService) error {
+
+		if _, err := tx.repoBanking.CreateTransactionAction(createBody); err != nil {
+			return bankTransactionError(err)
+		}
+		if err := tx.repoBanking.ConfirmPendingCreditDepositTransaction(id, transition.From, updateData); err != nil {
+			return bankTransactionError(err)
+		}
+		if err := tx.increaseMemberCredit(record.UserId, record.CreditAmount, "deposit", "ฝากเครดิต", ledgerBankAccountId(record.ToAccountId)); err != nil {
+			return err
 		}
 		if record.BonusAmount > 0 {
-			if err := s.increaseMemberCredit(record.UserId, record.BonusAmount, "deposit", "ได้รับโบนัสจากการฝากเครดิต"); err != nil {
-				// if err := s.repoBanking.RollbackTransactionAction(*actionId); err != nil {
-				// 	return internalServerError(err.Error())
-				// }
-				return internalServerError(err.Error())
+			if err := tx.increaseMemberCredit(record.UserId, record.BonusAmount, "bonus", "ได้รับโบนัสจากการฝากเครดิต", nil); err != nil {
+				return err
 			}
 		}
-		if err := s.repoBanking.ConfirmPendingCreditDepositTransaction(id, updateData); err != nil {
-			// if err := s.repoBanking.RollbackTransactionAction(*actionId); err != nil {
-			// 	return internalServerError(err.Error())
-			// }
-			return internalServerError(err.Error())
-		}
-	} else {
+		return nil // COMMIT
+	})
+}
+
+// checkMemberTurnover refuses a withdraw before the member bet its turnover
+// limit, the turnover comes from the imported bets.
+func (s *bankingService) checkMemberTurnover(userId int64) error {
+
+	turnover, err := s.repoBanking.GetMemberTurnover(userId)
+	if err != nil {
 		return internalServerError(err.Error())
 	}
+	if status := memberTurnoverStatus(turnover); !status.IsPassed {
+		return badRequest(fmt.Sprintf("%s (%.2f/%d)", TurnoverNotReached, status.Turnover, status.TurnoverLimit))
+	}
+	return nil
+}
+
+// withdrawAgentCredit debits the member wallet at the agent and checks the
+// agent really took the amount.
+func (s *bankingService) withdrawAgentCredit(ctx context.Context, userId int64, creditAmount model.Money, transactionId int64, agentTransactionId string) error {
+
+	member, err := s.repoBanking.GetMemberById(userId)
+	if err != nil {
+		return badRequest("Invalid Member")
+	}
+	if member.Username == "" {
+		return badRequest(AgentUserNotRegistered)
+	}
+
+	agentData := model.AGCWithdraw{
+		PlayerName:    member.Username,
+		Amount:        creditAmount.Float64(),
+		TransactionId: agentTransactionId,
+	}
+
+	// a confirm sent again after a timeout replays the journaled answer
+	response, err := s.agentCall.Withdraw(ctx, &userId, &transactionId, agentData)
+	if err != nil {
+		if agentErr, ok := agent.AsError(err); ok && agentErr.Kind == agent.KindRefused {
+			return badRequest(AgentWithdrawRefused + ": " + agentErr.Message)
+		}
+		return agentError(err)
+	}
+
+	if response.Data.Balance < 0 {
+		return internalServerError(AgentWithdrawBalanceMismatch)
+	}
+	// some agent versions do not send the balance before, only check when present
+	if response.Data.BeforeBalance > 0 && model.NewMoney(response.Data.BeforeBalance)-creditAmount != model.NewMoney(response.Data.Balance) {
+		return internalServerError(AgentWithdrawBalanceMismatch)
+	}
+
+	return nil
+}
+
+// debitWithdrawAgent takes the withdraw amount off the agent wallet, so the
+// member cannot bet there what is being paid out. The withdraw waits in
+// pending_agent from before the call is sent until the agent answered, a
+// debit that ended without an answer stays tied to it: the retrier or the
+// next confirm sends the same TransactionId again and a cancel refunds it
+// once the journal settled. It then waits in pending_credit, one the agent
+// was debited for already is left as it is. getcreditback has no
+// pending_agent and is debited straight away on confirm.
+func (s *bankingService) debitWithdrawAgent(ctx context.Context, record model.BankTransaction, creditAmount model.Money) (string, error) {
+
+	agentTransactionId, err := s.withdrawAgentTransactionId(record.Id)
+	if err != nil {
+		return "", err
+	}
+	if record.TransferType != "withdraw" {
+		return agentTransactionId, s.withdrawAgentCredit(ctx, record.UserId, creditAmount, record.Id, agentTransactionId)
+	}
+
+	debit, err := s.repoBanking.GetWithdrawAgentDebit(agentTransactionId)
+	if err != nil {
+		return "", internalServerError(err.Error())
+	}
+	if debit != nil && debit.Amount != creditAmount {
+		return "", badRequest(WithdrawAmountDebited)
+	}
+	if debit != nil && debit.Status == agentCallSuccess && record.Status != model.BankTransactionPendingAgent {
+		return agentTransactionId, nil
+	}
+
+	// requested before the agent debit, it is debited now
+	if record.Status != model.BankTransactionPendingAgent {
+		transition, err := bankTransactionTransition(record, model.BankTransactionActionDebitAgent)
+		if err != nil {
+			return "", err
+		}
+		if err := s.repoBanking.UpdateBankTransactionStatus(record.Id, transition.From, transition.To); err != nil {
+			return "", bankTransactionError(err)
+		}
+		record.Status = transition.To
+	}
+	if err := s.withdrawAgentCredit(ctx, record.UserId, creditAmount, record.Id, agentTransactionId); err != nil {
+		return "", err
+	}
+	transition, err := bankTransactionTransition(record, model.BankTransactionActionAgentDebited)
+	if err != nil {
+		return "", err
+	}
+	if err := s.repoBanking.UpdateBankTransactionStatus(record.Id, transition.From, transition.To); err != nil {
+		return "", bankTransactionError(err)
+	}
+	return agentTransactionId, nil
+}
+
+// settledWithdrawAgentDebit reads the agent debit a cancel gives back, nil
+// when the agent took nothing. A debit still running or waiting for a retry
+// may yet land, the cancel is refused until the journal settled.
+func (s *bankingService) settledWithdrawAgentDebit(agentTransactionId string) (*model.AgentCallJournal, error) {
+
+	journal, err := s.repoBanking.GetWithdrawAgentDebit(agentTransactionId)
+	if err != nil {
+		return nil, internalServerError(err.Error())
+	}
+	if journal == nil {
+		return nil, nil
+	}
+	switch journal.Status {
+	case agentCallSuccess:
+		return journal, nil
+	case agentCallRunning, agentCallRetry:
+		return nil, ResponseError{Code: http.StatusConflict, Message: WithdrawAgentDebitInProgress}
+	}
+	return nil, nil
+}
+
+// refundAgentCredit gives back to the agent wallet a debit of
+// withdrawAgentCredit that the local credit did not follow. It is journaled
+// under its own TransactionId, one the agent could not take now is sent
+// again by the retrier or from the journal list by an admin.
+func (s *bankingService) refundAgentCredit(userId int64, creditAmount model.Money, transactionId int64, agentTransactionId string) error {
+
+	member, err := s.repoBanking.GetMemberById(userId)
+	if err != nil {
+		return badRequest("Invalid Member")
+	}
+
+	agentData := model.AGCDeposit{
+		PlayerName:    member.Username,
+		Amount:        creditAmount.Float64(),
+		TransactionId: agentRefundTransactionId(agentTransactionId),
+	}
+	// not tied to the request, an admin who gives up must not stop the money going back
+	if _, err := s.agentCall.Deposit(context.Background(), &userId, &transactionId, agentData); err != nil {
+		log.Printf("withdraw %d agent refund %s: %s", transactionId, agentData.TransactionId, err.Error())
+		if isRetryableAgentError(err) {
+			return nil
+		}
+		return agentError(err)
+	}
 	return nil
 }
 
-func (s *bankingService) increaseMemberCredit(userId int64, creditAmount float64, statementTypeName string, info string) error {
+// withdrawAgentTransactionId is the agent TransactionId of the debit of a
+// withdraw. A debit given back by refundAgentCredit is over, the next confirm
+// debits again under the next id instead of replaying it.
+func (s *bankingService) withdrawAgentTransactionId(transactionId int64) (string, error) {
+
+	agentTransactionId := strconv.FormatInt(transactionId, 10)
+	for attempt := 1; ; attempt++ {
+		refunded, err := s.agentCall.AgentCallExists(agentRefundTransactionId(agentTransactionId))
+		if err != nil {
+			return "", err
+		}
+		if !refunded {
+			return agentTransactionId, nil
+		}
+		agentTransactionId = fmt.Sprintf("%d-%d", transactionId, attempt)
+	}
+}
+
+func agentRefundTransactionId(agentTransactionId string) string {
+	return agentTransactionId + "-refund"
+}
+
+func (s *bankingService) increaseMemberCredit(userId int64, creditAmount model.Money, statementTypeName string, info string, bankAccountId *int64) error {
 
 	statementType, err := s.repoBanking.GetMemberStatementTypeByCode(statementTypeName)
 	if err != nil {
@@ -929,13 +1249,14 @@ func (s *bankingService) increaseMemberCredit(userId int64, creditAmount float64
 	body.StatementTypeId = statementType.Id
 	body.Info = info
 	body.Amount = creditAmount
+	body.BankAccountId = bankAccountId
 	if err := s.repoBanking.IncreaseMemberCredit(body); err != nil {
 		return internalServerError(err.Error())
 	}
 	return nil
 }
 
-func (s *bankingService) decreaseMemberCredit(userId int64, creditAmount float64, statementTypeName string, info string) error {
+func (s *bankingService) decreaseMemberCredit(userId int64, creditAmount model.Money, statementTypeName string, info string, bankAccountId *int64) error {
 
 	statementType, err := s.repoBanking.GetMemberStatementTypeByCode(statementTypeName)
 	if err != nil {
@@ -947,13 +1268,23 @@ func (s *bankingService) decreaseMemberCredit(userId int64, creditAmount float64
 	body.StatementTypeId = statementType.Id
 	body.Info = info
 	body.Amount = creditAmount
+	body.BankAccountId = bankAccountId
 	if err := s.repoBanking.DecreaseMemberCredit(body); err != nil {
 		return internalServerError(err.Error())
 	}
 	return nil
 }
 
-func (s *bankingService) ContinueAutoWithdrawTransaction(id int64) error {
+// ledgerBankAccountId is the system bank account the ledger posts against,
+// nil posts to the unassigned bank account.
+func ledgerBankAccountId(bankAccountId int64) *int64 {
+	if bankAccountId == 0 {
+		return nil
+	}
+	return &bankAccountId
+}
+
+func (s *bankingService) ContinueAutoWithdrawTransaction(ctx context.Context, id int64) error {
 
 	record, err := s.repoBanking.GetBankTransactionById(id)
 	if err != nil {
@@ -964,26 +1295,31 @@ func (s *bankingService) ContinueAutoWithdrawTransaction(id int64) error {
 	}
 	var fromAccountId = record.FromAccountId
 
-	// later : AutoSelect ? checkBalance ?
 	var autoWithdrawCondition *model.BankAutoWithdrawCondition
-	systemAccount, err := s.repoAccounting.GetWithdrawAccountById(fromAccountId)
-	if err == nil {
+	var systemAccount *model.BankAccount
+	if fromAccountId != 0 {
+		systemAccount, _ = s.repoAccounting.GetWithdrawAccountById(fromAccountId)
+	} else {
+		systemAccount = s.selectWithdrawAccount(record.CreditAmount)
+	}
+	if systemAccount != nil {
 		if condition, err := s.GetAutoWithdrawCondition(*record, *systemAccount); err == nil {
 			autoWithdrawCondition = condition
 		}
 	}
 
 	if autoWithdrawCondition != nil {
-		err := s.SetAutoWithdrawCondition(record.Id, autoWithdrawCondition)
-		log.Println(err)
-		if err := s.ProcessAutoWithdrawCondition(*autoWithdrawCondition); err != nil {
+		if err := s.SetAutoWithdrawCondition(record.Id, autoWithdrawCondition); err != nil {
+			log.Println(err)
+		}
+		if err := s.ProcessAutoWithdrawCondition(ctx, *autoWithdrawCondition); err != nil {
 			return internalServerError(err.Error())
 		}
 	}
 	return nil
 }
 
-func (s *bankingService) ConfirmWithdrawTransaction(id int64, req model.BankConfirmCreditWithdrawRequest) error {
+func (s *bankingService) ConfirmWithdrawTransaction(ctx context.Context, id int64, req model.BankConfirmCreditWithdrawRequest) error {
 
 	record, err := s.repoBanking.GetBankTransactionById(id)
 	if err != nil {
@@ -992,44 +1328,59 @@ func (s *bankingService) ConfirmWithdrawTransaction(id int64, req model.BankConf
 	if record.TransferType != "withdraw" && record.TransferType != "getcreditback" {
 		return badRequest("Transaction is not withdraw")
 	}
+	// getcreditback finishes here, a withdraw still waits for the transfer. A
+	// withdraw still in pending_agent has its debit resumed by
+	// debitWithdrawAgent below and is confirmed from pending_credit.
+	next := *record
+	if record.TransferType == "withdraw" && record.Status == model.BankTransactionPendingAgent {
+		next.Status = model.BankTransactionPendingCredit
+	}
+	transition, err := bankTransactionTransition(next, model.BankTransactionActionConfirmCredit)
+	if err != nil {
+		return err
+	}
 	var fromAccountId = record.FromAccountId
 	var updateData model.BankWithdrawTransactionConfirmBody
-	if record.TransferType == "getcreditback" {
-		updateData.Status = "finished"
-	} else {
-		updateData.Status = "pending_transfer"
-	}
+	updateData.Status = transition.To
 	updateData.ConfirmedAt = req.ConfirmedAt
 	updateData.ConfirmedByUserId = req.ConfirmedByUserId
 	updateData.ConfirmedByUsername = req.ConfirmedByUsername
-	if record.Status == "pending" || record.Status == "pending_credit" {
-		if req.FromAccountId != nil {
-			fromAccount, err := s.repoAccounting.GetBankAccountById(*req.FromAccountId)
-			if err != nil {
-				return badRequest("Invalid Bank Account")
-			}
+	if req.FromAccountId != nil {
+		fromAccount, err := s.repoAccounting.GetBankAccountById(*req.FromAccountId)
+		if err != nil {
+			return badRequest("Invalid Bank Account")
+		}
+		fromAccountId = fromAccount.Id
+		updateData.FromAccountId = &fromAccount.Id
+	} else if fromAccountId == 0 && record.TransferType == "withdraw" {
+		if fromAccount := s.selectWithdrawAccount(record.CreditAmount); fromAccount != nil {
 			fromAccountId = fromAccount.Id
 			updateData.FromAccountId = &fromAccount.Id
 		}
-		if req.CreditAmount != nil {
-			updateData.CreditAmount = *req.CreditAmount
-		} else {
-			updateData.CreditAmount = record.CreditAmount
-		}
-		if req.BankChargeAmount != nil {
-			updateData.BankChargeAmount = *req.BankChargeAmount
-		}
+	}
+	// the amount an admin confirms is the one debited at the agent and here
+	if req.CreditAmount != nil {
+		updateData.CreditAmount = *req.CreditAmount
 	} else {
-		return badRequest("Transaction is not pending")
+		updateData.CreditAmount = record.CreditAmount
+	}
+	creditAmount := updateData.CreditAmount
+	if req.BankChargeAmount != nil {
+		updateData.BankChargeAmount = *req.BankChargeAmount
 	}
 	jsonBefore, _ := json.Marshal(record)
 
-	// Check Credit/Balance
-	if err := s.repoBanking.CheckMemeberHasEnoughtCredit(record.UserId, record.CreditAmount); err != nil {
+	// Check Credit/Balance, the hold of this withdraw counts as its own
+	if err := s.repoBanking.CheckMemeberHasEnoughtCredit(record.UserId, record.Id, creditAmount); err != nil {
 		return internalServerError(err.Error())
 	}
 
-	// later : AutoSelect ? checkBalance ?
+	// the agent holds the playable wallet, nothing is written here unless it was debited
+	agentTransactionId, err := s.debitWithdrawAgent(ctx, *record, creditAmount)
+	if err != nil {
+		return err
+	}
+
 	var autoWithdrawCondition *model.BankAutoWithdrawCondition
 	systemAccount, err := s.repoAccounting.GetWithdrawAccountById(fromAccountId)
 	if err == nil {
@@ -1052,35 +1403,61 @@ func (s *bankingService) ConfirmWithdrawTransaction(id int64, req model.BankConf
 	createBody.ConfirmedAt = req.ConfirmedAt
 	createBody.ConfirmedByUserId = req.ConfirmedByUserId
 	createBody.ConfirmedByUsername = req.ConfirmedByUsername
-	// BOF : transaction
-	if actionId, err := s.repoBanking.CreateTransactionAction(createBody); err == nil {
-		if err := s.decreaseMemberCredit(record.UserId, record.CreditAmount, "withdraw", "ถอนเครดิต"); err != nil {
-			if err := s.repoBanking.RollbackTransactionAction(*actionId); err != nil {
-				return internalServerError(err.Error())
-			}
-			return internalServerError(err.Error())
+	if err := s.inTransaction(func(tx *bankingService) error {
+
+		if _, err := tx.repoBanking.CreateTransactionAction(createBody); err != nil {
+			return bankTransactionError(err)
 		}
-		if err := s.repoBanking.ConfirmPendingWithdrawTransaction(id, updateData); err != nil {
-			if err := s.repoBanking.RollbackTransactionAction(*actionId); err != nil {
-				return internalServerError(err.Error())
-			}
+		if err := tx.repoBanking.ConfirmPendingWithdrawTransaction(id, transition.From, updateData); err != nil {
+			return bankTransactionError(err)
+		}
+		// the hold ends first, the debit may not take held credit
+		if err := tx.repoBanking.CaptureMemberCredit(record.Id); err != nil {
 			return internalServerError(err.Error())
 		}
-		// EOF : transaction
-		if autoWithdrawCondition != nil {
-			err := s.SetAutoWithdrawCondition(record.Id, autoWithdrawCondition)
-			log.Println(err)
-			if err := s.ProcessAutoWithdrawCondition(*autoWithdrawCondition); err != nil {
-				return internalServerError(err.Error())
-			}
+		if err := tx.decreaseMemberCredit(record.UserId, creditAmount, record.TransferType, "ถอนเครดิต", ledgerBankAccountId(fromAccountId)); err != nil {
+			return err
 		}
-	} else {
-		return internalServerError(err.Error())
+		return nil // COMMIT
+	}); err != nil {
+		// a withdraw keeps its agent debit for the next confirm or a cancel
+		if record.TransferType != "withdraw" {
+			s.refundUnconfirmedWithdraw(*record, transition, creditAmount, agentTransactionId)
+		}
+		return err
 	}
 
+	// the transfer runs on its own, after the credit is committed
+	if autoWithdrawCondition != nil {
+		if err := s.SetAutoWithdrawCondition(record.Id, autoWithdrawCondition); err != nil {
+			log.Println(err)
+		}
+		if err := s.ProcessAutoWithdrawCondition(ctx, *autoWithdrawCondition); err != nil {
+			return internalServerError(err.Error())
+		}
+	}
 	return nil
 }
 
+// refundUnconfirmedWithdraw gives the agent debit of a getcreditback back
+// after the local confirm failed. A confirm that lost to another one shares its debit, the
+// same TransactionId was replayed, so nothing goes back once the
+// transaction moved on to the confirmed status.
+func (s *bankingService) refundUnconfirmedWithdraw(record model.BankTransaction, transition *model.BankTransactionTransition, creditAmount model.Money, agentTransactionId string) {
+
+	current, err := s.repoBanking.GetBankTransactionById(record.Id)
+	if err != nil {
+		log.Printf("withdraw %d agent refund: %s", record.Id, err.Error())
+		return
+	}
+	if current.Status == transition.To || current.Status == model.BankTransactionFinished {
+		return
+	}
+	if err := s.refundAgentCredit(record.UserId, creditAmount, record.Id, agentTransactionId); err != nil {
+		log.Printf("withdraw %d agent refund: %s", record.Id, err.Error())
+	}
+}
+
 func (s *bankingService) ConfirmWithdrawTransfer(id int64, req model.BankConfirmTransferWithdrawRequest) error {
 
 	record, err := s.repoBanking.GetBankTransactionById(id)
@@ -1090,30 +1467,34 @@ func (s *bankingService) ConfirmWithdrawTransfer(id int64, req model.BankConfirm
 	if record.TransferType != "withdraw" {
 		return badRequest("Transaction is not withdraw")
 	}
+	transition, err := bankTransactionTransition(*record, model.BankTransactionActionConfirmTransfer)
+	if err != nil {
+		return err
+	}
 	var fromAccountId = record.FromAccountId
 	var updateData model.BankWithdrawTransactionConfirmBody
-	updateData.Status = "finished"
+	updateData.Status = transition.To
 	updateData.ConfirmedAt = req.ConfirmedAt
 	updateData.ConfirmedByUserId = req.ConfirmedByUserId
 	updateData.ConfirmedByUsername = req.ConfirmedByUsername
-	if record.Status == "pending_transfer" {
-		if req.FromAccountId != nil {
-			fromAccount, err := s.repoAccounting.GetBankAccountById(*req.FromAccountId)
-			if err != nil {
-				return badRequest("Invalid Bank Account")
-			}
+	if req.FromAccountId != nil {
+		fromAccount, err := s.repoAccounting.GetBankAccountById(*req.FromAccountId)
+		if err != nil {
+			return badRequest("Invalid Bank Account")
+		}
+		fromAccountId = fromAccount.Id
+		updateData.FromAccountId = &fromAccountId
+	} else if fromAccountId == 0 {
+		if fromAccount := s.selectWithdrawAccount(record.CreditAmount); fromAccount != nil {
 			fromAccountId = fromAccount.Id
 			updateData.FromAccountId = &fromAccountId
 		}
-		if req.BankChargeAmount != nil {
-			updateData.BankChargeAmount = *req.BankChargeAmount
-		}
-	} else {
-		return badRequest("Transaction is not transfer pending")
+	}
+	if req.BankChargeAmount != nil {
+		updateData.BankChargeAmount = *req.BankChargeAmount
 	}
 	jsonBefore, _ := json.Marshal(record)
 
-	// later : AutoSelectAccount ? checkBalance ?
 	systemAccount, err := s.repoAccounting.GetWithdrawAccountById(fromAccountId)
 	if err != nil {
 		if err.Error() == recordNotFound {
@@ -1154,7 +1535,7 @@ func (s *bankingService) ConfirmWithdrawTransfer(id int64, req model.BankConfirm
 			var body model.ExternalAccountTransferBody
 			body.AccountForm = systemAccount.AccountNumber
 			body.AccountTo = record.ToAccountNumber
-			body.Amount = strconv.FormatFloat(record.CreditAmount, 'E', -1, 64)
+			body.Amount = record.CreditAmount.String()
 			body.BankCode = record.ToBankCode
 			body.Pin = systemAccount.PinCode
 			if err := s.repoBanking.TransferExternalAccount(body); err != nil {
@@ -1166,11 +1547,11 @@ func (s *bankingService) ConfirmWithdrawTransfer(id int64, req model.BankConfirm
 			// later : read from FASTBANK reponse, updateData.TransferAt = time.Now()
 		}
 		updateData.TransferAt = time.Now()
-		if err := s.repoBanking.ConfirmPendingWithdrawTransfer(id, updateData); err != nil {
+		if err := s.repoBanking.ConfirmPendingWithdrawTransfer(id, transition.From, updateData); err != nil {
 			if err := s.repoBanking.RollbackTransactionAction(*actionId); err != nil {
 				return internalServerError(err.Error())
 			}
-			return internalServerError(err.Error())
+			return bankTransactionError(err)
 		}
 		// EOF : commit transaction
 	} else {
@@ -1197,12 +1578,14 @@ func (s *bankingService) RemoveFinishedTransaction(id int64, data model.BankTran
 	if err != nil {
 		return internalServerError(err.Error())
 	}
-	if record.Status != "finished" {
-		return badRequest("Transaction is not finished")
+	transition, err := bankTransactionTransition(*record, model.BankTransactionActionRemove)
+	if err != nil {
+		return err
 	}
+	data.Status = transition.To
 
-	if err := s.repoBanking.RemoveFinishedTransaction(id, data); err != nil {
-		return internalServerError(err.Error())
+	if err := s.repoBanking.RemoveFinishedTransaction(id, transition.From, data); err != nil {
+		return bankTransactionError(err)
 	}
 	return nil
 }
@@ -1360,7 +1743,7 @@ func (s *bankingService) GetMemberStatements(req model.MemberStatementListReques
 	return records, nil
 }
 
-func (s *bankingService) ProcessMemberDepositCredit(userId int64, amount float64) error {
+func (s *bankingService) ProcessMemberDepositCredit(userId int64, amount model.Money) error {
 
 	statementCode := "deposit"
 	var body model.MemberStatementCreateBody
@@ -1387,7 +1770,7 @@ func (s *bankingService) ProcessMemberDepositCredit(userId int64, amount float64
 	return nil
 }
 
-func (s *bankingService) ProcessMemberWithdrawCredit(userId int64, amount float64) error {
+func (s *bankingService) ProcessMemberWithdrawCredit(userId int64, amount model.Money) error {
 
 	statementCode := "withdraw"
 	var body model.MemberStatementCreateBody
@@ -1414,7 +1797,7 @@ func (s *bankingService) ProcessMemberWithdrawCredit(userId int64, amount float6
 	return nil
 }
 
-func (s *bankingService) ProcessMemberBonusCredit(userId int64, amount float64) error {
+func (s *bankingService) ProcessMemberBonusCredit(userId int64, amount model.Money) error {
 
 	statementCode := "bonus"
 	var body model.MemberStatementCreateBody
@@ -1441,7 +1824,7 @@ func (s *bankingService) ProcessMemberBonusCredit(userId int64, amount float64)
 	return nil
 }
 
-func (s *bankingService) ProcessMemberGetbackCredit(userId int64, amount float64) error {
+func (s *bankingService) ProcessMemberGetbackCredit(userId int64, amount model.Money) error {
 
 	statementCode := "getcreditback"
 	var body model.MemberStatementCreateBody
@@ -1467,3 +1850,168 @@ func (s *bankingService) ProcessMemberGetbackCredit(userId int64, amount float64
 	}
 	return nil
 }
+
+// SyncMemberCredits compares the local credit of every registered member, or
+// only body.UserId, with the agent wallet and writes an adjustment statement
+// for each difference unless body.DryRun. Seamless members, members with a
+// deposit or withdraw in progress and agent balances under the reserved
+// credit are skipped.
+func (s *bankingService) SyncMemberCredits(ctx context.Context, body model.MemberCreditSyncBody) (*model.MemberCreditSyncResult, error) {
+
+	var statementTypeId int64
+	if !body.DryRun {
+		statementType, err := s.repoBanking.GetMemberStatementTypeByCode("adjustment")
+		if err != nil {
+			return nil, internalServerError(err.Error())
+		}
+		statementTypeId = statementType.Id
+	}
+
+	result := model.MemberCreditSyncResult{Mismatches: []model.MemberCreditSyncItem{}}
+
+	if body.UserId != nil {
+		member, err := s.repoBanking.GetMemberById(*body.UserId)
+		if err != nil {
+			if err.Error() == recordNotFound {
+				return nil, notFound(memberNotFound)
+			}
+			return nil, internalServerError(err.Error())
+		}
+		if member.Username == "" {
+			return nil, badRequest(AgentUserNotRegistered)
+		}
+		s.syncMemberCredit(ctx, *member, statementTypeId, body.DryRun, &result)
+		return &result, nil
+	}
+
+	var lastId int64
+	for {
+		// a canceled request stops between batches, the members done stay synced
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		members, err := s.repoBanking.GetMembersForCreditSync(lastId, memberCreditSyncBatch)
+		if err != nil {
+			return nil, internalServerError(err.Error())
+		}
+		for _, member := range members {
+			s.syncMemberCredit(ctx, member, statementTypeId, body.DryRun, &result)
+			lastId = member.Id
+		}
+		if len(members) < memberCreditSyncBatch {
+			break
+		}
+	}
+
+	return &result, nil
+}
+
+func (s *bankingService) syncMemberCredit(ctx context.Context, member model.Member, statementTypeId int64, dryRun bool, result *model.MemberCreditSyncResult) {
+
+	result.Checked++
+
+	item := model.MemberCreditSyncItem{
+		UserId:     member.Id,
+		MemberCode: member.MemberCode,
+		Username:   member.Username,
+		Credit:     member.Credit,
+	}
+
+	if err := s.repoBanking.CheckMemberCreditSync(member.Id); err != nil {
+		if message, ok := memberCreditSyncSkipped(err); ok {
+			result.Skipped++
+			item.Error = message
+		} else {
+			result.Failed++
+			item.Error = err.Error()
+		}
+		result.Mismatches = append(result.Mismatches, item)
+		return
+	}
+
+	agentData := model.AGCBalance{
+		PlayerName: member.Username,
+	}
+
+	response, err := s.repoAgentConnect.Balance(ctx, agentData)
+	if err != nil {
+		result.Failed++
+		item.Error = agentError(err).Error()
+		result.Mismatches = append(result.Mismatches, item)
+		return
+	}
+
+	item.AgentBalance = model.NewMoney(response.Data.Balance)
+	item.Difference = item.AgentBalance - member.Credit
+	if item.Difference == 0 {
+		result.Matched++
+		return
+	}
+	if item.AgentBalance < member.CreditReserved {
+		// the held withdraws must stay covered, an admin looks at it
+		result.Skipped++
+		item.Error = MemberCreditUnderReserved
+		result.Mismatches = append(result.Mismatches, item)
+		return
+	}
+
+	if !dryRun {
+		info := fmt.Sprintf("ปรับยอดตามเอเย่นต์ %s", item.Difference)
+		adjusted, err := s.repoBanking.AdjustMemberCredit(member.Id, statementTypeId, member.Credit, item.AgentBalance, info)
+		message, skipped := memberCreditSyncSkipped(err)
+		switch {
+		case skipped:
+			result.Skipped++
+			item.Error = message
+		case err != nil:
+			result.Failed++
+			item.Error = err.Error()
+		case !adjusted:
+			// a deposit or withdraw ran meanwhile, the next sync compares again
+			result.Failed++
+			item.Error = MemberCreditMoved
+		default:
+			result.Adjusted++
+			item.Adjusted = true
+		}
+	}
+	result.Mismatches = append(result.Mismatches, item)
+}
+
+// memberCreditSyncSkipped is the reason a member is left as is by the sync.
+func memberCreditSyncSkipped(err error) (string, bool) {
+	switch {
+	case errors.Is(err, repository.ErrMemberCreditSeamless):
+		return MemberCreditSeamless, true
+	case errors.Is(err, repository.ErrMemberCreditInProgress):
+		return MemberCreditInProgress, true
+	case errors.Is(err, repository.ErrMemberCreditUnderReserved):
+		return MemberCreditUnderReserved, true
+	}
+	return "", false
+}
+
+// StartMemberCreditSync runs SyncMemberCredits over every member each
+// interval in the background and logs the mismatches.
+func (s *bankingService) StartMemberCreditSync(interval time.Duration) {
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if !atomic.CompareAndSwapInt32(&memberCreditSyncing, 0, 1) {
+				continue
+			}
+			result, err := s.SyncMemberCredits(context.Background(), model.MemberCreditSyncBody{})
+			if err != nil {
+				log.Println("member credit sync:", err.Error())
+			} else {
+				log.Printf("member credit sync: checked %d matched %d adjusted %d skipped %d failed %d", result.Checked, result.Matched, result.Adjusted, result.Skipped, result.Failed)
+				for _, item := range result.Mismatches {
+					log.Printf("member credit sync: %s credit %s agent %s %s", item.Username, item.Credit, item.AgentBalance, item.Error)
+				}
+			}
+			atomic.StoreInt32(&memberCreditSyncing, 0)
+		}
+	}()
+}
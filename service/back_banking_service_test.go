@@ -0,0 +1,688 @@
+package service
+
+import (
+	"context"
+	"cybergame-api/agent/fake"
+	"cybergame-api/model"
+	"cybergame-api/repository"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// memoryBankingRepository holds the members, transactions and holds a
+// withdraw goes through, other BankingRepository methods are not used here.
+type memoryBankingRepository struct {
+	repository.BankingRepository
+
+	mutex        sync.Mutex
+	members      map[int64]model.Member
+	transactions map[int64]model.BankTransaction
+	holds        map[int64]model.Money
+	// ledger has the entries of every credit movement, member accounts have
+	// the user id and contra accounts the bank account id, 0 for house
+	ledger   []model.LedgerEntry
+	journals *memoryAgentCallRepository
+	// loseConfirm answers every confirm and cancel update as lost to another request
+	loseConfirm bool
+	// failDebit refuses the local credit debit
+	failDebit bool
+}
+
+func newMemoryBankingRepository() *memoryBankingRepository {
+	return &memoryBankingRepository{
+		members:      make(map[int64]model.Member),
+		transactions: make(map[int64]model.BankTransaction),
+		holds:        make(map[int64]model.Money),
+	}
+}
+
+func (r *memoryBankingRepository) snapshot() func() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	members := make(map[int64]model.Member)
+	for id, member := range r.members {
+		members[id] = member
+	}
+	transactions := make(map[int64]model.BankTransaction)
+	for id, transaction := range r.transactions {
+		transactions[id] = transaction
+	}
+	holds := make(map[int64]model.Money)
+	for id, amount := range r.holds {
+		holds[id] = amount
+	}
+	ledger := append([]model.LedgerEntry{}, r.ledger...)
+	return func() {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		r.members, r.transactions, r.holds, r.ledger = members, transactions, holds, ledger
+	}
+}
+
+func (r *memoryBankingRepository) GetBankTransactionById(id int64) (*model.BankTransaction, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	transaction, ok := r.transactions[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &transaction, nil
+}
+
+func (r *memoryBankingRepository) GetMemberById(id int64) (*model.Member, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	member, ok := r.members[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &member, nil
+}
+
+func (r *memoryBankingRepository) CheckMemeberHasEnoughtCredit(memberId int64, transactionId int64, creditAmount model.Money) error {
+	member, err := r.GetMemberById(memberId)
+	if err != nil {
+		return err
+	}
+	r.mutex.Lock()
+	held := r.holds[transactionId]
+	r.mutex.Unlock()
+	if member.Credit-member.CreditReserved+held < creditAmount {
+		return errors.New("INSUFFICIENT_CREDIT")
+	}
+	return nil
+}
+
+func (r *memoryBankingRepository) CreateTransactionAction(data model.CreateBankTransactionActionBody) (*int64, error) {
+	id := data.TransactionId
+	return &id, nil
+}
+
+func (r *memoryBankingRepository) updateStatus(id int64, fromStatus []string, status string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	transaction := r.transactions[id]
+	if r.loseConfirm {
+		return repository.ErrBankTransactionStatusChanged
+	}
+	for _, from := range fromStatus {
+		if transaction.Status == from {
+			transaction.Status = status
+			r.transactions[id] = transaction
+			return nil
+		}
+	}
+	return repository.ErrBankTransactionStatusChanged
+}
+
+func (r *memoryBankingRepository) ConfirmPendingWithdrawTransaction(id int64, fromStatus []string, data model.BankWithdrawTransactionConfirmBody) error {
+	return r.updateStatus(id, fromStatus, data.Status)
+}
+
+func (r *memoryBankingRepository) CancelPendingTransaction(id int64, fromStatus []string, data model.BankTransactionCancelBody) error {
+	return r.updateStatus(id, fromStatus, data.Status)
+}
+
+func (r *memoryBankingRepository) UpdateBankTransactionStatus(id int64, fromStatus []string, status string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	transaction := r.transactions[id]
+	for _, from := range fromStatus {
+		if transaction.Status == from {
+			transaction.Status = status
+			r.transactions[id] = transaction
+			return nil
+		}
+	}
+	return repository.ErrBankTransactionStatusChanged
+}
+
+func (r *memoryBankingRepository) GetWithdrawAgentDebit(agentTransactionId string) (*model.AgentCallJournal, error) {
+	journal, err := r.journals.GetAgentCallJournalByTransactionId(agentTransactionId)
+	if err != nil {
+		return nil, nil
+	}
+	return journal, nil
+}
+
+func (r *memoryBankingRepository) settleHold(transactionId int64) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	amount, ok := r.holds[transactionId]
+	if !ok {
+		return nil
+	}
+	delete(r.holds, transactionId)
+	userId := r.transactions[transactionId].UserId
+	member := r.members[userId]
+	member.CreditReserved -= amount
+	r.members[userId] = member
+	return nil
+}
+
+func (r *memoryBankingRepository) ReleaseMemberCredit(transactionId int64) error {
+	return r.settleHold(transactionId)
+}
+
+func (r *memoryBankingRepository) CaptureMemberCredit(transactionId int64) error {
+	return r.settleHold(transactionId)
+}
+
+func (r *memoryBankingRepository) GetMemberStatementTypeByCode(code string) (*model.MemberStatementType, error) {
+	return &model.MemberStatementType{Id: 1, Code: code}, nil
+}
+
+// postLedger writes the posting of a credit movement like the repository,
+// the caller holds the mutex.
+func (r *memoryBankingRepository) postLedger(body model.MemberStatementCreateBody, amount model.Money) error {
+	var contraAccountId int64
+	if body.BankAccountId != nil {
+		contraAccountId = *body.BankAccountId
+	}
+	var transactionId int64 = 1
+	if len(r.ledger) > 0 {
+		transactionId = r.ledger[len(r.ledger)-1].TransactionId + 1
+	}
+	posting := model.LedgerPosting{UserId: body.UserId, Info: body.Info, Amount: amount, BankAccountId: body.BankAccountId}
+	entries, err := repository.LedgerEntries(posting, transactionId, body.UserId, contraAccountId)
+	if err != nil {
+		return err
+	}
+	r.ledger = append(r.ledger, entries...)
+	return nil
+}
+
+func (r *memoryBankingRepository) IncreaseMemberCredit(body model.MemberStatementCreateBody) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	member := r.members[body.UserId]
+	member.Credit += body.Amount
+	r.members[body.UserId] = member
+	return r.postLedger(body, body.Amount)
+}
+
+func (r *memoryBankingRepository) DecreaseMemberCredit(body model.MemberStatementCreateBody) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	member := r.members[body.UserId]
+	if r.failDebit || member.Credit-member.CreditReserved < body.Amount {
+		return errors.New("NOT_ENOUGH_CREDIT")
+	}
+	member.Credit -= body.Amount
+	r.members[body.UserId] = member
+	return r.postLedger(body, -body.Amount)
+}
+
+// memoryLedgerRepository verifies the ledger of memoryBankingRepository. The
+// members start without an opening posting, their credit is not compared.
+type memoryLedgerRepository struct {
+	repository.LedgerRepository
+	banking *memoryBankingRepository
+}
+
+func (r memoryLedgerRepository) VerifyLedger() (*model.LedgerVerifyResult, error) {
+	r.banking.mutex.Lock()
+	defer r.banking.mutex.Unlock()
+
+	result := model.LedgerVerifyResult{Unbalanced: []model.LedgerUnbalanced{}}
+	sums := make(map[int64]*model.LedgerUnbalanced)
+	var ids []int64
+	for _, entry := range r.banking.ledger {
+		result.TotalDebit += entry.Debit
+		result.TotalCredit += entry.Credit
+		sum, ok := sums[entry.TransactionId]
+		if !ok {
+			sum = &model.LedgerUnbalanced{TransactionId: entry.TransactionId}
+			sums[entry.TransactionId] = sum
+			ids = append(ids, entry.TransactionId)
+		}
+		sum.Debit += entry.Debit
+		sum.Credit += entry.Credit
+	}
+	for _, id := range ids {
+		if sums[id].Debit != sums[id].Credit {
+			result.Unbalanced = append(result.Unbalanced, *sums[id])
+		}
+	}
+	result.IsBalanced = result.Balanced()
+	return &result, nil
+}
+
+// memoryAccountingRepository has no system account, withdraws stay manual.
+type memoryAccountingRepository struct {
+	repository.AccountingRepository
+}
+
+func (r memoryAccountingRepository) GetBankAccountById(id int64) (*model.BankAccount, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r memoryAccountingRepository) GetWithdrawAccountById(id int64) (*model.BankAccount, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r memoryAccountingRepository) GetAutoWithdrawAccounts() ([]model.BankAccount, error) {
+	return nil, nil
+}
+
+// memoryUnitOfWork puts the banking repository back as it was when fn fails.
+type memoryUnitOfWork struct {
+	banking    *memoryBankingRepository
+	accounting repository.AccountingRepository
+}
+
+func (u memoryUnitOfWork) WithinTransaction(fn func(repos repository.UnitOfWorkRepositories) error) error {
+	rollback := u.banking.snapshot()
+	if err := fn(repository.UnitOfWorkRepositories{Banking: u.banking, Accounting: u.accounting}); err != nil {
+		rollback()
+		return err
+	}
+	return nil
+}
+
+type withdrawTest struct {
+	server   *fake.Server
+	banking  *memoryBankingRepository
+	journals *memoryAgentCallRepository
+	service  *bankingService
+}
+
+const testWithdrawId = 7
+const testMemberId = 1
+
+// newWithdrawTest has a member with 500 at the agent and here, 200 of it held
+// by a pending withdraw.
+func newWithdrawTest(t *testing.T) *withdrawTest {
+
+	server, connect := startFakeAgent(t)
+	server.AddPlayer("member1", "secret", 500)
+
+	banking := newMemoryBankingRepository()
+	banking.members[testMemberId] = model.Member{Id: testMemberId, Username: "member1", Credit: model.NewMoney(500), CreditReserved: model.NewMoney(200)}
+	banking.transactions[testWithdrawId] = model.BankTransaction{Id: testWithdrawId, UserId: testMemberId, TransferType: "withdraw", Status: model.BankTransactionPending, CreditAmount: model.NewMoney(200)}
+	banking.holds[testWithdrawId] = model.NewMoney(200)
+
+	accounting := memoryAccountingRepository{}
+	journals := &memoryAgentCallRepository{banking: banking}
+	banking.journals = journals
+	uow := memoryUnitOfWork{banking, accounting}
+	service := &bankingService{
+		repoBanking:      banking,
+		repoAccounting:   accounting,
+		repoAgentConnect: connect,
+		agentCall:        NewAgentCallService(journals, connect),
+		accounting:       NewAccountingService(accounting, uow),
+		uow:              uow,
+	}
+
+	return &withdrawTest{server, banking, journals, service}
+}
+
+func (w *withdrawTest) confirm() error {
+	return w.service.ConfirmWithdrawTransaction(context.Background(), testWithdrawId, model.BankConfirmCreditWithdrawRequest{ConfirmedAt: time.Now(), ConfirmedByUsername: "admin"})
+}
+
+func (w *withdrawTest) check(t *testing.T, status string, agentBalance float64, credit float64, reserved float64) {
+	t.Helper()
+	transaction, _ := w.banking.GetBankTransactionById(testWithdrawId)
+	member, _ := w.banking.GetMemberById(testMemberId)
+	if transaction.Status != status {
+		t.Errorf("status %s, want %s", transaction.Status, status)
+	}
+	if balance := playerBalance(t, w.server, "member1"); balance != agentBalance {
+		t.Errorf("agent balance %v, want %v", balance, agentBalance)
+	}
+	if member.Credit != model.NewMoney(credit) || member.CreditReserved != model.NewMoney(reserved) {
+		t.Errorf("credit %s reserved %s, want %.2f reserved %.2f", member.Credit, member.CreditReserved, credit, reserved)
+	}
+}
+
+func TestConfirmWithdrawTransaction(t *testing.T) {
+
+	w := newWithdrawTest(t)
+	if err := w.confirm(); err != nil {
+		t.Fatalf("confirm: %s", err.Error())
+	}
+	w.check(t, model.BankTransactionPendingTransfer, 300, 300, 0)
+	if _, ok := w.server.Transfer("7"); !ok {
+		t.Fatalf("agent has no transfer 7")
+	}
+
+	// a second confirm finds the transaction moved on
+	if err := w.confirm(); responseCode(err) != http.StatusConflict {
+		t.Fatalf("confirm again: got %v, want 409", err)
+	}
+	w.check(t, model.BankTransactionPendingTransfer, 300, 300, 0)
+}
+
+func TestConfirmWithdrawTransactionLocalFailure(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		setup    func(banking *memoryBankingRepository)
+		wantCode int
+	}{
+		{"status changed", func(banking *memoryBankingRepository) { banking.loseConfirm = true }, http.StatusConflict},
+		{"debit refused", func(banking *memoryBankingRepository) { banking.failDebit = true }, http.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+
+			w := newWithdrawTest(t)
+			test.setup(w.banking)
+
+			if err := w.confirm(); responseCode(err) != test.wantCode {
+				t.Fatalf("confirm: got %v, want %d", err, test.wantCode)
+			}
+			// the withdraw keeps its agent debit and its hold
+			w.check(t, model.BankTransactionPendingCredit, 300, 500, 200)
+			if _, err := w.journals.GetAgentCallJournalByTransactionId("7-refund"); err == nil {
+				t.Fatalf("journal has a refund 7-refund, want none")
+			}
+
+			// the next confirm takes the local credit without a second debit
+			w.banking.loseConfirm, w.banking.failDebit = false, false
+			if err := w.confirm(); err != nil {
+				t.Fatalf("confirm again: %s", err.Error())
+			}
+			w.check(t, model.BankTransactionPendingTransfer, 300, 300, 0)
+			if calls := w.server.Calls(fake.PathWithdraw); calls != 1 {
+				t.Fatalf("agent got %d withdraws, want 1", calls)
+			}
+		})
+	}
+}
+
+// TestDebitRequestedWithdraw is the debit a withdraw request sends, the
+// confirm afterwards only takes the local credit.
+func TestDebitRequestedWithdraw(t *testing.T) {
+
+	w := newWithdrawTest(t)
+	record := w.banking.transactions[testWithdrawId]
+	record.Status = model.BankTransactionPendingAgent
+	w.banking.transactions[testWithdrawId] = record
+
+	if _, err := w.service.debitWithdrawAgent(context.Background(), record, record.CreditAmount); err != nil {
+		t.Fatalf("debit: %s", err.Error())
+	}
+	w.check(t, model.BankTransactionPendingCredit, 300, 500, 200)
+
+	// another amount than the one debited is refused
+	other := model.NewMoney(150)
+	err := w.service.ConfirmWithdrawTransaction(context.Background(), testWithdrawId, model.BankConfirmCreditWithdrawRequest{CreditAmount: &other, ConfirmedAt: time.Now()})
+	if responseCode(err) != http.StatusBadRequest {
+		t.Fatalf("confirm 150: got %v, want 400", err)
+	}
+
+	if err := w.confirm(); err != nil {
+		t.Fatalf("confirm: %s", err.Error())
+	}
+	w.check(t, model.BankTransactionPendingTransfer, 300, 300, 0)
+	if calls := w.server.Calls(fake.PathWithdraw); calls != 1 {
+		t.Fatalf("agent got %d withdraws, want 1", calls)
+	}
+}
+
+// TestCancelDebitedWithdraw gives back the debit of a withdraw request that
+// was not confirmed.
+func TestCancelDebitedWithdraw(t *testing.T) {
+
+	w := newWithdrawTest(t)
+	record := w.banking.transactions[testWithdrawId]
+	record.Status = model.BankTransactionPendingAgent
+	w.banking.transactions[testWithdrawId] = record
+	if _, err := w.service.debitWithdrawAgent(context.Background(), record, record.CreditAmount); err != nil {
+		t.Fatalf("debit: %s", err.Error())
+	}
+
+	if err := w.service.CancelPendingTransaction(context.Background(), testWithdrawId, model.BankTransactionCancelBody{CanceledAt: time.Now()}); err != nil {
+		t.Fatalf("cancel: %s", err.Error())
+	}
+	w.check(t, model.BankTransactionCanceled, 500, 500, 0)
+	if _, ok := w.server.Transfer("7-refund"); !ok {
+		t.Fatalf("agent has no refund 7-refund")
+	}
+}
+
+// TestConfirmWithdrawCountsOtherHolds checks the credit another withdraw holds
+// is not confirmed for this one.
+func TestConfirmWithdrawCountsOtherHolds(t *testing.T) {
+
+	w := newWithdrawTest(t)
+	w.banking.members[testMemberId] = model.Member{Id: testMemberId, Username: "member1", Credit: model.NewMoney(400), CreditReserved: model.NewMoney(400)}
+	w.banking.holds[testWithdrawId+1] = model.NewMoney(200)
+
+	more := model.NewMoney(250)
+	err := w.service.ConfirmWithdrawTransaction(context.Background(), testWithdrawId, model.BankConfirmCreditWithdrawRequest{CreditAmount: &more, ConfirmedAt: time.Now()})
+	if responseCode(err) != http.StatusInternalServerError {
+		t.Fatalf("confirm 250: got %v, want 500", err)
+	}
+	if calls := w.server.Calls(fake.PathWithdraw); calls != 0 {
+		t.Fatalf("agent got %d withdraws, want 0", calls)
+	}
+
+	// its own hold covers the requested amount
+	if err := w.confirm(); err != nil {
+		t.Fatalf("confirm: %s", err.Error())
+	}
+	w.check(t, model.BankTransactionPendingTransfer, 300, 200, 200)
+}
+
+func TestConfirmWithdrawTransactionAfterTimeout(t *testing.T) {
+
+	w := newWithdrawTest(t)
+	// the agent takes the money but the answer is lost
+	w.server.InjectFailure(fake.PathWithdraw, fake.Failure{Status: http.StatusGatewayTimeout, Apply: true})
+
+	if err := w.confirm(); responseCode(err) != http.StatusBadGateway && responseCode(err) != http.StatusGatewayTimeout {
+		t.Fatalf("confirm: got %v, want 502 or 504", err)
+	}
+	// the withdraw waits in pending_agent with the debit journaled for a retry
+	w.check(t, model.BankTransactionPendingAgent, 300, 500, 200)
+	if journal := w.journals.journal(t, "7"); journal.Status != agentCallRetry {
+		t.Fatalf("debit journal %s, want retry", journal.Status)
+	}
+
+	// confirming again settles the same debit, the agent is not debited twice
+	if err := w.confirm(); err != nil {
+		t.Fatalf("confirm again: %s", err.Error())
+	}
+	w.check(t, model.BankTransactionPendingTransfer, 300, 300, 0)
+	if calls := w.server.Calls(fake.PathWithdraw); calls != 2 {
+		t.Fatalf("agent got %d withdraws, want 2", calls)
+	}
+}
+
+func TestCancelConfirmedWithdraw(t *testing.T) {
+
+	w := newWithdrawTest(t)
+	if err := w.confirm(); err != nil {
+		t.Fatalf("confirm: %s", err.Error())
+	}
+
+	if err := w.service.CancelPendingTransaction(context.Background(), testWithdrawId, model.BankTransactionCancelBody{CanceledAt: time.Now()}); err != nil {
+		t.Fatalf("cancel: %s", err.Error())
+	}
+	w.check(t, model.BankTransactionCanceled, 500, 500, 0)
+	if _, ok := w.server.Transfer("7-refund"); !ok {
+		t.Fatalf("agent has no refund 7-refund")
+	}
+}
+
+func TestCancelWithdrawAfterTimeout(t *testing.T) {
+
+	w := newWithdrawTest(t)
+	w.server.InjectFailure(fake.PathWithdraw, fake.Failure{Status: http.StatusGatewayTimeout, Apply: true})
+	if err := w.confirm(); err == nil {
+		t.Fatalf("confirm: got no error, want 502 or 504")
+	}
+
+	// the debit may still land, the cancel waits for the journal
+	cancel := func() error {
+		return w.service.CancelPendingTransaction(context.Background(), testWithdrawId, model.BankTransactionCancelBody{CanceledAt: time.Now()})
+	}
+	if err := cancel(); responseCode(err) != http.StatusConflict {
+		t.Fatalf("cancel: got %v, want 409", err)
+	}
+	w.check(t, model.BankTransactionPendingAgent, 300, 500, 200)
+
+	// the retry settles the debit the agent took, the cancel then gives it back
+	journal := w.journals.journal(t, "7")
+	if err := w.service.agentCall.RetryAgentCall(context.Background(), journal.Id); err != nil {
+		t.Fatalf("retry: %s", err.Error())
+	}
+	if err := cancel(); err != nil {
+		t.Fatalf("cancel after the retry: %s", err.Error())
+	}
+	w.check(t, model.BankTransactionCanceled, 500, 500, 0)
+	if _, ok := w.server.Transfer("7-refund"); !ok {
+		t.Fatalf("agent has no refund 7-refund")
+	}
+}
+
+func TestCancelWithdrawRefusedByAgent(t *testing.T) {
+
+	w := newWithdrawTest(t)
+	w.server.InjectFailure(fake.PathWithdraw, fake.Failure{Code: 9999})
+	if err := w.confirm(); responseCode(err) != http.StatusBadRequest {
+		t.Fatalf("confirm: got %v, want 400", err)
+	}
+	w.check(t, model.BankTransactionPendingAgent, 500, 500, 200)
+
+	// the agent took nothing, the cancel only releases the hold
+	if err := w.service.CancelPendingTransaction(context.Background(), testWithdrawId, model.BankTransactionCancelBody{CanceledAt: time.Now()}); err != nil {
+		t.Fatalf("cancel: %s", err.Error())
+	}
+	w.check(t, model.BankTransactionCanceled, 500, 500, 0)
+	if calls := w.server.Calls(fake.PathDeposit); calls != 0 {
+		t.Fatalf("agent got %d deposits, want 0", calls)
+	}
+}
+
+func TestCancelWithdrawWhileDebitRunning(t *testing.T) {
+
+	w := newWithdrawTest(t)
+	w.server.InjectFailure(fake.PathWithdraw, fake.Failure{Status: http.StatusBadGateway, Delay: 300 * time.Millisecond, Apply: true})
+
+	confirmed := make(chan error, 1)
+	go func() { confirmed <- w.confirm() }()
+	time.Sleep(100 * time.Millisecond)
+
+	err := w.service.CancelPendingTransaction(context.Background(), testWithdrawId, model.BankTransactionCancelBody{CanceledAt: time.Now()})
+	if responseCode(err) != http.StatusConflict {
+		t.Fatalf("cancel during the debit: got %v, want 409", err)
+	}
+	if err := <-confirmed; responseCode(err) != http.StatusBadGateway {
+		t.Fatalf("confirm: got %v, want 502", err)
+	}
+	w.check(t, model.BankTransactionPendingAgent, 300, 500, 200)
+}
+
+func TestSyncMemberCreditsCanceled(t *testing.T) {
+
+	w := newWithdrawTest(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := w.service.SyncMemberCredits(ctx, model.MemberCreditSyncBody{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("sync: got %v, want %v", err, context.Canceled)
+	}
+	w.check(t, model.BankTransactionPending, 500, 500, 200)
+}
+
+// TestLedgerPostingsBalanced checks every credit movement of a withdraw and a
+// getcreditback posts one balanced posting of two entries.
+func TestLedgerPostingsBalanced(t *testing.T) {
+
+	w := newWithdrawTest(t)
+	if err := w.confirm(); err != nil {
+		t.Fatalf("confirm: %s", err.Error())
+	}
+	if err := w.service.CancelPendingTransaction(context.Background(), testWithdrawId, model.BankTransactionCancelBody{CanceledAt: time.Now()}); err != nil {
+		t.Fatalf("cancel: %s", err.Error())
+	}
+	w.banking.transactions[testWithdrawId+1] = model.BankTransaction{Id: testWithdrawId + 1, UserId: testMemberId, TransferType: "getcreditback", Status: model.BankTransactionPendingCredit, CreditAmount: model.NewMoney(50)}
+	if err := w.service.ConfirmWithdrawTransaction(context.Background(), testWithdrawId+1, model.BankConfirmCreditWithdrawRequest{ConfirmedAt: time.Now()}); err != nil {
+		t.Fatalf("confirm getcreditback: %s", err.Error())
+	}
+	// a failed debit leaves nothing in the ledger
+	w.banking.failDebit = true
+	w.banking.transactions[testWithdrawId+2] = model.BankTransaction{Id: testWithdrawId + 2, UserId: testMemberId, TransferType: "getcreditback", Status: model.BankTransactionPendingCredit, CreditAmount: model.NewMoney(50)}
+	if err := w.service.ConfirmWithdrawTransaction(context.Background(), testWithdrawId+2, model.BankConfirmCreditWithdrawRequest{ConfirmedAt: time.Now()}); err == nil {
+		t.Fatalf("confirm refused debit: got no error")
+	}
+
+	// the withdraw, its cancel and the getcreditback
+	wantMember := []model.Money{model.NewMoney(-200), model.NewMoney(200), model.NewMoney(-50)}
+	postings := make(map[int64][]model.LedgerEntry)
+	var ids []int64
+	for _, entry := range w.banking.ledger {
+		if _, ok := postings[entry.TransactionId]; !ok {
+			ids = append(ids, entry.TransactionId)
+		}
+		postings[entry.TransactionId] = append(postings[entry.TransactionId], entry)
+	}
+	if len(ids) != len(wantMember) {
+		t.Fatalf("ledger has %d postings, want %d", len(ids), len(wantMember))
+	}
+	var memberBalance model.Money
+	for i, id := range ids {
+		entries := postings[id]
+		if len(entries) != 2 {
+			t.Fatalf("posting %d has %d entries, want 2", id, len(entries))
+		}
+		if err := repository.CheckLedgerBalanced(entries); err != nil {
+			t.Errorf("posting %d: %s", id, err.Error())
+		}
+		member, contra := entries[0], entries[1]
+		if member.AccountId != testMemberId || contra.AccountId == testMemberId {
+			t.Errorf("posting %d accounts %d and %d, want the member and a contra account", id, member.AccountId, contra.AccountId)
+		}
+		if got := member.Credit - member.Debit; got != wantMember[i] {
+			t.Errorf("posting %d moves the member %s, want %s", id, got, wantMember[i])
+		}
+		memberBalance += member.Credit - member.Debit
+	}
+
+	// the member account moved with the credit
+	member, _ := w.banking.GetMemberById(testMemberId)
+	if member.Credit != model.NewMoney(500)+memberBalance {
+		t.Errorf("credit %s, want 500.00 %s", member.Credit, memberBalance)
+	}
+
+	result, err := NewLedgerService(memoryLedgerRepository{banking: w.banking}).VerifyLedger()
+	if err != nil || !result.IsBalanced {
+		t.Fatalf("verify: %+v %v, want balanced", result, err)
+	}
+}
+
+func TestVerifyLedgerReportsImbalance(t *testing.T) {
+
+	w := newWithdrawTest(t)
+	if err := w.confirm(); err != nil {
+		t.Fatalf("confirm: %s", err.Error())
+	}
+	// an entry written outside postLedger, without its contra entry
+	posted := w.banking.ledger[0].TransactionId
+	w.banking.ledger = append(w.banking.ledger, model.LedgerEntry{TransactionId: posted, AccountId: testMemberId, Debit: model.NewMoney(10)})
+
+	result, err := NewLedgerService(memoryLedgerRepository{banking: w.banking}).VerifyLedger()
+	if err != nil {
+		t.Fatalf("verify: %s", err.Error())
+	}
+	if result.IsBalanced {
+		t.Fatalf("verify: balanced, want the imbalance reported")
+	}
+	if len(result.Unbalanced) != 1 || result.Unbalanced[0].TransactionId != posted || result.Unbalanced[0].Debit-result.Unbalanced[0].Credit != model.NewMoney(10) {
+		t.Fatalf("unbalanced %+v, want transaction %d off by 10.00", result.Unbalanced, posted)
+	}
+	if result.TotalDebit == result.TotalCredit {
+		t.Fatalf("totals %s %s, want them to differ", result.TotalDebit, result.TotalCredit)
+	}
+}
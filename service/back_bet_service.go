@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"cybergame-api/helper"
+	"cybergame-api/model"
+	"cybergame-api/repository"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const TurnoverNotReached = "ยอดเทิร์นโอเวอร์ยังไม่ถึงกำหนด"
+
+// agentBetTimeLayout is how the agent writes bet times and the history range.
+const agentBetTimeLayout = "2006-01-02 15:04:05"
+
+// the pull starts this long before the last imported bet, late settlements
+// are picked up again
+const agentBetSyncOverlap = 10 * time.Minute
+const agentBetSyncFirstWindow = time.Hour
+const agentBetSyncPageSize = 500
+
+var agentBetSyncing int32
+
+type BetService interface {
+	GetBets(req model.BetListRequest) (*model.SuccessWithPagination, error)
+	ImportBets(body model.BetImportBody) (*model.BetImportResult, error)
+	SyncAgentBets(ctx context.Context, body model.BetSyncBody) (*model.BetImportResult, error)
+	StartAgentBetSync(interval time.Duration)
+	GetMemberTurnover(userId int64) (*model.MemberTurnover, error)
+}
+
+type betService struct {
+	repo             repository.BetRepository
+	repoAgentConnect repository.AgentConnectRepository
+}
+
+func NewBetService(
+	repo repository.BetRepository,
+	repoAgentConnect repository.AgentConnectRepository,
+) BetService {
+	return &betService{repo, repoAgentConnect}
+}
+
+func (s *betService) GetBets(req model.BetListRequest) (*model.SuccessWithPagination, error) {
+
+	if err := helper.Pagination(&req.Page, &req.Limit); err != nil {
+		return nil, badRequest(err.Error())
+	}
+
+	list, err := s.repo.GetBets(req)
+	if err != nil {
+		return nil, internalServerError(err.Error())
+	}
+
+	return list, nil
+}
+
+// ImportBets saves the bets pushed to us, see SaveBets for the turnover.
+func (s *betService) ImportBets(body model.BetImportBody) (*model.BetImportResult, error) {
+
+	bets := make([]model.Bet, 0, len(body.Bets))
+	for _, item := range body.Bets {
+		bets = append(bets, model.Bet{
+			Provider:     item.Provider,
+			BetRef:       item.BetId,
+			Username:     item.Username,
+			GameCode:     item.GameCode,
+			RoundId:      item.RoundId,
+			BetAmount:    item.BetAmount,
+			ValidAmount:  item.ValidAmount,
+			PayoutAmount: item.PayoutAmount,
+			WinLoss:      item.WinLoss,
+			Status:       item.Status,
+			Source:       "push",
+			BetAt:        item.BetAt,
+			SettledAt:    item.SettledAt,
+		})
+	}
+
+	return s.saveBets(bets)
+}
+
+// SyncAgentBets pulls the bet history from the agent, by default from the
+// last imported bet until now.
+func (s *betService) SyncAgentBets(ctx context.Context, body model.BetSyncBody) (*model.BetImportResult, error) {
+
+	to := time.Now()
+	if body.To != nil {
+		to = *body.To
+	}
+
+	var from time.Time
+	if body.From != nil {
+		from = *body.From
+	} else {
+		lastAt, err := s.repo.GetLastAgentBetAt()
+		if err != nil {
+			return nil, internalServerError(err.Error())
+		}
+		if lastAt != nil {
+			from = lastAt.Add(-agentBetSyncOverlap)
+		} else {
+			from = to.Add(-agentBetSyncFirstWindow)
+		}
+	}
+	if !from.Before(to) {
+		return nil, badRequest("From must be before To")
+	}
+
+	var bets []model.Bet
+	for page := 1; ; page++ {
+		agentData := model.AGCBetHistory{
+			StartTime: from.Format(agentBetTimeLayout),
+			EndTime:   to.Format(agentBetTimeLayout),
+			Page:      page,
+			PageSize:  agentBetSyncPageSize,
+		}
+
+		response, err := s.repoAgentConnect.BetHistory(ctx, agentData)
+		if err != nil {
+			return nil, agentError(err)
+		}
+
+		for _, record := range response.Data.Records {
+			bet, err := agentBet(record)
+			if err != nil {
+				log.Printf("agent bet %s: %s", record.BetId, err.Error())
+				continue
+			}
+			bets = append(bets, bet)
+		}
+
+		if len(response.Data.Records) < agentBetSyncPageSize || page*agentBetSyncPageSize >= response.Data.Total {
+			break
+		}
+	}
+
+	return s.saveBets(bets)
+}
+
+// StartAgentBetSync runs SyncAgentBets every interval in the background, one
+// run at a time.
+func (s *betService) StartAgentBetSync(interval time.Duration) {
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if !atomic.CompareAndSwapInt32(&agentBetSyncing, 0, 1) {
+				continue
+			}
+			result, err := s.SyncAgentBets(context.Background(), model.BetSyncBody{})
+			if err != nil {
+				log.Println("agent bet sync:", err.Error())
+			} else if result.Received > 0 {
+				log.Printf("agent bet sync: received %d created %d updated %d turnover %.2f unknown %v", result.Received, result.Created, result.Updated, result.TurnoverAdded, result.UnknownMembers)
+			}
+			atomic.StoreInt32(&agentBetSyncing, 0)
+		}
+	}()
+}
+
+func (s *betService) GetMemberTurnover(userId int64) (*model.MemberTurnover, error) {
+
+	turnover, err := s.repo.GetMemberTurnover(userId)
+	if err != nil {
+		if err.Error() == recordNotFound {
+			return nil, notFound(memberNotFound)
+		}
+		return nil, internalServerError(err.Error())
+	}
+
+	return memberTurnoverStatus(turnover), nil
+}
+
+// saveBets links the bets to the members by username, the bets of unknown
+// usernames are left out and reported.
+func (s *betService) saveBets(bets []model.Bet) (*model.BetImportResult, error) {
+
+	result := model.BetImportResult{Received: len(bets), UnknownMembers: []string{}}
+	if len(bets) == 0 {
+		return &result, nil
+	}
+
+	var usernames []string
+	seen := make(map[string]bool)
+	for _, bet := range bets {
+		if !seen[bet.Username] {
+			seen[bet.Username] = true
+			usernames = append(usernames, bet.Username)
+		}
+	}
+
+	userIds, err := s.repo.GetUserIdsByUsernames(usernames)
+	if err != nil {
+		return nil, internalServerError(err.Error())
+	}
+
+	known := make([]model.Bet, 0, len(bets))
+	for _, bet := range bets {
+		userId, ok := userIds[bet.Username]
+		if !ok {
+			continue
+		}
+		bet.UserId = userId
+		known = append(known, bet)
+	}
+	for _, username := range usernames {
+		if _, ok := userIds[username]; !ok {
+			result.UnknownMembers = append(result.UnknownMembers, username)
+		}
+	}
+
+	saved, err := s.repo.SaveBets(known)
+	if err != nil {
+		return nil, internalServerError(err.Error())
+	}
+	result.BetSaveResult = *saved
+
+	return &result, nil
+}
+
+func agentBet(record model.AGCBetRecord) (model.Bet, error) {
+
+	bet := model.Bet{
+		Provider:     record.Provider,
+		BetRef:       record.BetId,
+		Username:     record.PlayerName,
+		GameCode:     record.GameCode,
+		RoundId:      record.RoundId,
+		BetAmount:    record.BetAmount,
+		ValidAmount:  record.ValidAmount,
+		PayoutAmount: record.PayoutAmount,
+		WinLoss:      record.WinLoss,
+		Status:       agentBetStatus(record.Status),
+		Source:       "agent",
+	}
+	if bet.BetRef == "" || bet.Username == "" {
+		return bet, fmt.Errorf("missing bet id or player")
+	}
+
+	betAt, err := time.ParseInLocation(agentBetTimeLayout, record.BetTime, time.Local)
+	if err != nil {
+		return bet, err
+	}
+	bet.BetAt = betAt
+
+	if record.SettleTime != "" {
+		if settledAt, err := time.ParseInLocation(agentBetTimeLayout, record.SettleTime, time.Local); err == nil {
+			bet.SettledAt = &settledAt
+		}
+	}
+	return bet, nil
+}
+
+func agentBetStatus(status string) string {
+
+	status = strings.ToLower(strings.TrimSpace(status))
+	switch {
+	case strings.HasPrefix(status, "cancel"), status == "void", status == "refund", status == "rollback":
+		return "canceled"
+	case strings.HasPrefix(status, "settle"), status == "win", status == "lose", status == "draw", status == "done":
+		return "settled"
+	}
+	return "pending"
+}
+
+func memberTurnoverStatus(turnover *model.MemberTurnover) *model.MemberTurnover {
+
+	turnover.Remaining = math.Max(0, math.Round((float64(turnover.TurnoverLimit)-turnover.Turnover)*100)/100)
+	turnover.IsPassed = turnover.Remaining == 0
+	return turnover
+}
@@ -0,0 +1,513 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"cybergame-api/catalog"
+	"cybergame-api/filestore"
+	"cybergame-api/helper"
+	"cybergame-api/model"
+	"cybergame-api/repository"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type GameService interface {
+	ImportGameCatalog(dir string) (int, error)
+	BuildGameSearchKeys() (int, error)
+	GetGames(req model.GameAdminListRequest) (*model.SuccessWithPagination, error)
+	UpdateGame(id int64, body model.GameUpdateBody) error
+	PreviewGameCatalogSync(req model.GameSyncRequest) (*model.GameSyncResult, error)
+	ApplyGameCatalogSync(req model.GameSyncRequest) (*model.GameSyncResult, error)
+	GetGameProviders() ([]model.GameProviderStatusResponse, error)
+	UpdateGameProvider(code string, body model.GameProviderUpdateBody) error
+	GetGameMaintenances(req model.GameMaintenanceListRequest) (*model.SuccessWithPagination, error)
+	CreateGameMaintenance(body model.GameMaintenanceBody) error
+	DeleteGameMaintenance(id int64) error
+	MirrorGameIcons(ctx context.Context, req model.GameIconMirrorRequest) (*model.GameIconMirrorResult, error)
+	StartGameIconMirror(req model.GameIconMirrorRequest) error
+}
+
+const GameIconMirrorWorkers = 8
+const GameIconMirrorTimeout = 20 * time.Second
+
+const GameNotFound = "ไม่พบเกม"
+const GameProviderNotFound = "ไม่พบค่ายเกม"
+const GameNotInProvider = "เกมไม่อยู่ในค่ายเกมที่เลือก"
+const GameMaintenanceNotFound = "ไม่พบรายการปิดปรับปรุง"
+const GameIconMirrorRunning = "กำลังดึงรูปเกมอยู่ กรุณารอให้เสร็จก่อน"
+
+// gameIconMirrorRunning is set while a mirror started from the back office runs.
+var gameIconMirrorRunning int32
+
+type gameService struct {
+	repo repository.GameRepository
+}
+
+func NewGameService(
+	repo repository.GameRepository,
+) GameService {
+	return &gameService{repo}
+}
+
+// ImportGameCatalog normalizes every provider list in dir and upserts it into
+// the Games table, returning the number of games read.
+func (s *gameService) ImportGameCatalog(dir string) (int, error) {
+
+	games, err := catalog.Load(dir)
+	if err != nil {
+		return 0, internalServerError(err.Error())
+	}
+
+	if err := s.repo.UpsertGames(games); err != nil {
+		return 0, internalServerError(err.Error())
+	}
+
+	return len(games), nil
+}
+
+// BuildGameSearchKeys fills the search columns of games imported before
+// search existed. Imports and syncs keep them up to date afterwards.
+func (s *gameService) BuildGameSearchKeys() (int, error) {
+
+	games, err := s.repo.GetGamesWithoutSearchKeys()
+	if err != nil {
+		return 0, internalServerError(err.Error())
+	}
+
+	for _, game := range games {
+		searchNames, searchPhonetic := catalog.SearchKeys(game.Name, game.Names)
+		if err := s.repo.UpdateGameSearchKeys(game.Id, searchNames, searchPhonetic); err != nil {
+			return 0, internalServerError(err.Error())
+		}
+	}
+
+	return len(games), nil
+}
+
+func (s *gameService) GetGames(req model.GameAdminListRequest) (*model.SuccessWithPagination, error) {
+
+	if err := helper.Pagination(&req.Page, &req.Limit); err != nil {
+		return nil, badRequest(err.Error())
+	}
+
+	list, err := s.repo.GetGames(req)
+	if err != nil {
+		return nil, internalServerError(err.Error())
+	}
+
+	return list, nil
+}
+
+func (s *gameService) UpdateGame(id int64, body model.GameUpdateBody) error {
+
+	if _, err := s.repo.GetGameById(id); err != nil {
+		if err.Error() == recordNotFound {
+			return notFound(GameNotFound)
+		}
+		return internalServerError(err.Error())
+	}
+
+	if err := s.repo.UpdateGame(id, body); err != nil {
+		return internalServerError(err.Error())
+	}
+
+	return nil
+}
+
+// PreviewGameCatalogSync reports what applying the provider file would add,
+// remove and change without touching the database.
+func (s *gameService) PreviewGameCatalogSync(req model.GameSyncRequest) (*model.GameSyncResult, error) {
+
+	result, _, _, err := s.diffGameCatalog(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ApplyGameCatalogSync writes the provider file into Games in one transaction.
+// Admin owned columns (sort_order, is_hidden) are never overwritten.
+func (s *gameService) ApplyGameCatalogSync(req model.GameSyncRequest) (*model.GameSyncResult, error) {
+
+	result, games, removedIds, err := s.diffGameCatalog(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SyncProviderGames(games, removedIds); err != nil {
+		return nil, internalServerError(err.Error())
+	}
+
+	result.Applied = true
+	return result, nil
+}
+
+func (s *gameService) GetGameProviders() ([]model.GameProviderStatusResponse, error) {
+
+	states, err := s.repo.GetGameProviderStates()
+	if err != nil {
+		return nil, internalServerError(err.Error())
+	}
+
+	totals, err := s.repo.GetGameProviderTotals()
+	if err != nil {
+		return nil, internalServerError(err.Error())
+	}
+
+	maintenances, err := s.repo.GetActiveGameMaintenances("")
+	if err != nil {
+		return nil, internalServerError(err.Error())
+	}
+
+	enabled := make(map[string]bool, len(states))
+	for _, state := range states {
+		enabled[state.Code] = state.IsEnabled
+	}
+
+	total := make(map[string]int64, len(totals))
+	for _, item := range totals {
+		total[item.Provider] = item.Total
+	}
+
+	inMaintenance := make(map[string]bool)
+	for _, maintenance := range maintenances {
+		if maintenance.GameId == nil {
+			inMaintenance[maintenance.Provider] = true
+		}
+	}
+
+	var list []model.GameProviderStatusResponse
+	for _, provider := range catalog.Providers() {
+		isEnabled, ok := enabled[provider.Code]
+		list = append(list, model.GameProviderStatusResponse{
+			Code:          provider.Code,
+			IsEnabled:     !ok || isEnabled,
+			Total:         total[provider.Code],
+			IsMaintenance: inMaintenance[provider.Code],
+		})
+	}
+
+	return list, nil
+}
+
+func (s *gameService) UpdateGameProvider(code string, body model.GameProviderUpdateBody) error {
+
+	if _, ok := catalog.FindProvider(code); !ok {
+		return notFound(GameProviderNotFound)
+	}
+
+	if err := s.repo.SetGameProviderEnabled(code, body); err != nil {
+		return internalServerError(err.Error())
+	}
+
+	return nil
+}
+
+func (s *gameService) GetGameMaintenances(req model.GameMaintenanceListRequest) (*model.SuccessWithPagination, error) {
+
+	if err := helper.Pagination(&req.Page, &req.Limit); err != nil {
+		return nil, badRequest(err.Error())
+	}
+
+	list, err := s.repo.GetGameMaintenances(req)
+	if err != nil {
+		return nil, internalServerError(err.Error())
+	}
+
+	return list, nil
+}
+
+func (s *gameService) CreateGameMaintenance(body model.GameMaintenanceBody) error {
+
+	if _, ok := catalog.FindProvider(body.Provider); !ok {
+		return notFound(GameProviderNotFound)
+	}
+
+	if body.GameId != nil {
+		game, err := s.repo.GetGameById(*body.GameId)
+		if err != nil {
+			if err.Error() == recordNotFound {
+				return notFound(GameNotFound)
+			}
+			return internalServerError(err.Error())
+		}
+		if game.Provider != body.Provider {
+			return badRequest(GameNotInProvider)
+		}
+	}
+
+	if err := s.repo.CreateGameMaintenance(body); err != nil {
+		return internalServerError(err.Error())
+	}
+
+	return nil
+}
+
+func (s *gameService) DeleteGameMaintenance(id int64) error {
+
+	if _, err := s.repo.GetGameMaintenanceById(id); err != nil {
+		if err.Error() == recordNotFound {
+			return notFound(GameMaintenanceNotFound)
+		}
+		return internalServerError(err.Error())
+	}
+
+	if err := s.repo.DeleteGameMaintenance(id); err != nil {
+		return internalServerError(err.Error())
+	}
+
+	return nil
+}
+
+// MirrorGameIcons copies each catalog icon into our storage once, so the lobby
+// no longer depends on the provider image hosts. Icons that fail to download
+// or are not images are marked failed and served as the placeholder.
+func (s *gameService) MirrorGameIcons(ctx context.Context, req model.GameIconMirrorRequest) (*model.GameIconMirrorResult, error) {
+
+	games, err := s.repo.GetGameIconsToMirror(req)
+	if err != nil {
+		return nil, internalServerError(err.Error())
+	}
+
+	store, err := filestore.New(ctx)
+	if err != nil {
+		return nil, internalServerError(err.Error())
+	}
+	defer store.Close()
+
+	if _, err := store.Put(ctx, catalog.PlaceholderIconName, "image/png", bytes.NewReader(catalog.PlaceholderIcon)); err != nil {
+		return nil, internalServerError(err.Error())
+	}
+
+	// games sharing an icon url are downloaded once
+	var sources []string
+	ids := make(map[string][]int64)
+	providers := make(map[string]string)
+	for _, game := range games {
+		if _, ok := ids[game.ImageIcon]; !ok {
+			sources = append(sources, game.ImageIcon)
+			providers[game.ImageIcon] = game.Provider
+		}
+		ids[game.ImageIcon] = append(ids[game.ImageIcon], game.Id)
+	}
+
+	client := &http.Client{Timeout: GameIconMirrorTimeout}
+	result := model.GameIconMirrorResult{Total: len(games)}
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	var firstErr error
+	queue := make(chan string)
+
+	for i := 0; i < GameIconMirrorWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for source := range queue {
+				pathname, mirrorErr := mirrorGameIcon(ctx, client, store, providers[source], source)
+
+				status := "success"
+				if mirrorErr != nil {
+					status = "failed"
+					fmt.Println("mirror icon", source, mirrorErr)
+				}
+
+				err := s.repo.UpdateGameIconMirror(ids[source], source, pathname, status)
+
+				mutex.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				if mirrorErr != nil {
+					result.Failed += len(ids[source])
+				} else {
+					result.Success += len(ids[source])
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	for _, source := range sources {
+		if ctx.Err() != nil {
+			break
+		}
+		queue <- source
+	}
+	close(queue)
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, internalServerError(firstErr.Error())
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, internalServerError(err.Error())
+	}
+
+	return &result, nil
+}
+
+// StartGameIconMirror runs MirrorGameIcons in the background, one run at a time.
+func (s *gameService) StartGameIconMirror(req model.GameIconMirrorRequest) error {
+
+	if !atomic.CompareAndSwapInt32(&gameIconMirrorRunning, 0, 1) {
+		return badRequest(GameIconMirrorRunning)
+	}
+
+	go func() {
+		defer atomic.StoreInt32(&gameIconMirrorRunning, 0)
+
+		result, err := s.MirrorGameIcons(context.Background(), req)
+		if err != nil {
+			fmt.Println("mirror icons", err)
+			return
+		}
+		fmt.Printf("mirror icons total %d, success %d, failed %d\n", result.Total, result.Success, result.Failed)
+	}()
+
+	return nil
+}
+
+func mirrorGameIcon(ctx context.Context, client *http.Client, store filestore.Storage, provider string, source string) (*string, error) {
+
+	data, contentType, err := catalog.FetchIcon(ctx, client, source)
+	if err != nil {
+		return nil, err
+	}
+
+	pathname, err := store.Put(ctx, catalog.IconObjectName(provider, source, contentType), contentType, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	return &pathname, nil
+}
+
+func (s *gameService) diffGameCatalog(req model.GameSyncRequest) (*model.GameSyncResult, []model.Game, []int64, error) {
+
+	if _, ok := catalog.FindProvider(req.Provider); !ok {
+		return nil, nil, nil, notFound(GameProviderNotFound)
+	}
+
+	if req.Dir == "" {
+		req.Dir = catalog.DefaultDir
+	}
+
+	games, err := catalog.LoadProvider(req.Dir, req.Provider)
+	if err != nil {
+		return nil, nil, nil, internalServerError(err.Error())
+	}
+
+	current, err := s.repo.GetProviderGames(req.Provider)
+	if err != nil {
+		return nil, nil, nil, internalServerError(err.Error())
+	}
+
+	existing := make(map[string]model.Game, len(current))
+	for _, game := range current {
+		existing[game.GameCode] = game
+	}
+
+	result := model.GameSyncResult{
+		Provider: req.Provider,
+		Added:    []model.GameSyncItem{},
+		Removed:  []model.GameSyncItem{},
+		Changed:  []model.GameSyncChange{},
+	}
+
+	for _, game := range games {
+		old, ok := existing[game.GameCode]
+		if !ok {
+			result.Added = append(result.Added, model.GameSyncItem{GameCode: game.GameCode, Name: game.Name})
+			continue
+		}
+		delete(existing, game.GameCode)
+
+		if fields := changedGameFields(old, game); len(fields) > 0 {
+			result.Changed = append(result.Changed, model.GameSyncChange{GameCode: game.GameCode, Name: game.Name, Fields: fields})
+		} else {
+			result.Unchanged++
+		}
+	}
+
+	var removedIds []int64
+	for _, game := range current {
+		if _, ok := existing[game.GameCode]; ok {
+			removedIds = append(removedIds, game.Id)
+			result.Removed = append(result.Removed, model.GameSyncItem{GameCode: game.GameCode, Name: game.Name})
+		}
+	}
+
+	return &result, games, removedIds, nil
+}
+
+// changedGameFields lists the catalog columns that differ between the stored
+// game and the one read from the provider file.
+func changedGameFields(old model.Game, new model.Game) []string {
+
+	var fields []string
+	if !reflect.DeepEqual(emptyMapAsNil(old.DeviceCodes), emptyMapAsNil(new.DeviceCodes)) {
+		fields = append(fields, "deviceCodes")
+	}
+	if old.Name != new.Name {
+		fields = append(fields, "name")
+	}
+	if !reflect.DeepEqual(emptyMapAsNil(old.Names), emptyMapAsNil(new.Names)) {
+		fields = append(fields, "names")
+	}
+	if old.GameType != new.GameType {
+		fields = append(fields, "gameType")
+	}
+	if old.ProviderGameType != new.ProviderGameType {
+		fields = append(fields, "providerGameType")
+	}
+	if old.ImageIcon != new.ImageIcon {
+		fields = append(fields, "imageIcon")
+	}
+	if len(old.Currencies) != len(new.Currencies) || (len(old.Currencies) > 0 && !reflect.DeepEqual(old.Currencies, new.Currencies)) {
+		fields = append(fields, "currencies")
+	}
+	if old.IsDesktop != new.IsDesktop {
+		fields = append(fields, "isDesktop")
+	}
+	if old.IsMobile != new.IsMobile {
+		fields = append(fields, "isMobile")
+	}
+	if old.IsHot != new.IsHot {
+		fields = append(fields, "isHot")
+	}
+	if old.IsNew != new.IsNew {
+		fields = append(fields, "isNew")
+	}
+	if old.IsRecommend != new.IsRecommend {
+		fields = append(fields, "isRecommend")
+	}
+	if old.IsAvailable != new.IsAvailable {
+		fields = append(fields, "isAvailable")
+	}
+	if !sameReleaseDate(old.ReleasedAt, new.ReleasedAt) {
+		fields = append(fields, "releasedAt")
+	}
+	return fields
+}
+
+func emptyMapAsNil(m map[string]string) map[string]string {
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
+
+func sameReleaseDate(a *time.Time, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	// the database keeps whole seconds only
+	return a.Truncate(time.Second).Equal(b.Truncate(time.Second))
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"cybergame-api/helper"
 	"cybergame-api/model"
 	"cybergame-api/repository"
@@ -9,7 +10,6 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
-	"time"
 )
 
 type UserService interface {
@@ -17,7 +17,7 @@ type UserService interface {
 	GetUser(id int64) (*model.UserDetail, error)
 	GetUserList(query model.UserListQuery) (*model.SuccessWithPagination, error)
 	GetUpdateLogs(query model.UserUpdateQuery) (*model.SuccessWithPagination, error)
-	Create(user *model.CreateUser) error
+	Create(ctx context.Context, user *model.CreateUser) error
 	UpdateUser(userId int64, body model.UpdateUser, adminName string) error
 	ResetPassword(userId int64, body model.UserUpdatePassword) error
 	DeleteUser(id int64) error
@@ -111,7 +111,7 @@ func (s *userService) GetUpdateLogs(query model.UserUpdateQuery) (*model.Success
 	return result, nil
 }
 
-func (s *userService) Create(data *model.CreateUser) error {
+func (s *userService) Create(ctx context.Context, data *model.CreateUser) error {
 
 	phone, err := s.repo.CheckUserPhone(data.Phone)
 	if err != nil {
@@ -175,22 +175,17 @@ func (s *userService) Create(data *model.CreateUser) error {
 
 	agentTotal := *countUser + int64(agentStart)
 	conv := strconv.FormatInt(agentTotal, 10)
-	agentName := os.Getenv("AGENT_NAME")
+	agentName := s.agentConnectRepo.AgentWallet().AgentName()
 	username := agentName + helper.IncrementNumber(conv)
 	newUser.Username = &username
-	sign := agentName + username
-	timeNow := time.Now()
 	agentData := model.AGCRegister{
-		Username:  username,
-		Agentname: agentName,
-		Fullname:  newUser.Fullname,
-		Password:  data.Password,
-		Currency:  "THB",
-		Dob:       "1990-01-01",
-		Mobile:    newUser.Phone,
-		Ip:        newUser.IpRegistered,
-		Timestamp: timeNow.Unix(),
-		Sign:      helper.CreateSign(sign, timeNow),
+		Username: username,
+		Fullname: newUser.Fullname,
+		Password: data.Password,
+		Currency: "THB",
+		Dob:      "1990-01-01",
+		Mobile:   newUser.Phone,
+		Ip:       newUser.IpRegistered,
 	}
 
 	userId, err := s.repo.CreateUser(newUser)
@@ -198,13 +193,13 @@ func (s *userService) Create(data *model.CreateUser) error {
 		return err
 	}
 
-	if err := s.agentConnectRepo.Register(agentData); err != nil {
+	if err := s.agentConnectRepo.Register(ctx, agentData); err != nil {
 
 		if err := s.repo.DeleteUser(userId); err != nil {
 			return internalServerError(ServerError)
 		}
 
-		return internalServerError(ServerError)
+		return agentError(err)
 	}
 
 	return nil
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"cybergame-api/model"
+	"log"
+	"net/http"
+	"sync"
+)
+
+const BulkActionCanceled = "คำขอถูกยกเลิกก่อนดำเนินการรายการนี้"
+
+// bankTransactionBulkWorkers is how many items of a bulk action run at once,
+// each one still holds its own database transaction and agent call.
+const bankTransactionBulkWorkers = 5
+
+func (s *bankingService) BulkConfirmDepositTransactions(ctx context.Context, ids []int64, req model.BankConfirmDepositRequest) *model.BankTransactionBulkResult {
+	return runBankTransactionBulk(ctx, "confirm deposit", ids, func(id int64) error {
+		return s.ConfirmDepositTransaction(id, req)
+	})
+}
+
+func (s *bankingService) BulkConfirmDepositCredits(ctx context.Context, ids []int64, req model.BankConfirmDepositRequest) *model.BankTransactionBulkResult {
+	return runBankTransactionBulk(ctx, "confirm deposit credit", ids, func(id int64) error {
+		return s.ConfirmDepositCredit(id, req)
+	})
+}
+
+func (s *bankingService) BulkConfirmWithdrawTransactions(ctx context.Context, ids []int64, req model.BankConfirmCreditWithdrawRequest) *model.BankTransactionBulkResult {
+	return runBankTransactionBulk(ctx, "confirm withdraw credit", ids, func(id int64) error {
+		return s.ConfirmWithdrawTransaction(ctx, id, req)
+	})
+}
+
+func (s *bankingService) BulkCancelPendingTransactions(ctx context.Context, ids []int64, data model.BankTransactionCancelBody) *model.BankTransactionBulkResult {
+	return runBankTransactionBulk(ctx, "cancel", ids, func(id int64) error {
+		return s.CancelPendingTransaction(ctx, id, data)
+	})
+}
+
+// runBankTransactionBulk runs action on each id through the single item flow,
+// so every item keeps its status check and action row, and reports each one.
+// An id sent twice runs once, a failed item does not stop the others. Once
+// the request is canceled the items not started yet are reported failed.
+func runBankTransactionBulk(ctx context.Context, name string, ids []int64, action func(id int64) error) *model.BankTransactionBulkResult {
+
+	var unique []int64
+	seen := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+
+	items := make([]model.BankTransactionBulkItem, len(unique))
+	workers := make(chan struct{}, bankTransactionBulkWorkers)
+	var wg sync.WaitGroup
+	for i, id := range unique {
+		select {
+		case workers <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			items[i] = model.BankTransactionBulkItem{Id: id, Code: http.StatusServiceUnavailable, Message: BulkActionCanceled}
+			continue
+		}
+		wg.Add(1)
+		go func(i int, id int64) {
+			defer wg.Done()
+			defer func() { <-workers }()
+			items[i] = runBankTransactionBulkItem(name, id, action)
+		}(i, id)
+	}
+	wg.Wait()
+
+	result := model.BankTransactionBulkResult{
+		Total: len(items),
+		Items: items,
+	}
+	for _, item := range items {
+		if item.Success {
+			result.Succeeded++
+		} else {
+			result.Failed++
+		}
+	}
+	log.Printf("bulk %s: %d succeeded, %d failed", name, result.Succeeded, result.Failed)
+	return &result
+}
+
+// runBankTransactionBulkItem keeps a panic of one item from taking the
+// server down, outside of the request goroutine gin does not recover it.
+func runBankTransactionBulkItem(name string, id int64, action func(id int64) error) (item model.BankTransactionBulkItem) {
+
+	item.Id = id
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("bulk %s of transaction %d: %v", name, id, r)
+			item.Success = false
+			item.Code = http.StatusInternalServerError
+			item.Message = ServerError
+		}
+	}()
+
+	if err := action(id); err != nil {
+		item.Code = http.StatusInternalServerError
+		if responseErr, ok := err.(ResponseError); ok {
+			item.Code = responseErr.Code
+		}
+		item.Message = err.Error()
+		return item
+	}
+	item.Success = true
+	return item
+}
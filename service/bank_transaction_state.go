@@ -0,0 +1,44 @@
+package service
+
+import (
+	"cybergame-api/model"
+	"cybergame-api/repository"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const BankTransactionStatusChanged = "รายการนี้ถูกดำเนินการไปแล้ว กรุณาโหลดข้อมูลใหม่"
+
+// bankTransactionTransition looks the action up in the transition table of
+// the transaction type. An action the type does not have is a bad request, a
+// transaction no longer in a status the action starts from is a conflict.
+func bankTransactionTransition(record model.BankTransaction, action string) (*model.BankTransactionTransition, error) {
+
+	transitions, ok := model.BankTransactionTransitions[record.TransferType]
+	if !ok {
+		return nil, badRequest(fmt.Sprintf("Invalid transfer type %s", record.TransferType))
+	}
+	transition, ok := transitions[action]
+	if !ok {
+		return nil, badRequest(fmt.Sprintf("Cannot %s a %s transaction", action, record.TransferType))
+	}
+	for _, status := range transition.From {
+		if record.Status == status {
+			return &transition, nil
+		}
+	}
+	return nil, ResponseError{
+		Code:    http.StatusConflict,
+		Message: fmt.Sprintf("%s (%s)", BankTransactionStatusChanged, record.Status),
+	}
+}
+
+// bankTransactionError answers 409 when the conditional status update lost
+// to another request.
+func bankTransactionError(err error) error {
+	if errors.Is(err, repository.ErrBankTransactionStatusChanged) {
+		return ResponseError{Code: http.StatusConflict, Message: BankTransactionStatusChanged}
+	}
+	return internalServerError(err.Error())
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"cybergame-api/agent/fake"
+	"cybergame-api/model"
+	"cybergame-api/repository"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBankTransactionTransition(t *testing.T) {
+
+	tests := []struct {
+		name         string
+		transferType string
+		status       string
+		action       string
+		wantTo       string
+		wantCode     int
+	}{
+		{"confirm deposit", "deposit", model.BankTransactionPending, model.BankTransactionActionConfirm, model.BankTransactionPendingCredit, 0},
+		{"debit withdraw agent", "withdraw", model.BankTransactionPendingCredit, model.BankTransactionActionDebitAgent, model.BankTransactionPendingAgent, 0},
+		{"withdraw agent debited", "withdraw", model.BankTransactionPendingAgent, model.BankTransactionActionAgentDebited, model.BankTransactionPendingCredit, 0},
+		{"confirm withdraw credit", "withdraw", model.BankTransactionPendingCredit, model.BankTransactionActionConfirmCredit, model.BankTransactionPendingTransfer, 0},
+		{"confirm withdraw credit during the debit", "withdraw", model.BankTransactionPendingAgent, model.BankTransactionActionConfirmCredit, "", http.StatusConflict},
+		{"cancel waiting transfer", "withdraw", model.BankTransactionPendingTransfer, model.BankTransactionActionCancel, model.BankTransactionCanceled, 0},
+		{"confirm twice", "deposit", model.BankTransactionPendingCredit, model.BankTransactionActionConfirm, "", http.StatusConflict},
+		{"cancel finished", "withdraw", model.BankTransactionFinished, model.BankTransactionActionCancel, "", http.StatusConflict},
+		{"confirm canceled", "getcreditback", model.BankTransactionCanceled, model.BankTransactionActionConfirmCredit, "", http.StatusConflict},
+		{"transfer a deposit", "deposit", model.BankTransactionPendingTransfer, model.BankTransactionActionConfirmTransfer, "", http.StatusBadRequest},
+		{"unknown type", "transfer", model.BankTransactionPending, model.BankTransactionActionConfirm, "", http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			record := model.BankTransaction{Id: 1, TransferType: test.transferType, Status: test.status}
+			transition, err := bankTransactionTransition(record, test.action)
+			if test.wantCode != 0 {
+				if responseCode(err) != test.wantCode {
+					t.Fatalf("got %v, want %d", err, test.wantCode)
+				}
+				return
+			}
+			if err != nil || transition.To != test.wantTo {
+				t.Fatalf("got %v %v, want to %s", transition, err, test.wantTo)
+			}
+		})
+	}
+}
+
+func TestBankTransactionError(t *testing.T) {
+
+	lost := fmt.Errorf("confirm 7: %w", repository.ErrBankTransactionStatusChanged)
+	if err := bankTransactionError(lost); responseCode(err) != http.StatusConflict || err.Error() != BankTransactionStatusChanged {
+		t.Fatalf("lost update: got %v, want 409 %s", err, BankTransactionStatusChanged)
+	}
+	if err := bankTransactionError(errors.New("connection refused")); responseCode(err) != http.StatusInternalServerError {
+		t.Fatalf("database error: got %v, want 500", err)
+	}
+}
+
+// TestCancelLostToConfirm is a cancel whose conditional update finds the
+// withdraw already confirmed by another admin.
+func TestCancelLostToConfirm(t *testing.T) {
+
+	w := newWithdrawTest(t)
+	w.banking.loseConfirm = true
+
+	err := w.service.CancelPendingTransaction(context.Background(), testWithdrawId, model.BankTransactionCancelBody{CanceledAt: time.Now()})
+	if responseCode(err) != http.StatusConflict {
+		t.Fatalf("cancel: got %v, want 409", err)
+	}
+	// the hold stays and nothing went to the agent
+	w.check(t, model.BankTransactionPending, 500, 500, 200)
+	if calls := w.server.Calls(fake.PathDeposit); calls != 0 {
+		t.Fatalf("agent got %d deposits, want 0", calls)
+	}
+}
+
+// TestCancelAfterStatusMoved is a cancel that read the transaction after
+// another request finished it.
+func TestCancelAfterStatusMoved(t *testing.T) {
+
+	w := newWithdrawTest(t)
+	transaction := w.banking.transactions[testWithdrawId]
+	transaction.Status = model.BankTransactionFinished
+	w.banking.transactions[testWithdrawId] = transaction
+
+	err := w.service.CancelPendingTransaction(context.Background(), testWithdrawId, model.BankTransactionCancelBody{CanceledAt: time.Now()})
+	if responseCode(err) != http.StatusConflict {
+		t.Fatalf("cancel: got %v, want 409", err)
+	}
+	w.check(t, model.BankTransactionFinished, 500, 500, 200)
+}
@@ -0,0 +1,451 @@
+package service
+
+import (
+	"context"
+	"cybergame-api/catalog"
+	"cybergame-api/filestore"
+	"cybergame-api/helper"
+	"cybergame-api/model"
+	"cybergame-api/repository"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+)
+
+type FrontGameService interface {
+	GetFrontGames(req model.GameListRequest) ([]model.GameResponse, int64, error)
+	GetFrontGame(req model.GameDetailRequest) (*model.GameResponse, error)
+	GetFrontGameProviders() ([]model.GameProviderResponse, error)
+	SearchFrontGames(req model.GameSearchRequest) ([]model.GameResponse, int64, error)
+	LaunchFrontGame(ctx context.Context, req model.GameDetailRequest, body model.GameLaunchRequest) (*model.GameLaunchResponse, error)
+	GetFrontGameFavorites(userId int64, req model.GameFavoriteListRequest) ([]model.GameResponse, int64, error)
+	AddFrontGameFavorite(userId int64, req model.GameDetailRequest) error
+	RemoveFrontGameFavorite(userId int64, req model.GameDetailRequest) error
+	GetFrontGameRecentPlays(userId int64, req model.GameRecentListRequest) ([]model.GameRecentResponse, error)
+	CheckCurrentUserId(input any) (*int64, error)
+}
+
+const FrontGameNotFound = "ไม่พบเกม"
+const FrontGameUserNotRegistered = "บัญชีผู้ใช้งานยังไม่ได้ลงทะเบียนกับเอเย่นต์"
+const FrontGameLaunchFailed = "ไม่สามารถเข้าเล่นเกมได้ กรุณาลองใหม่อีกครั้ง"
+const FrontGameDisabled = "เกมนี้ปิดให้บริการชั่วคราว"
+const FrontGameDisabledEn = "This game is temporarily unavailable"
+const FrontGameMaintenance = "เกมนี้อยู่ระหว่างปิดปรับปรุง กรุณาลองใหม่ภายหลัง"
+const FrontGameMaintenanceEn = "This game is under maintenance, please try again later"
+const invalidCurrentUserId = "Invalid current user id"
+
+type frontGameService struct {
+	repo             repository.FrontGameRepository
+	userRepo         repository.FrontUserRepository
+	agentConnectRepo repository.AgentConnectRepository
+}
+
+func NewFrontGameService(
+	repo repository.FrontGameRepository,
+	userRepo repository.FrontUserRepository,
+	agentConnectRepo repository.AgentConnectRepository,
+) FrontGameService {
+	return &frontGameService{repo, userRepo, agentConnectRepo}
+}
+
+func (s *frontGameService) GetFrontGames(req model.GameListRequest) ([]model.GameResponse, int64, error) {
+
+	if err := helper.Pagination(&req.Page, &req.Limit); err != nil {
+		return nil, 0, badRequest(err.Error())
+	}
+
+	list, total, err := s.repo.GetFrontGames(req)
+	if err != nil {
+		return nil, 0, internalServerError(err.Error())
+	}
+
+	maintenances, err := s.repo.GetActiveGameMaintenances(req.Provider)
+	if err != nil {
+		return nil, 0, internalServerError(err.Error())
+	}
+
+	for i := range list {
+		list[i].ImageIcon = gameIconUrl(list[i])
+		list[i].Maintenance = maintenanceResponse(findGameMaintenance(maintenances, list[i].Provider, list[i].Id))
+	}
+
+	return list, total, nil
+}
+
+func (s *frontGameService) GetFrontGame(req model.GameDetailRequest) (*model.GameResponse, error) {
+
+	game, err := s.repo.GetFrontGame(req.Provider, req.GameCode)
+	if err != nil {
+		if err.Error() == recordNotFound {
+			return nil, notFound(FrontGameNotFound)
+		}
+		return nil, internalServerError(err.Error())
+	}
+
+	maintenances, err := s.repo.GetActiveGameMaintenances(game.Provider)
+	if err != nil {
+		return nil, internalServerError(err.Error())
+	}
+
+	game.ImageIcon = gameIconUrl(*game)
+	game.Maintenance = maintenanceResponse(findGameMaintenance(maintenances, game.Provider, game.Id))
+	return game, nil
+}
+
+func (s *frontGameService) GetFrontGameProviders() ([]model.GameProviderResponse, error) {
+
+	list, err := s.repo.GetFrontGameProviders()
+	if err != nil {
+		return nil, internalServerError(err.Error())
+	}
+
+	maintenances, err := s.repo.GetActiveGameMaintenances("")
+	if err != nil {
+		return nil, internalServerError(err.Error())
+	}
+
+	for i := range list {
+		list[i].Maintenance = maintenanceResponse(findGameMaintenance(maintenances, list[i].Provider, 0))
+	}
+
+	return list, nil
+}
+
+// SearchFrontGames matches the query against every language variant of the
+// game names and their phonetic keys, so a Thai spelling of an English title
+// finds it too. Best matches come first.
+func (s *frontGameService) SearchFrontGames(req model.GameSearchRequest) ([]model.GameResponse, int64, error) {
+
+	if err := helper.Pagination(&req.Page, &req.Limit); err != nil {
+		return nil, 0, badRequest(err.Error())
+	}
+
+	query := catalog.NormalizeSearchText(req.Q)
+	if query == "" {
+		return []model.GameResponse{}, 0, nil
+	}
+
+	queryKey := catalog.PhoneticKey(query)
+	if len(queryKey) < 2 {
+		queryKey = ""
+	}
+
+	candidates, err := s.repo.SearchFrontGames(req, query, queryKey)
+	if err != nil {
+		return nil, 0, internalServerError(err.Error())
+	}
+
+	scores := make([]int, len(candidates))
+	for i, candidate := range candidates {
+		scores[i] = catalog.SearchScore(query, queryKey, candidate.SearchNames, candidate.SearchPhonetic)
+	}
+
+	index := make([]int, 0, len(candidates))
+	for i := range candidates {
+		if scores[i] > 0 {
+			index = append(index, i)
+		}
+	}
+
+	sort.SliceStable(index, func(a, b int) bool {
+		x, y := candidates[index[a]], candidates[index[b]]
+		if scores[index[a]] != scores[index[b]] {
+			return scores[index[a]] > scores[index[b]]
+		}
+		if x.IsHot != y.IsHot {
+			return x.IsHot
+		}
+		if x.SortOrder != y.SortOrder {
+			return x.SortOrder < y.SortOrder
+		}
+		return x.Id < y.Id
+	})
+
+	total := int64(len(index))
+	start := req.Page * req.Limit
+	if start > len(index) {
+		start = len(index)
+	}
+	end := start + req.Limit
+	if end > len(index) {
+		end = len(index)
+	}
+
+	maintenances, err := s.repo.GetActiveGameMaintenances(req.Provider)
+	if err != nil {
+		return nil, 0, internalServerError(err.Error())
+	}
+
+	list := make([]model.GameResponse, 0, end-start)
+	for _, i := range index[start:end] {
+		game := candidates[i].GameResponse
+		game.ImageIcon = gameIconUrl(game)
+		game.Maintenance = maintenanceResponse(findGameMaintenance(maintenances, game.Provider, game.Id))
+		list = append(list, game)
+	}
+
+	return list, total, nil
+}
+
+func (s *frontGameService) LaunchFrontGame(ctx context.Context, req model.GameDetailRequest, body model.GameLaunchRequest) (*model.GameLaunchResponse, error) {
+
+	game, err := s.repo.GetFrontLaunchGame(req.Provider, req.GameCode)
+	if err != nil {
+		if err.Error() == recordNotFound {
+			return nil, notFound(FrontGameNotFound)
+		}
+		return nil, internalServerError(err.Error())
+	}
+
+	// switched off or in maintenance, never reach the agent
+	isEnglish := strings.HasPrefix(strings.ToLower(body.Lang), "en")
+
+	providerEnabled, err := s.repo.IsGameProviderEnabled(game.Provider)
+	if err != nil {
+		return nil, internalServerError(err.Error())
+	}
+
+	if !providerEnabled || !game.IsEnabled {
+		if isEnglish {
+			return nil, serviceUnavailable(FrontGameDisabledEn)
+		}
+		return nil, serviceUnavailable(FrontGameDisabled)
+	}
+
+	maintenances, err := s.repo.GetActiveGameMaintenances(game.Provider)
+	if err != nil {
+		return nil, internalServerError(err.Error())
+	}
+
+	if maintenance := findGameMaintenance(maintenances, game.Provider, game.Id); maintenance != nil {
+		if isEnglish {
+			return nil, serviceUnavailable(firstNonEmpty(maintenance.MessageEn, FrontGameMaintenanceEn))
+		}
+		return nil, serviceUnavailable(firstNonEmpty(maintenance.MessageTh, FrontGameMaintenance))
+	}
+
+	user, err := s.userRepo.CheckFrontUserById(body.UserId)
+	if err != nil {
+		return nil, internalServerError(err.Error())
+	}
+
+	if user == nil {
+		return nil, notFound(FrontUserNotFound)
+	}
+
+	if user.Username == nil || *user.Username == "" {
+		return nil, badRequest(FrontGameUserNotRegistered)
+	}
+
+	// cq9 publishes a separate code per device
+	gameCode := game.GameCode
+	if body.IsMobile && game.DeviceCodes["mobile"] != "" {
+		gameCode = game.DeviceCodes["mobile"]
+	}
+
+	if body.Lang == "" {
+		body.Lang = "th-th"
+	}
+
+	agentData := model.AGCLogin{
+		Username: *user.Username,
+		Domain:   body.Domain,
+		Lang:     body.Lang,
+		IsMobile: body.IsMobile,
+		Ip:       body.Ip,
+		Provider: game.Provider,
+		GameCode: gameCode,
+	}
+
+	launchLog := model.GameLaunchLog{
+		UserId:   body.UserId,
+		GameId:   game.Id,
+		Provider: game.Provider,
+		GameCode: game.GameCode,
+		IsMobile: body.IsMobile,
+		Ip:       body.Ip,
+	}
+
+	response, err := s.agentConnectRepo.Login(ctx, agentData)
+	if err == nil && response.GameUrl() == "" {
+		err = errors.New("agent returned no game url")
+	}
+
+	if err != nil {
+		launchLog.Status = "failed"
+		launchLog.ErrorMessage = err.Error()
+		if message := []rune(launchLog.ErrorMessage); len(message) > 255 {
+			launchLog.ErrorMessage = string(message[:255])
+		}
+		if err := s.repo.CreateFrontGameLaunchLog(launchLog); err != nil {
+			return nil, internalServerError(err.Error())
+		}
+		return nil, internalServerError(FrontGameLaunchFailed)
+	}
+
+	launchLog.Status = "success"
+	launchLog.GameUrl = response.GameUrl()
+	if err := s.repo.CreateFrontGameLaunchLog(launchLog); err != nil {
+		return nil, internalServerError(err.Error())
+	}
+
+	// the game is already open at the agent, a missed history row must not block it
+	if err := s.repo.SaveFrontGameRecentPlay(body.UserId, game.Id); err != nil {
+		log.Printf("save recent play of user %d: %s", body.UserId, err.Error())
+	}
+
+	return &model.GameLaunchResponse{
+		Provider: game.Provider,
+		GameCode: game.GameCode,
+		Url:      launchLog.GameUrl,
+	}, nil
+}
+
+func (s *frontGameService) GetFrontGameFavorites(userId int64, req model.GameFavoriteListRequest) ([]model.GameResponse, int64, error) {
+
+	if err := helper.Pagination(&req.Page, &req.Limit); err != nil {
+		return nil, 0, badRequest(err.Error())
+	}
+
+	list, total, err := s.repo.GetFrontGameFavorites(userId, req)
+	if err != nil {
+		return nil, 0, internalServerError(err.Error())
+	}
+
+	maintenances, err := s.repo.GetActiveGameMaintenances("")
+	if err != nil {
+		return nil, 0, internalServerError(err.Error())
+	}
+
+	for i := range list {
+		list[i].ImageIcon = gameIconUrl(list[i])
+		list[i].Maintenance = maintenanceResponse(findGameMaintenance(maintenances, list[i].Provider, list[i].Id))
+	}
+
+	return list, total, nil
+}
+
+func (s *frontGameService) AddFrontGameFavorite(userId int64, req model.GameDetailRequest) error {
+
+	game, err := s.repo.GetFrontGame(req.Provider, req.GameCode)
+	if err != nil {
+		if err.Error() == recordNotFound {
+			return notFound(FrontGameNotFound)
+		}
+		return internalServerError(err.Error())
+	}
+
+	if err := s.repo.AddFrontGameFavorite(userId, game.Id); err != nil {
+		return internalServerError(err.Error())
+	}
+
+	return nil
+}
+
+func (s *frontGameService) RemoveFrontGameFavorite(userId int64, req model.GameDetailRequest) error {
+
+	game, err := s.repo.GetFrontLaunchGame(req.Provider, req.GameCode)
+	if err != nil {
+		if err.Error() == recordNotFound {
+			return notFound(FrontGameNotFound)
+		}
+		return internalServerError(err.Error())
+	}
+
+	if err := s.repo.RemoveFrontGameFavorite(userId, game.Id); err != nil {
+		return internalServerError(err.Error())
+	}
+
+	return nil
+}
+
+func (s *frontGameService) GetFrontGameRecentPlays(userId int64, req model.GameRecentListRequest) ([]model.GameRecentResponse, error) {
+
+	if req.Limit == 0 {
+		req.Limit = 20
+	}
+
+	list, err := s.repo.GetFrontGameRecentPlays(userId, req.Limit)
+	if err != nil {
+		return nil, internalServerError(err.Error())
+	}
+
+	maintenances, err := s.repo.GetActiveGameMaintenances("")
+	if err != nil {
+		return nil, internalServerError(err.Error())
+	}
+
+	for i := range list {
+		list[i].ImageIcon = gameIconUrl(list[i].GameResponse)
+		list[i].Maintenance = maintenanceResponse(findGameMaintenance(maintenances, list[i].Provider, list[i].Id))
+	}
+
+	return list, nil
+}
+
+func (s *frontGameService) CheckCurrentUserId(input any) (*int64, error) {
+
+	// input := c.MustGet("userId")
+	userId, ok := input.(float64)
+	if !ok || userId <= 0 {
+		return nil, badRequest(invalidCurrentUserId)
+	}
+
+	id := int64(userId)
+	return &id, nil
+}
+
+// gameIconUrl serves the mirrored icon, the placeholder when there is no icon
+// or mirroring failed, and the provider url until the icon has been mirrored.
+func gameIconUrl(game model.GameResponse) string {
+
+	if game.ImageIcon == "" {
+		return filestore.Url(filestore.Pathname(catalog.PlaceholderIconName))
+	}
+
+	if game.ImageMirrorSource == nil || *game.ImageMirrorSource != game.ImageIcon || game.ImageMirrorStatus == nil {
+		return game.ImageIcon
+	}
+
+	if *game.ImageMirrorStatus == "success" && game.ImageMirror != nil {
+		return filestore.Url(*game.ImageMirror)
+	}
+
+	return filestore.Url(filestore.Pathname(catalog.PlaceholderIconName))
+}
+
+// findGameMaintenance picks the open window covering the game, or the whole
+// provider when gameId is 0.
+func findGameMaintenance(list []model.GameMaintenance, provider string, gameId int64) *model.GameMaintenance {
+
+	for i, maintenance := range list {
+		if maintenance.Provider != provider {
+			continue
+		}
+		if maintenance.GameId == nil || (gameId != 0 && *maintenance.GameId == gameId) {
+			return &list[i]
+		}
+	}
+	return nil
+}
+
+func maintenanceResponse(maintenance *model.GameMaintenance) *model.GameMaintenanceResponse {
+
+	if maintenance == nil {
+		return nil
+	}
+	return &model.GameMaintenanceResponse{
+		StartAt:   maintenance.StartAt,
+		EndAt:     maintenance.EndAt,
+		MessageTh: maintenance.MessageTh,
+		MessageEn: maintenance.MessageEn,
+	}
+}
+
+func firstNonEmpty(value string, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
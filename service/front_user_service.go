@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"cybergame-api/helper"
 	"cybergame-api/model"
 	"cybergame-api/repository"
@@ -13,14 +14,14 @@ import (
 type FrontUserService interface {
 	GetFrontUserLoginLogs(id int64) (*[]model.UserLoginLog, error)
 	GetFrontUser(id int64) (*model.UserDetail, error)
-	FrontUserLogin(body model.UserLogin) (*string, error)
+	FrontUserLogin(ctx context.Context, body model.UserLogin) (*string, error)
 	CreateFrontUser(user *model.CreateUser) error
 	FrontUserSendOTPRegister(body model.UserSendOTP) error
 	FrontUserVerifyOTPRegister(body model.UserVerifyOTP) (*int64, error)
 	FrontUserSendOTPFotget(body model.UserSendOTP) error
 	FrontUserVerifyOTPForget(body model.UserVerifyOTP) (*int64, error)
-	FrontUserUpdateInfo(userId int64, body model.FrontUserUpdate) error
-	FrontUserResetPassword(userId int64, body model.UserUpdatePassword) error
+	FrontUserUpdateInfo(ctx context.Context, userId int64, body model.FrontUserUpdate) error
+	FrontUserResetPassword(ctx context.Context, userId int64, body model.UserUpdatePassword) error
 	FrontUserChangePassword(userId int64, body model.UserUpdatePassword) error
 }
 
@@ -74,7 +75,7 @@ func (s *frontUserService) GetFrontUser(id int64) (*model.UserDetail, error) {
 	return admin, nil
 }
 
-func (s *frontUserService) FrontUserLogin(body model.UserLogin) (*string, error) {
+func (s *frontUserService) FrontUserLogin(ctx context.Context, body model.UserLogin) (*string, error) {
 
 	user, err := s.repo.GetFrontUserByPhone(body.Phone)
 	if err != nil {
@@ -94,22 +95,23 @@ func (s *frontUserService) FrontUserLogin(body model.UserLogin) (*string, error)
 		return nil, err
 	}
 
-	agentName := os.Getenv("AGENT_NAME")
-	sign := agentName + *user.Username + body.Password
+	// the member login is signed with the password, the wallet cannot do it
+	wallet := s.agentConnectRepo.AgentWallet()
+	sign := wallet.AgentName() + *user.Username + body.Password
 	timeNow := time.Now()
 	agentData := model.AGCLogin{
 		Username:  *user.Username,
-		Partner:   agentName,
+		Partner:   wallet.AgentName(),
 		Timestamp: timeNow.Unix(),
-		Sign:      helper.CreateSign(sign, timeNow),
+		Sign:      wallet.Sign(sign, timeNow),
 		Domain:    "http://test.com",
 		Lang:      "th-th",
 		IsMobile:  false,
 		Ip:        body.IP,
 	}
 
-	if err = s.agentConnectRepo.Login(agentData); err != nil {
-		return nil, err
+	if _, err = s.agentConnectRepo.Login(ctx, agentData); err != nil {
+		return nil, agentError(err)
 	}
 
 	return &token, nil
@@ -322,7 +324,7 @@ func (s *frontUserService) FrontUserVerifyOTPForget(body model.UserVerifyOTP) (*
 	return &user.Id, nil
 }
 
-func (s *frontUserService) FrontUserUpdateInfo(userId int64, body model.FrontUserUpdate) error {
+func (s *frontUserService) FrontUserUpdateInfo(ctx context.Context, userId int64, body model.FrontUserUpdate) error {
 
 	user, err := s.repo.GetFrontUser(userId)
 	if err != nil {
@@ -404,22 +406,17 @@ func (s *frontUserService) FrontUserUpdateInfo(userId int64, body model.FrontUse
 
 	agentTotal := *countUser + int64(agentStart)
 	conv := strconv.FormatInt(agentTotal, 10)
-	agentName := os.Getenv("AGENT_NAME")
+	agentName := s.agentConnectRepo.AgentWallet().AgentName()
 	username := agentName + helper.IncrementNumber(conv)
 	body.Username = username
-	sign := agentName + username
-	timeNow := time.Now()
 	agentData := model.AGCRegister{
-		Username:  username,
-		Agentname: agentName,
-		Fullname:  body.Fullname,
-		Password:  passwordOrigin,
-		Currency:  "THB",
-		Dob:       "1990-01-01",
-		Mobile:    user.Phone,
-		Ip:        body.Ip,
-		Timestamp: timeNow.Unix(),
-		Sign:      helper.CreateSign(sign, timeNow),
+		Username: username,
+		Fullname: body.Fullname,
+		Password: passwordOrigin,
+		Currency: "THB",
+		Dob:      "1990-01-01",
+		Mobile:   user.Phone,
+		Ip:       body.Ip,
 	}
 
 	body.VerifiedAt = time.Now()
@@ -428,21 +425,21 @@ func (s *frontUserService) FrontUserUpdateInfo(userId int64, body model.FrontUse
 		return err
 	}
 
-	if err := s.agentConnectRepo.Register(agentData); err != nil {
+	if err := s.agentConnectRepo.Register(ctx, agentData); err != nil {
 
 		agentData.Fullname = ""
 		if err := s.repo.FrontUpdateUser(userId, body); err != nil {
 			return internalServerError(ServerError)
 		}
 
-		return internalServerError(ServerError)
+		return agentError(err)
 	}
 
 	return nil
 
 }
 
-func (s *frontUserService) FrontUserResetPassword(userId int64, body model.UserUpdatePassword) error {
+func (s *frontUserService) FrontUserResetPassword(ctx context.Context, userId int64, body model.UserUpdatePassword) error {
 
 	user, err := s.repo.CheckFrontUserById(userId)
 	if err != nil {
@@ -462,19 +459,13 @@ func (s *frontUserService) FrontUserResetPassword(userId int64, body model.UserU
 		return internalServerError(err.Error())
 	}
 
-	agentName := os.Getenv("AGENT_NAME")
-	sign := agentName + *user.Username + body.Password
-	timeNow := time.Now()
 	agentData := model.AGCChangePassword{
 		PlayerName:  *user.Username,
-		Partner:     agentName,
 		NewPassword: body.Password,
-		Timestamp:   timeNow.Unix(),
-		Sign:        helper.CreateSign(sign, timeNow),
 	}
 
-	if err = s.agentConnectRepo.ChangePassword(agentData); err != nil {
-		return err
+	if err = s.agentConnectRepo.ChangePassword(ctx, agentData); err != nil {
+		return agentError(err)
 	}
 
 	body.Password = newPasword
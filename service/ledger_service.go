@@ -0,0 +1,79 @@
+package service
+
+import (
+	"cybergame-api/helper"
+	"cybergame-api/model"
+	"cybergame-api/repository"
+)
+
+const LedgerAccountNotFound = "ไม่พบบัญชีแยกประเภท"
+
+type LedgerService interface {
+	GetLedgerAccounts(req model.LedgerAccountListRequest) (*model.SuccessWithPagination, error)
+	GetLedgerAccountById(id int64) (*model.LedgerAccount, error)
+	GetLedgerEntries(req model.LedgerEntryListRequest) (*model.SuccessWithPagination, error)
+	VerifyLedger() (*model.LedgerVerifyResult, error)
+}
+
+type ledgerService struct {
+	repo repository.LedgerRepository
+}
+
+func NewLedgerService(
+	repo repository.LedgerRepository,
+) LedgerService {
+	return &ledgerService{repo}
+}
+
+func (s *ledgerService) GetLedgerAccounts(req model.LedgerAccountListRequest) (*model.SuccessWithPagination, error) {
+
+	if err := helper.Pagination(&req.Page, &req.Limit); err != nil {
+		return nil, badRequest(err.Error())
+	}
+
+	list, err := s.repo.GetLedgerAccounts(req)
+	if err != nil {
+		return nil, internalServerError(err.Error())
+	}
+
+	return list, nil
+}
+
+func (s *ledgerService) GetLedgerAccountById(id int64) (*model.LedgerAccount, error) {
+
+	account, err := s.repo.GetLedgerAccountById(id)
+	if err != nil {
+		if err.Error() == recordNotFound {
+			return nil, notFound(LedgerAccountNotFound)
+		}
+		return nil, internalServerError(err.Error())
+	}
+
+	return account, nil
+}
+
+func (s *ledgerService) GetLedgerEntries(req model.LedgerEntryListRequest) (*model.SuccessWithPagination, error) {
+
+	if err := helper.Pagination(&req.Page, &req.Limit); err != nil {
+		return nil, badRequest(err.Error())
+	}
+
+	list, err := s.repo.GetLedgerEntries(req)
+	if err != nil {
+		return nil, internalServerError(err.Error())
+	}
+
+	return list, nil
+}
+
+// VerifyLedger reports the transactions that do not balance and the balances
+// that differ from their entries or from the member credit.
+func (s *ledgerService) VerifyLedger() (*model.LedgerVerifyResult, error) {
+
+	result, err := s.repo.VerifyLedger()
+	if err != nil {
+		return nil, internalServerError(err.Error())
+	}
+
+	return result, nil
+}
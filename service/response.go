@@ -1,6 +1,7 @@
 package service
 
 import (
+	"cybergame-api/agent"
 	"net/http"
 )
 
@@ -10,6 +11,9 @@ type ResponseError struct {
 }
 
 const ServerError = "ระบบขัดข้อง กรุณาทำรายการใหม่อีกครั้ง"
+const AgentRefused = "เอเย่นต์ปฏิเสธรายการ"
+const AgentTimeout = "เอเย่นต์ไม่ตอบกลับภายในเวลาที่กำหนด"
+const AgentUnavailable = "ไม่สามารถเชื่อมต่อเอเย่นต์ได้"
 
 func (e ResponseError) Error() string {
 	return e.Message
@@ -35,3 +39,39 @@ func internalServerError(msg string) error {
 		Message: msg,
 	}
 }
+
+func serviceUnavailable(msg string) error {
+	return ResponseError{
+		Code:    http.StatusServiceUnavailable,
+		Message: msg,
+	}
+}
+
+// agentError turns an agent call failure into a ResponseError admins can act
+// on: refusals keep the agent message, transport problems become 502/504.
+func agentError(err error) error {
+
+	if _, ok := err.(ResponseError); ok {
+		return err
+	}
+
+	agentErr, ok := agent.AsError(err)
+	if !ok {
+		return internalServerError(err.Error())
+	}
+
+	switch agentErr.Kind {
+	case agent.KindRefused:
+		return ResponseError{Code: http.StatusBadRequest, Message: AgentRefused + ": " + agentErr.Message}
+	case agent.KindHttp:
+		if agentErr.Status >= 400 && agentErr.Status < 500 && agentErr.Status != http.StatusUnauthorized && agentErr.Status != http.StatusForbidden {
+			return ResponseError{Code: http.StatusBadRequest, Message: AgentRefused + ": " + agentErr.Message}
+		}
+		// a rejected signature is our configuration, not the member request
+		return ResponseError{Code: http.StatusBadGateway, Message: AgentUnavailable + ": " + agentErr.Message}
+	case agent.KindTimeout, agent.KindCanceled:
+		return ResponseError{Code: http.StatusGatewayTimeout, Message: AgentTimeout}
+	default:
+		return ResponseError{Code: http.StatusBadGateway, Message: AgentUnavailable}
+	}
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"cybergame-api/helper"
+	"cybergame-api/model"
+	"cybergame-api/repository"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Codes answered to the providers in SeamlessResponse.Code.
+const (
+	SeamlessCodeSuccess             = 0
+	SeamlessCodeInvalidRequest      = 1
+	SeamlessCodeInvalidSign         = 2
+	SeamlessCodePlayerNotFound      = 3
+	SeamlessCodeInsufficientCredit  = 4
+	SeamlessCodeTransactionNotFound = 5
+	SeamlessCodeTransactionMismatch = 6
+	SeamlessCodeTransactionSettled  = 7
+	SeamlessCodeServerError         = 9
+)
+
+// seamlessClockSkew is how far a callback Timestamp may be from our clock.
+const seamlessClockSkew = 5 * time.Minute
+
+type SeamlessWalletService interface {
+	Balance(req model.SeamlessRequest) model.SeamlessResponse
+	Bet(req model.SeamlessRequest) model.SeamlessResponse
+	Win(req model.SeamlessRequest) model.SeamlessResponse
+	Refund(req model.SeamlessRequest) model.SeamlessResponse
+	Rollback(req model.SeamlessRequest) model.SeamlessResponse
+}
+
+type seamlessWalletService struct {
+	repo repository.SeamlessWalletRepository
+}
+
+func NewSeamlessWalletService(
+	repo repository.SeamlessWalletRepository,
+) SeamlessWalletService {
+	return &seamlessWalletService{repo}
+}
+
+// SeamlessWalletKey is the key a provider signs with, SEAMLESS_KEY_<PROVIDER>
+// or SEAMLESS_KEY for every provider.
+func SeamlessWalletKey(provider string) string {
+	if key := os.Getenv("SEAMLESS_KEY_" + strings.ToUpper(provider)); key != "" {
+		return key
+	}
+	return os.Getenv("SEAMLESS_KEY")
+}
+
+func (s *seamlessWalletService) Balance(req model.SeamlessRequest) model.SeamlessResponse {
+
+	if !checkSeamlessSign(req, req.Provider+req.Username) {
+		return seamlessFail(SeamlessCodeInvalidSign, "Invalid sign")
+	}
+
+	member, err := s.repo.GetSeamlessBalance(req.Username)
+	if err != nil {
+		if err.Error() == recordNotFound {
+			return seamlessFail(SeamlessCodePlayerNotFound, repository.ErrSeamlessPlayerNotFound.Error())
+		}
+		return seamlessServerError(err)
+	}
+
+	// the provider sees what can be bet, without the credit held for withdraws
+	return model.SeamlessResponse{Message: "Success", Username: member.Username, Balance: member.Credit - member.CreditReserved}
+}
+
+func (s *seamlessWalletService) Bet(req model.SeamlessRequest) model.SeamlessResponse {
+
+	if req.Amount <= 0 {
+		return seamlessFail(SeamlessCodeInvalidRequest, "Amount must be greater than 0")
+	}
+	return s.apply(req, "bet", "seamless_bet", -req.Amount)
+}
+
+func (s *seamlessWalletService) Win(req model.SeamlessRequest) model.SeamlessResponse {
+
+	// a lost round settles with a zero win
+	return s.apply(req, "win", "seamless_win", req.Amount)
+}
+
+func (s *seamlessWalletService) Refund(req model.SeamlessRequest) model.SeamlessResponse {
+
+	if req.RefTransactionId == "" {
+		return seamlessFail(SeamlessCodeInvalidRequest, "Missing refTransactionId")
+	}
+	return s.apply(req, "refund", "seamless_refund", 0)
+}
+
+func (s *seamlessWalletService) Rollback(req model.SeamlessRequest) model.SeamlessResponse {
+
+	if req.RefTransactionId == "" {
+		return seamlessFail(SeamlessCodeInvalidRequest, "Missing refTransactionId")
+	}
+	return s.apply(req, "rollback", "seamless_rollback", 0)
+}
+
+func (s *seamlessWalletService) apply(req model.SeamlessRequest, action string, statementCode string, amount model.Money) model.SeamlessResponse {
+
+	if req.TransactionId == "" {
+		return seamlessFail(SeamlessCodeInvalidRequest, "Missing transactionId")
+	}
+	if !checkSeamlessSign(req, req.Provider+req.Username+req.TransactionId) {
+		return seamlessFail(SeamlessCodeInvalidSign, "Invalid sign")
+	}
+
+	statementType, err := s.repo.GetMemberStatementTypeByCode(statementCode)
+	if err != nil {
+		return seamlessServerError(err)
+	}
+
+	body := model.SeamlessTransactionBody{
+		Provider:         req.Provider,
+		TransactionId:    req.TransactionId,
+		RoundId:          req.RoundId,
+		RefTransactionId: req.RefTransactionId,
+		Username:         req.Username,
+		GameCode:         req.GameCode,
+		Action:           action,
+		Amount:           amount,
+		StatementTypeId:  statementType.Id,
+		Info:             fmt.Sprintf("%s %s %s", statementType.Name, req.Provider, req.RoundId),
+	}
+
+	result, err := s.repo.ApplySeamlessTransaction(body)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrSeamlessPlayerNotFound):
+			return seamlessFail(SeamlessCodePlayerNotFound, err.Error())
+		case errors.Is(err, repository.ErrSeamlessInsufficientCredit):
+			return s.failWithBalance(req.Username, SeamlessCodeInsufficientCredit, err.Error())
+		case errors.Is(err, repository.ErrSeamlessTransactionNotFound):
+			return s.failWithBalance(req.Username, SeamlessCodeTransactionNotFound, err.Error())
+		case errors.Is(err, repository.ErrSeamlessTransactionMismatch):
+			return s.failWithBalance(req.Username, SeamlessCodeTransactionMismatch, err.Error())
+		case errors.Is(err, repository.ErrSeamlessTransactionSettled):
+			return s.failWithBalance(req.Username, SeamlessCodeTransactionSettled, err.Error())
+		}
+		return seamlessServerError(err)
+	}
+
+	return model.SeamlessResponse{
+		Message:       "Success",
+		Username:      result.Username,
+		Balance:       result.Balance,
+		TransactionId: req.TransactionId,
+	}
+}
+
+// failWithBalance still tells the provider the balance, most of them show it
+// in the game after a refused bet.
+func (s *seamlessWalletService) failWithBalance(username string, code int, message string) model.SeamlessResponse {
+
+	response := seamlessFail(code, message)
+	if member, err := s.repo.GetSeamlessBalance(username); err == nil {
+		response.Username = member.Username
+		response.Balance = member.Credit - member.CreditReserved
+	}
+	return response
+}
+
+func checkSeamlessSign(req model.SeamlessRequest, data string) bool {
+
+	key := SeamlessWalletKey(req.Provider)
+	if key == "" {
+		return false
+	}
+
+	signedAt := time.Unix(req.Timestamp, 0)
+	if skew := time.Since(signedAt); skew > seamlessClockSkew || skew < -seamlessClockSkew {
+		return false
+	}
+
+	return strings.EqualFold(helper.CreateSignWithKey(data, signedAt, key), req.Sign)
+}
+
+func seamlessFail(code int, message string) model.SeamlessResponse {
+	return model.SeamlessResponse{Code: code, Message: message}
+}
+
+func seamlessServerError(err error) model.SeamlessResponse {
+	log.Println("seamless wallet:", err.Error())
+	return seamlessFail(SeamlessCodeServerError, ServerError)
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"cybergame-api/model"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+const WithdrawRiskOverAccountMax = "ยอดถอนเกินยอดถอนอัตโนมัติของบัญชี"
+const WithdrawRiskOverTransactionMax = "ยอดถอนเกินยอดสูงสุดต่อครั้ง"
+const WithdrawRiskOverDayMax = "ยอดถอนรวมวันนี้เกินยอดสูงสุดต่อวัน"
+const WithdrawRiskFirstWithdraw = "ถอนครั้งแรก"
+const WithdrawRiskRecentBonus = "ได้รับโบนัสภายใน"
+const WithdrawRiskScammer = "ข้อมูลตรงกับรายชื่อมิจฉาชีพ"
+const WithdrawRiskNewBankAccount = "บัญชีธนาคารใหม่ภายใน"
+
+func (s *bankingService) GetWithdrawRiskRules() ([]model.WithdrawRiskRule, error) {
+
+	list, err := s.repoBanking.GetWithdrawRiskRules()
+	if err != nil {
+		return nil, internalServerError(err.Error())
+	}
+	return list, nil
+}
+
+func (s *bankingService) UpdateWithdrawRiskRule(id int64, req model.WithdrawRiskRuleUpdateRequest) error {
+
+	if _, err := s.repoBanking.GetWithdrawRiskRuleById(id); err != nil {
+		if err.Error() == recordNotFound {
+			return notFound("Rule not found")
+		}
+		return internalServerError(err.Error())
+	}
+	if req.Amount != nil && *req.Amount < 0 {
+		return badRequest("Amount must not be negative")
+	}
+
+	body := model.WithdrawRiskRuleUpdateBody{
+		IsEnabled:         req.IsEnabled,
+		Amount:            req.Amount,
+		Hours:             req.Hours,
+		UpdatedByUserId:   req.UpdatedByUserId,
+		UpdatedByUsername: req.UpdatedByUsername,
+	}
+	if err := s.repoBanking.UpdateWithdrawRiskRule(id, body); err != nil {
+		return internalServerError(err.Error())
+	}
+	return nil
+}
+
+// checkWithdrawRisk lists why a withdraw must wait for an admin instead of
+// going out automatically, empty when it may go. The account auto withdraw
+// max is always checked, the other rules when enabled in Withdraw_risk_rules.
+func (s *bankingService) checkWithdrawRisk(check model.WithdrawRiskCheck) ([]string, error) {
+
+	var reasons []string
+
+	if check.FromAccount.AutoWithdrawMaxAmount > 0 && check.CreditAmount > check.FromAccount.AutoWithdrawMaxAmount {
+		reasons = append(reasons, fmt.Sprintf("%s %s", WithdrawRiskOverAccountMax, check.FromAccount.AutoWithdrawMaxAmount))
+	}
+
+	rules, err := s.repoBanking.GetWithdrawRiskRules()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rule := range rules {
+		if !rule.IsEnabled {
+			continue
+		}
+		switch rule.RuleKey {
+		case model.WithdrawRiskMaxPerTransaction:
+			if check.CreditAmount > rule.Amount {
+				reasons = append(reasons, fmt.Sprintf("%s %s", WithdrawRiskOverTransactionMax, rule.Amount))
+			}
+		case model.WithdrawRiskMaxPerDay:
+			now := time.Now()
+			today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+			total, err := s.repoBanking.GetMemberWithdrawTotalSince(check.UserId, today, check.TransactionId)
+			if err != nil {
+				return nil, err
+			}
+			if total+check.CreditAmount > rule.Amount {
+				reasons = append(reasons, fmt.Sprintf("%s %s", WithdrawRiskOverDayMax, rule.Amount))
+			}
+		case model.WithdrawRiskFirstWithdraw:
+			count, err := s.repoBanking.CountMemberFinishedWithdraws(check.UserId)
+			if err != nil {
+				return nil, err
+			}
+			if count == 0 {
+				reasons = append(reasons, WithdrawRiskFirstWithdraw)
+			}
+		case model.WithdrawRiskRecentBonus:
+			lastAt, err := s.repoBanking.GetMemberLastBonusAt(check.UserId)
+			if err != nil {
+				return nil, err
+			}
+			if lastAt != nil && time.Since(*lastAt) < time.Duration(rule.Hours)*time.Hour {
+				reasons = append(reasons, fmt.Sprintf("%s %d ชั่วโมง", WithdrawRiskRecentBonus, rule.Hours))
+			}
+		case model.WithdrawRiskScammer:
+			member, err := s.repoBanking.GetMemberById(check.UserId)
+			if err != nil {
+				return nil, err
+			}
+			isScammer, err := s.repoBanking.IsMemberScammer(member.BankAccount, member.Phone)
+			if err != nil {
+				return nil, err
+			}
+			if isScammer {
+				reasons = append(reasons, WithdrawRiskScammer)
+			}
+		case model.WithdrawRiskNewBankAccount:
+			since, err := s.repoBanking.GetMemberBankAccountSince(check.UserId)
+			if err != nil {
+				return nil, err
+			}
+			if since != nil && time.Since(*since) < time.Duration(rule.Hours)*time.Hour {
+				reasons = append(reasons, fmt.Sprintf("%s %d ชั่วโมง", WithdrawRiskNewBankAccount, rule.Hours))
+			}
+		}
+	}
+	return reasons, nil
+}
+
+// holdAutoWithdraw runs the risk rules on an auto withdraw condition. A held
+// withdraw loses its auto flags and gets the reasons as StatusDetail, a rule
+// that cannot be checked holds it too.
+func (s *bankingService) holdAutoWithdraw(condition *model.BankAutoWithdrawCondition, check model.WithdrawRiskCheck) {
+
+	if condition.AutoWithdrawCreditFlag != "auto" && condition.AutoWithdrawConfirmFlag != "auto" {
+		return
+	}
+
+	reasons, err := s.checkWithdrawRisk(check)
+	if err != nil {
+		log.Printf("withdraw risk check of user %d: %s", check.UserId, err.Error())
+		reasons = []string{ServerError}
+	}
+	if len(reasons) == 0 {
+		return
+	}
+
+	condition.AutoWithdrawCreditFlag = ""
+	condition.AutoWithdrawConfirmFlag = ""
+	condition.StatusDetail = withdrawRiskDetail(reasons)
+}
+
+// withdrawRiskDetail fits the reasons in the 255 characters of status_detail.
+func withdrawRiskDetail(reasons []string) string {
+
+	detail := []rune(strings.Join(reasons, ", "))
+	if len(detail) > 255 {
+		detail = detail[:255]
+	}
+	return string(detail)
+}